@@ -14,8 +14,12 @@ import (
 	"github.com/anis7x/cliniclab/internal/auth"
 	"github.com/anis7x/cliniclab/internal/config"
 	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/document"
 	"github.com/anis7x/cliniclab/internal/handlers"
 	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/anis7x/cliniclab/internal/notify"
+	"github.com/anis7x/cliniclab/internal/storage"
 	"github.com/go-chi/chi/v5"
 	chimw "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
@@ -27,6 +31,35 @@ func main() {
 
 	// Init JWT
 	auth.InitJWT(cfg.JWTSecret)
+	handlers.InitListings(cfg.ListingApprovalRequired)
+	middleware.InitLocale(handlers.OrgDefaultLanguage)
+
+	// Media storage
+	var store storage.Storage
+	if cfg.StorageBackend == "s3" {
+		store = storage.NewS3(storage.S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+			PublicURL: cfg.S3PublicURL,
+		})
+	} else {
+		local, err := storage.NewLocal(cfg.StorageDir)
+		if err != nil {
+			log.Fatalf("❌ Media storage init failed: %v", err)
+		}
+		store = local
+	}
+	handlers.InitMedia(store, cfg.PublicBaseURL, cfg.MaxUploadMB)
+
+	// Printed documents
+	renderer, err := document.NewRenderer(cfg.PDFFontPath)
+	if err != nil {
+		log.Printf("⚠️ PDF font warning: %v (using the bundled font)", err)
+	}
+	handlers.InitPrescriptions(renderer, cfg.PublicAPIURL)
 
 	// Connect to database
 	if err := database.Connect(cfg.DBUrl); err != nil {
@@ -70,6 +103,10 @@ func main() {
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
+	r.Use(middleware.Locale)
+
+	// Uploaded media (public, outside /api so URLs stay short and stable)
+	r.Get("/media/*", handlers.ServeMedia)
 
 	// Routes
 	r.Route("/api", func(r chi.Router) {
@@ -90,11 +127,138 @@ func main() {
 
 		// Provider/search routes (public)
 		r.Get("/providers/search", handlers.SearchProviders)
+		r.Get("/providers/compare", handlers.CompareProviders)
 		r.Get("/providers/{id}", handlers.GetProvider)
+		r.Get("/providers/{id}/reviews", handlers.ListProviderReviews)
+
+		// Autocomplete (public)
+		r.Get("/suggest", handlers.Suggest)
+
+		// Price statistics (public)
+		r.Get("/prices/stats", handlers.GetPriceStats)
+
+		// Review routes (authenticated)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.AuthRequired)
+			r.With(middleware.RequireRole(models.RolePatient)).
+				Post("/providers/{id}/reviews", handlers.CreateReview)
+			r.With(middleware.RequireRole(models.RoleLabAdmin, models.RoleClinicAdmin)).
+				Post("/reviews/{id}/reply", handlers.ReplyToReview)
+			r.Post("/reviews/{id}/report", handlers.ReportReview)
+		})
+
+		// Patient favorites, saved searches, record links & portal
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.AuthRequired)
+			r.Use(middleware.RequireRole(models.RolePatient))
+			r.Get("/favorites", handlers.ListFavorites)
+			r.Put("/favorites/{providerId}", handlers.AddFavorite)
+			r.Delete("/favorites/{providerId}", handlers.RemoveFavorite)
+			r.Get("/saved-searches", handlers.ListSavedSearches)
+			r.Post("/saved-searches", handlers.CreateSavedSearch)
+			r.Patch("/saved-searches/{id}", handlers.UpdateSavedSearch)
+			r.Delete("/saved-searches/{id}", handlers.DeleteSavedSearch)
+			r.Post("/link-codes", handlers.CreateLinkCode)
+			r.Get("/links", handlers.ListMyLinks)
+			r.Post("/links/{id}/confirm", handlers.ConfirmLink)
+			r.Post("/links/{id}/decline", handlers.DeclineLink)
+			r.Delete("/links/{id}", handlers.RevokeLink)
+			r.Get("/links/{id}/access", handlers.ListLinkAccess)
+			r.Get("/portal/appointments", handlers.PortalAppointments)
+			r.Get("/portal/lab-results", handlers.PortalLabResults)
+			r.Get("/portal/prescriptions", handlers.PortalPrescriptions)
+			r.Get("/portal/discharges", handlers.PortalDischarges)
+			r.Get("/portal/invoices", handlers.PortalInvoices)
+		})
+
+		// Listing self-service (labs & clinics)
+		r.Route("/listings", func(r chi.Router) {
+			r.Use(middleware.AuthRequired)
+			r.Use(middleware.RequireRole(models.RoleLabAdmin, models.RoleClinicAdmin))
+			r.Get("/mine", handlers.MyListings)
+			r.Post("/{id}/claim", handlers.ClaimListing)
+			r.Patch("/{id}", handlers.UpdateListing)
+			r.Get("/{id}/changes", handlers.ListListingChanges)
+			r.Post("/{id}/services", handlers.AddListingService)
+			r.Put("/{id}/services/{serviceId}", handlers.UpdateListingService)
+			r.Delete("/{id}/services/{serviceId}", handlers.DeleteListingService)
+			r.Post("/{id}/images", handlers.UploadListingImage)
+		})
+
+		// Organization listing & prices (ERP tenant → public search)
+		r.Route("/org", func(r chi.Router) {
+			r.Use(middleware.AuthRequired)
+			r.Use(middleware.RequireRole(models.RoleLabAdmin, models.RoleClinicAdmin))
+			r.Get("/listing", handlers.GetOrgListing)
+			r.Post("/listing", handlers.PublishOrgListing)
+			r.Delete("/listing", handlers.UnpublishOrgListing)
+			r.Post("/logo", handlers.UploadOrgLogo)
+			r.Get("/prices", handlers.ListOrgPrices)
+			r.Put("/prices/acts/{code}", handlers.SetOrgActPrice)
+			r.Delete("/prices/acts/{code}", handlers.DeleteOrgActPrice)
+			r.Put("/prices/lab-tests/{code}", handlers.SetOrgLabTestPrice)
+			r.Delete("/prices/lab-tests/{code}", handlers.DeleteOrgLabTestPrice)
+			r.Get("/patients", handlers.ListERPPatients)
+			r.Post("/patients", handlers.CreateERPPatient)
+			r.Get("/patients/{id}", handlers.GetERPPatient)
+			r.Patch("/patients/{id}", handlers.UpdateERPPatient)
+			r.Get("/patients/{id}/duplicates", handlers.FindDuplicatePatients)
+			r.Post("/patients/{id}/merge", handlers.MergePatients)
+			r.Get("/patients/{id}/link", handlers.GetERPPatientLink)
+			r.Post("/patients/{id}/link", handlers.LinkERPPatient)
+			r.Delete("/patients/{id}/link", handlers.UnlinkERPPatient)
+			r.Get("/patients/{id}/visits", handlers.ListVisits)
+			r.Post("/patients/{id}/visits", handlers.CreateVisit)
+			r.Get("/patients/{id}/vitals/trends", handlers.VitalTrends)
+			r.Get("/patients/{id}/timeline", handlers.PatientTimeline)
+			r.Get("/patients/{id}/allergies", handlers.ListPatientAllergies)
+			r.Post("/patients/{id}/allergies", handlers.AddPatientAllergy)
+			r.Delete("/patients/{id}/allergies/{allergyId}", handlers.DeletePatientAllergy)
+			r.Get("/patients/{id}/conditions", handlers.ListPatientConditions)
+			r.Post("/patients/{id}/conditions", handlers.AddPatientCondition)
+			r.Delete("/patients/{id}/conditions/{conditionId}", handlers.DeletePatientCondition)
+			r.Get("/visits/{id}", handlers.GetVisit)
+			r.Patch("/visits/{id}", handlers.AmendVisit)
+			r.Post("/visits/{id}/prescriptions", handlers.CreatePrescription)
+			r.Post("/visits/{id}/prescriptions/check", handlers.CheckPrescription)
+			r.Get("/patients/{id}/prescriptions", handlers.ListPatientPrescriptions)
+			r.Get("/prescriptions/{id}", handlers.GetPrescription)
+			r.Get("/prescriptions/{id}/pdf", handlers.PrescriptionPDF)
+			r.Get("/portal-settings", handlers.GetPortalSettings)
+			r.Patch("/portal-settings", handlers.UpdatePortalSettings)
+		})
+
+		// Platform admin routes
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(middleware.AuthRequired)
+			r.Use(middleware.RequireRole(models.RolePlatformAdmin))
+			r.Patch("/reviews/{id}", handlers.ModerateReview)
+			r.Get("/review-reports", handlers.ListReviewReports)
+			r.Patch("/review-reports/{id}", handlers.ResolveReviewReport)
+			r.Get("/listing-changes", handlers.ListPendingListingChanges)
+			r.Post("/listing-changes/{id}/approve", handlers.ApproveListingChange)
+			r.Post("/listing-changes/{id}/reject", handlers.RejectListingChange)
+			r.Post("/cache/flush", handlers.FlushCaches)
+			r.Get("/search-analytics", handlers.GetSearchAnalytics)
+			r.Post("/icd10/import", handlers.ImportICD10)
+			r.Post("/medications/import", handlers.ImportMedications)
+			r.Get("/drug-interactions", handlers.ListDrugInteractions)
+			r.Post("/drug-interactions", handlers.CreateDrugInteraction)
+			r.Delete("/drug-interactions/{id}", handlers.DeleteDrugInteraction)
+		})
 
 		// Data routes (public)
 		r.Get("/wilayas", handlers.GetWilayas)
+		r.Get("/wilayas/{id}/dairas", handlers.GetDairas)
+		r.Get("/wilayas/{id}/communes", handlers.GetCommunes)
 		r.Get("/services", handlers.GetServices)
+		r.Get("/services/categories", handlers.GetServiceCategories)
+		r.Get("/icd10", handlers.SearchICD10)
+		r.Get("/icd10/{code}", handlers.GetICD10Code)
+		r.Get("/medications", handlers.SearchMedications)
+		r.Get("/medications/dci", handlers.LookupDCI)
+		r.Get("/medications/{id}", handlers.GetMedication)
+		r.Get("/prescriptions/verify/{code}", handlers.VerifyPrescription)
 
 		// Health check
 		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -112,6 +276,16 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Background jobs: search log writer and saved search alerts
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go handlers.RunSearchLogger(jobsCtx)
+	var notifier notify.Notifier = notify.Log{}
+	if cfg.NotifyWebhookURL != "" {
+		notifier = notify.NewWebhook(cfg.NotifyWebhookURL)
+	}
+	go handlers.RunSavedSearchAlerts(jobsCtx, notifier, time.Duration(max(cfg.AlertIntervalMinutes, 1))*time.Minute)
+
 	// Graceful shutdown
 	go func() {
 		sigChan := make(chan os.Signal, 1)
@@ -119,6 +293,7 @@ func main() {
 		<-sigChan
 
 		log.Println("🛑 Shutting down server...")
+		stopJobs()
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		srv.Shutdown(ctx)
@@ -132,11 +307,104 @@ func main() {
 	fmt.Println("   GET  /api/auth/me")
 	fmt.Println("   POST /api/auth/setup-2fa")
 	fmt.Println("   POST /api/auth/verify-2fa")
-	fmt.Println("   GET  /api/providers/search?wilaya=&service=")
+	fmt.Println("   GET  /api/providers/search?wilaya=&commune=&type=&service=&category=&price=&facets=")
+	fmt.Println("   GET  /api/providers/compare?ids=&services=&lat=&lng=")
 	fmt.Println("   GET  /api/providers/{id}")
+	fmt.Println("   GET  /api/providers/{id}/reviews?page=&limit=")
+	fmt.Println("   GET  /api/suggest?q=&type=&limit=")
+	fmt.Println("   GET  /api/prices/stats?service=&wilaya=&type=&cheapest=")
+	fmt.Println("   POST /api/providers/{id}/reviews")
+	fmt.Println("   POST /api/reviews/{id}/reply")
+	fmt.Println("   POST /api/reviews/{id}/report")
+	fmt.Println("   GET  /api/favorites")
+	fmt.Println("   PUT  /api/favorites/{providerId}")
+	fmt.Println("   DELETE /api/favorites/{providerId}")
+	fmt.Println("   GET  /api/saved-searches")
+	fmt.Println("   POST /api/saved-searches")
+	fmt.Println("   PATCH /api/saved-searches/{id}")
+	fmt.Println("   DELETE /api/saved-searches/{id}")
+	fmt.Println("   POST /api/link-codes")
+	fmt.Println("   GET  /api/links")
+	fmt.Println("   POST /api/links/{id}/confirm")
+	fmt.Println("   POST /api/links/{id}/decline")
+	fmt.Println("   DELETE /api/links/{id}")
+	fmt.Println("   GET  /api/links/{id}/access")
+	fmt.Println("   GET  /api/portal/appointments?upcoming=")
+	fmt.Println("   GET  /api/portal/lab-results")
+	fmt.Println("   GET  /api/portal/prescriptions")
+	fmt.Println("   GET  /api/portal/discharges")
+	fmt.Println("   GET  /api/portal/invoices")
+	fmt.Println("   GET  /api/listings/mine")
+	fmt.Println("   POST /api/listings/{id}/claim")
+	fmt.Println("   PATCH /api/listings/{id}")
+	fmt.Println("   GET  /api/listings/{id}/changes")
+	fmt.Println("   POST /api/listings/{id}/services")
+	fmt.Println("   PUT  /api/listings/{id}/services/{serviceId}")
+	fmt.Println("   DELETE /api/listings/{id}/services/{serviceId}")
+	fmt.Println("   POST /api/listings/{id}/images?cover=")
+	fmt.Println("   GET  /api/org/listing")
+	fmt.Println("   POST /api/org/listing")
+	fmt.Println("   DELETE /api/org/listing")
+	fmt.Println("   POST /api/org/logo")
+	fmt.Println("   GET  /api/org/prices")
+	fmt.Println("   PUT  /api/org/prices/acts/{code}")
+	fmt.Println("   DELETE /api/org/prices/acts/{code}")
+	fmt.Println("   PUT  /api/org/prices/lab-tests/{code}")
+	fmt.Println("   DELETE /api/org/prices/lab-tests/{code}")
+	fmt.Println("   GET  /api/org/patients")
+	fmt.Println("   POST /api/org/patients")
+	fmt.Println("   GET  /api/org/patients/{id}")
+	fmt.Println("   PATCH /api/org/patients/{id}")
+	fmt.Println("   GET  /api/org/patients/{id}/duplicates")
+	fmt.Println("   POST /api/org/patients/{id}/merge")
+	fmt.Println("   GET  /api/org/patients/{id}/link")
+	fmt.Println("   POST /api/org/patients/{id}/link")
+	fmt.Println("   DELETE /api/org/patients/{id}/link")
+	fmt.Println("   GET  /api/org/patients/{id}/visits")
+	fmt.Println("   POST /api/org/patients/{id}/visits")
+	fmt.Println("   GET  /api/org/patients/{id}/vitals/trends")
+	fmt.Println("   GET  /api/org/patients/{id}/timeline")
+	fmt.Println("   GET  /api/org/patients/{id}/allergies")
+	fmt.Println("   POST /api/org/patients/{id}/allergies")
+	fmt.Println("   DELETE /api/org/patients/{id}/allergies/{allergyId}")
+	fmt.Println("   GET  /api/org/patients/{id}/conditions")
+	fmt.Println("   POST /api/org/patients/{id}/conditions")
+	fmt.Println("   DELETE /api/org/patients/{id}/conditions/{conditionId}")
+	fmt.Println("   GET  /api/org/visits/{id}")
+	fmt.Println("   PATCH /api/org/visits/{id}")
+	fmt.Println("   POST /api/org/visits/{id}/prescriptions")
+	fmt.Println("   POST /api/org/visits/{id}/prescriptions/check")
+	fmt.Println("   GET  /api/org/patients/{id}/prescriptions")
+	fmt.Println("   GET  /api/org/prescriptions/{id}")
+	fmt.Println("   GET  /api/org/prescriptions/{id}/pdf")
+	fmt.Println("   GET  /api/org/portal-settings")
+	fmt.Println("   PATCH /api/org/portal-settings")
+	fmt.Println("   PATCH /api/admin/reviews/{id}")
+	fmt.Println("   GET  /api/admin/review-reports?status=")
+	fmt.Println("   PATCH /api/admin/review-reports/{id}")
+	fmt.Println("   GET  /api/admin/listing-changes?status=")
+	fmt.Println("   POST /api/admin/listing-changes/{id}/approve")
+	fmt.Println("   POST /api/admin/listing-changes/{id}/reject")
+	fmt.Println("   POST /api/admin/cache/flush")
+	fmt.Println("   GET  /api/admin/search-analytics?days=&wilaya=&limit=")
+	fmt.Println("   POST /api/admin/icd10/import?format=&lang=&replace=")
+	fmt.Println("   POST /api/admin/medications/import?replace=")
+	fmt.Println("   GET  /api/admin/drug-interactions?substance=")
+	fmt.Println("   POST /api/admin/drug-interactions")
+	fmt.Println("   DELETE /api/admin/drug-interactions/{id}")
 	fmt.Println("   GET  /api/wilayas")
-	fmt.Println("   GET  /api/services")
+	fmt.Println("   GET  /api/wilayas/{id}/dairas")
+	fmt.Println("   GET  /api/wilayas/{id}/communes?daira=")
+	fmt.Println("   GET  /api/services?category=")
+	fmt.Println("   GET  /api/services/categories")
+	fmt.Println("   GET  /api/icd10?q=&parent=&billable=")
+	fmt.Println("   GET  /api/icd10/{code}")
+	fmt.Println("   GET  /api/medications?q=&dci=&form=&reimbursable=")
+	fmt.Println("   GET  /api/medications/dci?q=")
+	fmt.Println("   GET  /api/medications/{id}")
+	fmt.Println("   GET  /api/prescriptions/verify/{code}")
 	fmt.Println("   GET  /api/health")
+	fmt.Println("   GET  /media/{key}")
 	fmt.Println()
 
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
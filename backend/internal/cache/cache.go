@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// Cache is a concurrency-safe in-process cache with a fixed TTL per entry.
+type Cache struct {
+	mu    sync.RWMutex
+	ttl   time.Duration
+	items map[string]entry
+}
+
+// New creates a cache whose entries expire after ttl.
+func New(ttl time.Duration) *Cache {
+	return &Cache{ttl: ttl, items: make(map[string]entry)}
+}
+
+// Get returns a live entry, or false if it is missing or expired.
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.RLock()
+	e, ok := c.items[key]
+	c.mu.RUnlock()
+	if !ok || time.Now().After(e.expiresAt) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Set stores a value, sweeping expired entries as it goes.
+func (c *Cache) Set(key string, value interface{}) {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.items {
+		if now.After(e.expiresAt) {
+			delete(c.items, k)
+		}
+	}
+	c.items[key] = entry{value: value, expiresAt: now.Add(c.ttl)}
+}
+
+// Clear drops every entry.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	c.items = make(map[string]entry)
+	c.mu.Unlock()
+}
+
+// Delete drops one entry, e.g. after the data behind it was written.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	delete(c.items, key)
+	c.mu.Unlock()
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 
 	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 // SeedData loads JSON data files from the frontend into the database.
@@ -19,6 +20,21 @@ func SeedData(dataDir string) error {
 	Pool.QueryRow(ctx, "SELECT COUNT(*) FROM wilayas").Scan(&count)
 	if count > 0 {
 		log.Println("📦 Data already seeded, skipping")
+		// Communes were added later and are upserted on every start, so
+		// databases seeded before get them and any fuller list
+		if err := seedCommunes(ctx, dataDir+"/communes.json"); err != nil {
+			return fmt.Errorf("seeding communes: %w", err)
+		}
+		// So were the ICD-10 catalog, the formulary and the drug rules
+		if err := seedICD10(ctx, dataDir+"/icd10.json"); err != nil {
+			return fmt.Errorf("seeding ICD-10 codes: %w", err)
+		}
+		if err := seedMedications(ctx, dataDir+"/medications.json"); err != nil {
+			return fmt.Errorf("seeding medications: %w", err)
+		}
+		if err := seedDrugRules(ctx, dataDir+"/drug_interactions.json"); err != nil {
+			return fmt.Errorf("seeding drug rules: %w", err)
+		}
 		return nil
 	}
 
@@ -29,15 +45,38 @@ func SeedData(dataDir string) error {
 		return fmt.Errorf("seeding wilayas: %w", err)
 	}
 
-	// 2. Seed medical services
+	// 2. Seed daïras and communes
+	if err := seedCommunes(ctx, dataDir+"/communes.json"); err != nil {
+		return fmt.Errorf("seeding communes: %w", err)
+	}
+
+	// 3. Seed medical services
 	if err := seedServices(ctx, dataDir+"/medical_services.json"); err != nil {
 		return fmt.Errorf("seeding services: %w", err)
 	}
 
-	// 3. Seed providers
+	// 4. Seed the ICD-10 sample
+	if err := seedICD10(ctx, dataDir+"/icd10.json"); err != nil {
+		return fmt.Errorf("seeding ICD-10 codes: %w", err)
+	}
+
+	// 5. Seed the formulary sample
+	if err := seedMedications(ctx, dataDir+"/medications.json"); err != nil {
+		return fmt.Errorf("seeding medications: %w", err)
+	}
+
+	// 6. Seed drug classes and interaction rules
+	if err := seedDrugRules(ctx, dataDir+"/drug_interactions.json"); err != nil {
+		return fmt.Errorf("seeding drug rules: %w", err)
+	}
+
+	// 7. Seed providers
 	if err := seedProviders(ctx, dataDir+"/mock_providers.json"); err != nil {
 		return fmt.Errorf("seeding providers: %w", err)
 	}
+	if err := linkCommunes(ctx); err != nil {
+		return fmt.Errorf("linking communes: %w", err)
+	}
 
 	log.Println("✅ Database seeded successfully")
 	return nil
@@ -66,6 +105,210 @@ func seedWilayas(ctx context.Context, path string) error {
 	return nil
 }
 
+// seedCommunes upserts daïras with their communes on every start, so that a
+// fuller list replacing the file is loaded into databases seeded before.
+func seedCommunes(ctx context.Context, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var dairas []models.Daira
+	if err := json.Unmarshal(data, &dairas); err != nil {
+		return err
+	}
+
+	communes := 0
+	for _, d := range dairas {
+		_, err := Pool.Exec(ctx,
+			`INSERT INTO dairas (code, wilaya_id, name, ar_name) VALUES ($1, $2, $3, $4)
+			 ON CONFLICT (code) DO UPDATE SET wilaya_id = EXCLUDED.wilaya_id, name = EXCLUDED.name, ar_name = EXCLUDED.ar_name`,
+			d.Code, d.WilayaID, d.Name, d.ArName)
+		if err != nil {
+			return fmt.Errorf("inserting daïra %s: %w", d.Code, err)
+		}
+		for _, c := range d.Communes {
+			_, err := Pool.Exec(ctx,
+				`INSERT INTO communes (code, daira_code, wilaya_id, name, ar_name) VALUES ($1, $2, $3, $4, $5)
+				 ON CONFLICT (code) DO UPDATE SET daira_code = EXCLUDED.daira_code, wilaya_id = EXCLUDED.wilaya_id,
+				     name = EXCLUDED.name, ar_name = EXCLUDED.ar_name`,
+				c.Code, d.Code, d.WilayaID, c.Name, c.ArName)
+			if err != nil {
+				return fmt.Errorf("inserting commune %s: %w", c.Code, err)
+			}
+			communes++
+		}
+	}
+	log.Printf("   Loaded %d daïras and %d communes", len(dairas), communes)
+	return linkCommunes(ctx)
+}
+
+// linkCommunes sets commune_code from the free-text city where it names a
+// commune of the same wilaya ("خنشلة المركز" is the commune خنشلة).
+func linkCommunes(ctx context.Context) error {
+	for _, table := range []string{"providers", "organizations", "erp_patients"} {
+		_, err := Pool.Exec(ctx,
+			`UPDATE `+table+` t SET commune_code = c.code
+			 FROM communes c
+			 WHERE t.commune_code IS NULL AND c.wilaya_id = t.wilaya_id
+			   AND (t.city = c.ar_name OR t.city = c.ar_name || ' المركز' OR LOWER(t.city) = LOWER(c.name))`)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedICD10 loads the sample ICD-10 codes into an empty catalog. Full
+// catalogs come from the admin import.
+func seedICD10(ctx context.Context, path string) error {
+	var count int
+	Pool.QueryRow(ctx, "SELECT COUNT(*) FROM icd10_codes").Scan(&count)
+	if count > 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var codes []models.ICD10Code
+	if err := json.Unmarshal(data, &codes); err != nil {
+		return err
+	}
+
+	for _, c := range codes {
+		_, err := Pool.Exec(ctx,
+			`INSERT INTO icd10_codes (code, label_fr, label_ar, label_en, source) VALUES ($1, $2, $3, $4, $5)
+			 ON CONFLICT (code) DO NOTHING`,
+			c.Code, c.LabelFr, c.LabelAr, c.LabelEn, models.ICD10SourceSeed)
+		if err != nil {
+			return fmt.Errorf("inserting ICD-10 code %s: %w", c.Code, err)
+		}
+	}
+	log.Printf("   Loaded %d ICD-10 codes", len(codes))
+	return RefreshICD10(ctx, Pool)
+}
+
+// execer is a pgx.Tx or the pool.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// RefreshICD10 derives each code's parent (J45.0 → J45, S06.00 → S06.0) and
+// whether it is billable after the catalog changed, then codes the visits
+// whose free-text diagnosis_code now names a catalog entry.
+func RefreshICD10(ctx context.Context, db execer) error {
+	_, err := db.Exec(ctx,
+		`UPDATE icd10_codes c SET parent_code = p.code
+		 FROM icd10_codes p
+		 WHERE p.code = rtrim(left(c.code, length(c.code) - 1), '.')
+		   AND c.parent_code IS DISTINCT FROM p.code`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx,
+		`UPDATE icd10_codes p SET is_billable = NOT EXISTS (
+			SELECT 1 FROM icd10_codes c WHERE c.parent_code = p.code AND c.is_active)
+		 WHERE p.is_billable = EXISTS (
+			SELECT 1 FROM icd10_codes c WHERE c.parent_code = p.code AND c.is_active)`)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(ctx,
+		`INSERT INTO visit_diagnoses (visit_id, code, is_primary)
+		 SELECT v.id, c.code, true
+		 FROM medical_visits v JOIN icd10_codes c ON c.code = UPPER(TRIM(v.diagnosis_code))
+		 WHERE NOT EXISTS (SELECT 1 FROM visit_diagnoses d WHERE d.visit_id = v.id)`)
+	return err
+}
+
+// seedMedications loads the sample formulary into an empty catalog. The
+// national nomenclature comes from the admin import.
+func seedMedications(ctx context.Context, path string) error {
+	var count int
+	Pool.QueryRow(ctx, "SELECT COUNT(*) FROM medications").Scan(&count)
+	if count > 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var medications []models.Medication
+	if err := json.Unmarshal(data, &medications); err != nil {
+		return err
+	}
+
+	for _, m := range medications {
+		_, err := Pool.Exec(ctx,
+			`INSERT INTO medications (registration_number, dci, brand, form, strength, packaging, laboratory, country,
+				is_generic, is_reimbursable, reference_price, source)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			 ON CONFLICT (registration_number) DO NOTHING`,
+			m.RegistrationNumber, m.DCI, m.Brand, m.Form, m.Strength, m.Packaging, m.Laboratory, m.Country,
+			m.IsGeneric, m.IsReimbursable, m.ReferencePrice, models.MedicationSourceSeed)
+		if err != nil {
+			return fmt.Errorf("inserting medication %s: %w", m.RegistrationNumber, err)
+		}
+	}
+	log.Printf("   Loaded %d medications", len(medications))
+	return nil
+}
+
+// seedDrugRules loads the drug classes and interaction rules into empty
+// tables. Rules are then maintained through the admin endpoints.
+func seedDrugRules(ctx context.Context, path string) error {
+	var count int
+	Pool.QueryRow(ctx, "SELECT (SELECT COUNT(*) FROM drug_classes) + (SELECT COUNT(*) FROM drug_interactions)").Scan(&count)
+	if count > 0 {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var rules models.DrugRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return err
+	}
+
+	for _, c := range rules.Classes {
+		_, err := Pool.Exec(ctx,
+			`INSERT INTO drug_classes (name, duplicate_check) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING`,
+			c.Name, c.DuplicateCheck)
+		if err != nil {
+			return fmt.Errorf("inserting drug class %s: %w", c.Name, err)
+		}
+		for _, dci := range c.Members {
+			_, err := Pool.Exec(ctx,
+				`INSERT INTO drug_class_members (class_name, dci) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+				c.Name, dci)
+			if err != nil {
+				return fmt.Errorf("inserting drug class %s: %w", c.Name, err)
+			}
+		}
+	}
+	for _, i := range rules.Interactions {
+		_, err := Pool.Exec(ctx,
+			`INSERT INTO drug_interactions (substance_a, substance_b, condition_code, severity,
+				description_fr, description_ar, description_en)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7)
+			 ON CONFLICT DO NOTHING`,
+			i.SubstanceA, i.SubstanceB, i.ConditionCode, i.Severity, i.DescriptionFr, i.DescriptionAr, i.DescriptionEn)
+		if err != nil {
+			return fmt.Errorf("inserting drug interaction %s: %w", i.SubstanceA, err)
+		}
+	}
+	log.Printf("   Loaded %d drug classes and %d interaction rules", len(rules.Classes), len(rules.Interactions))
+	return nil
+}
+
 func seedServices(ctx context.Context, path string) error {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -113,24 +356,36 @@ func seedProviders(ctx context.Context, path string) error {
 
 		// Insert services
 		for _, s := range p.Services {
-			var dName, dSpec, dExp, eName, eType, eOrigin string
-			if s.Doctor != nil {
-				dName = s.Doctor.Name
-				dSpec = s.Doctor.Specialty
-				dExp = s.Doctor.Experience
+			var doctorID, equipmentID *int
+			if s.Doctor != nil && s.Doctor.Name != "" {
+				var id int
+				err := Pool.QueryRow(ctx,
+					`INSERT INTO provider_doctors (provider_id, name, specialty, experience_years)
+					 VALUES ($1, $2, NULLIF($3, ''), NULLIF(substring($4 FROM '[0-9]+'), '')::INT)
+					 ON CONFLICT (provider_id, name) DO UPDATE SET specialty = EXCLUDED.specialty
+					 RETURNING id`,
+					p.ID, s.Doctor.Name, s.Doctor.Specialty, s.Doctor.Experience).Scan(&id)
+				if err == nil {
+					doctorID = &id
+				}
 			}
-			if s.Equipment != nil {
-				eName = s.Equipment.Name
-				eType = s.Equipment.Type
-				eOrigin = s.Equipment.Origin
+			if s.Equipment != nil && s.Equipment.Name != "" {
+				var id int
+				err := Pool.QueryRow(ctx,
+					`INSERT INTO provider_equipment (provider_id, name, type, origin)
+					 VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+					 ON CONFLICT (provider_id, name) DO UPDATE SET type = EXCLUDED.type
+					 RETURNING id`,
+					p.ID, s.Equipment.Name, s.Equipment.Type, s.Equipment.Origin).Scan(&id)
+				if err == nil {
+					equipmentID = &id
+				}
 			}
 
 			_, err := Pool.Exec(ctx,
-				`INSERT INTO provider_services (provider_id, service_id, name, price, turnaround,
-				 doctor_name, doctor_specialty, doctor_experience, equipment_name, equipment_type, equipment_origin)
-				 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
-				p.ID, s.ServiceID, s.Name, s.Price, s.Turnaround,
-				dName, dSpec, dExp, eName, eType, eOrigin)
+				`INSERT INTO provider_services (provider_id, service_id, name, price, turnaround, doctor_id, equipment_id)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+				p.ID, s.ServiceID, s.Name, s.Price, s.Turnaround, doctorID, equipmentID)
 			if err != nil {
 				log.Printf("   Warning: inserting service for %s: %v", p.ID, err)
 			}
@@ -0,0 +1,165 @@
+package document
+
+import (
+	"strings"
+	"unicode"
+)
+
+// PDF fonts draw one glyph per code point from left to right, without the
+// shaping and bidirectional ordering a text engine would apply. Arabic is
+// therefore converted to its presentation forms (the contextual glyph of each
+// letter) and laid out in visual order before drawing.
+
+// arabicForms holds the first presentation form of each letter. Letters that
+// join on both sides have four consecutive forms (isolated, final, initial,
+// medial); the others only join the preceding letter and have two, except
+// the hamza, which has a single isolated form.
+var arabicForms = map[rune]struct {
+	base rune
+	dual bool
+}{
+	'ء': {0xFE80, false}, 'آ': {0xFE81, false}, 'أ': {0xFE83, false}, 'ؤ': {0xFE85, false},
+	'إ': {0xFE87, false}, 'ئ': {0xFE89, true}, 'ا': {0xFE8D, false}, 'ب': {0xFE8F, true},
+	'ة': {0xFE93, false}, 'ت': {0xFE95, true}, 'ث': {0xFE99, true}, 'ج': {0xFE9D, true},
+	'ح': {0xFEA1, true}, 'خ': {0xFEA5, true}, 'د': {0xFEA9, false}, 'ذ': {0xFEAB, false},
+	'ر': {0xFEAD, false}, 'ز': {0xFEAF, false}, 'س': {0xFEB1, true}, 'ش': {0xFEB5, true},
+	'ص': {0xFEB9, true}, 'ض': {0xFEBD, true}, 'ط': {0xFEC1, true}, 'ظ': {0xFEC5, true},
+	'ع': {0xFEC9, true}, 'غ': {0xFECD, true}, 'ف': {0xFED1, true}, 'ق': {0xFED5, true},
+	'ك': {0xFED9, true}, 'ل': {0xFEDD, true}, 'م': {0xFEE1, true}, 'ن': {0xFEE5, true},
+	'ه': {0xFEE9, true}, 'و': {0xFEED, false}, 'ى': {0xFEEF, false}, 'ي': {0xFEF1, true},
+}
+
+// lamAlef maps the alef following a lam to the isolated form of their
+// ligature; the final form follows it.
+var lamAlef = map[rune]rune{'آ': 0xFEF5, 'أ': 0xFEF7, 'إ': 0xFEF9, 'ا': 0xFEFB}
+
+const tatweel = 'ـ'
+
+// mirrored brackets swap in right-to-left runs.
+var mirrored = map[rune]rune{'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{', '<': '>', '>': '<', '«': '»', '»': '«'}
+
+// HasArabic reports whether s contains Arabic letters.
+func HasArabic(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Arabic, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinsNext reports whether r connects to the letter after it.
+func joinsNext(r rune) bool {
+	f, ok := arabicForms[r]
+	return (ok && f.dual) || r == tatweel
+}
+
+// joinsPrevious reports whether r connects to the letter before it.
+func joinsPrevious(r rune) bool {
+	_, ok := arabicForms[r]
+	return (ok && r != 'ء') || r == tatweel
+}
+
+// shapeArabic replaces each letter with its contextual form. Diacritics are
+// dropped: they are rarely written in records and would break joining.
+func shapeArabic(s string) []rune {
+	var in []rune
+	for _, r := range s {
+		if !unicode.Is(unicode.Mn, r) {
+			in = append(in, r)
+		}
+	}
+
+	out := make([]rune, 0, len(in))
+	for i := 0; i < len(in); i++ {
+		r := in[i]
+		f, ok := arabicForms[r]
+		if !ok {
+			out = append(out, r)
+			continue
+		}
+		if r == 'ء' {
+			// The hamza never joins: its only form is the isolated one
+			out = append(out, f.base)
+			continue
+		}
+		prev := i > 0 && joinsNext(in[i-1])
+		if r == 'ل' && i+1 < len(in) {
+			if lig, ok := lamAlef[in[i+1]]; ok {
+				if prev {
+					lig++
+				}
+				out = append(out, lig)
+				i++
+				continue
+			}
+		}
+		next := f.dual && i+1 < len(in) && joinsPrevious(in[i+1])
+		switch {
+		case prev && next:
+			out = append(out, f.base+3)
+		case next:
+			out = append(out, f.base+2)
+		case prev:
+			out = append(out, f.base+1)
+		default:
+			out = append(out, f.base)
+		}
+	}
+	return out
+}
+
+// Visual returns a right-to-left line in the order it is drawn from left to
+// right: Arabic is shaped and reversed, while runs of Latin letters and
+// digits ("500 mg", "J45.0") keep their reading order.
+func Visual(s string) string {
+	shaped := shapeArabic(s)
+
+	// Split into runs; neutrals (spaces, punctuation) between two
+	// left-to-right characters stay in their run, others belong to the
+	// right-to-left paragraph.
+	type run struct {
+		ltr   bool
+		runes []rune
+	}
+	isLTR := func(r rune) bool {
+		return unicode.IsDigit(r) || (unicode.IsLetter(r) && !unicode.Is(unicode.Arabic, r))
+	}
+	var runs []run
+	for i := 0; i < len(shaped); i++ {
+		r := shaped[i]
+		ltr := isLTR(r)
+		if !ltr && !unicode.Is(unicode.Arabic, r) && len(runs) > 0 && runs[len(runs)-1].ltr {
+			// Neutral after a left-to-right run: keep it there if the run resumes
+			j := i
+			for j < len(shaped) && !isLTR(shaped[j]) && !unicode.Is(unicode.Arabic, shaped[j]) {
+				j++
+			}
+			if j < len(shaped) && isLTR(shaped[j]) {
+				runs[len(runs)-1].runes = append(runs[len(runs)-1].runes, shaped[i:j]...)
+				i = j - 1
+				continue
+			}
+		}
+		if len(runs) == 0 || runs[len(runs)-1].ltr != ltr {
+			runs = append(runs, run{ltr: ltr})
+		}
+		runs[len(runs)-1].runes = append(runs[len(runs)-1].runes, r)
+	}
+
+	var b strings.Builder
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].ltr {
+			b.WriteString(string(runs[i].runes))
+			continue
+		}
+		for j := len(runs[i].runes) - 1; j >= 0; j-- {
+			r := runs[i].runes[j]
+			if m, ok := mirrored[r]; ok {
+				r = m
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package document
+
+import "testing"
+
+func TestShapeArabic(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []rune
+	}{
+		{"isolated letter", "ب", []rune{0xFE8F}},
+		{"initial and final", "بب", []rune{0xFE91, 0xFE90}},
+		{"medial", "ببب", []rune{0xFE91, 0xFE92, 0xFE90}},
+		{"non-joining letter breaks the word", "داد", []rune{0xFEA9, 0xFE8D, 0xFEA9}},
+		{"alef joins the previous letter only", "با", []rune{0xFE91, 0xFE8E}},
+		{"lam alef ligature", "لا", []rune{0xFEFB}},
+		{"lam alef ligature after a joining letter", "سلا", []rune{0xFEB3, 0xFEFC}},
+		{"lam hamza alef ligature", "لأ", []rune{0xFEF7}},
+		{"hamza after a joining letter stays isolated", "شيء", []rune{0xFEB7, 0xFEF2, 0xFE80}},
+		{"hamza after a non-joining letter", "جزء", []rune{0xFE9F, 0xFEB0, 0xFE80}},
+		{"letter after hamza starts a new form", "ءب", []rune{0xFE80, 0xFE8F}},
+		{"taa marbuta", "مة", []rune{0xFEE3, 0xFE94}},
+		{"diacritics are dropped", "بَب", []rune{0xFE91, 0xFE90}},
+		{"tatweel joins both sides", "بـب", []rune{0xFE91, 'ـ', 0xFE90}},
+		{"latin is kept", "ab", []rune{'a', 'b'}},
+		{"empty", "", []rune{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := shapeArabic(tt.in)
+			if string(got) != string(tt.want) {
+				t.Errorf("shapeArabic(%q) = %U, want %U", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVisual(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"arabic is reversed", "بب", string([]rune{0xFE90, 0xFE91})},
+		{"words are reversed", "ب د", string([]rune{0xFEA9, ' ', 0xFE8F})},
+		{"numbers keep their order", "ب 500 mg", "500 mg " + string(rune(0xFE8F))},
+		{"code keeps its order", "J45.0 د", string(rune(0xFEA9)) + " J45.0"},
+		{"brackets are mirrored", "(ب)", "(" + string(rune(0xFE8F)) + ")"},
+		{"latin only", "Doliprane 1 g", "Doliprane 1 g"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Visual(tt.in); got != tt.want {
+				t.Errorf("Visual(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasArabic(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"Doliprane", false},
+		{"دواء", true},
+		{"Doliprane دواء", true},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := HasArabic(tt.in); got != tt.want {
+			t.Errorf("HasArabic(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
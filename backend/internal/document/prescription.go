@@ -0,0 +1,368 @@
+package document
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// defaultFont is DejaVu Sans Condensed, which covers Latin and the Arabic
+// presentation forms.
+//
+//go:embed fonts/DejaVuSansCondensed.ttf
+var defaultFont []byte
+
+// Renderer lays out printable documents. Arabic needs a TrueType font that
+// covers Latin and the Arabic presentation forms (DejaVu Sans, Amiri...);
+// without one, documents are printed in French only with a core font.
+type Renderer struct {
+	font []byte
+}
+
+// NewRenderer loads the font at fontPath, or uses the bundled one when the
+// path is empty. A font that cannot be read is reported, and the bundled one
+// is used instead.
+func NewRenderer(fontPath string) (*Renderer, error) {
+	if fontPath == "" {
+		return &Renderer{font: defaultFont}, nil
+	}
+	font, err := os.ReadFile(fontPath)
+	if err != nil {
+		return &Renderer{font: defaultFont}, err
+	}
+	return &Renderer{font: font}, nil
+}
+
+// Arabic reports whether documents include their Arabic parts.
+func (r *Renderer) Arabic() bool {
+	return r != nil && len(r.font) > 0
+}
+
+// Prescription is the content of a printed ordonnance.
+type Prescription struct {
+	Reference   string // short identifier printed under the QR code
+	Date        time.Time
+	RenewalOf   *time.Time // date of the renewed prescription
+	OrgName     string
+	OrgAddress  string
+	OrgPhone    string
+	Logo        []byte
+	LogoType    string // JPG, PNG or GIF
+	DoctorFr    string
+	DoctorAr    string
+	SpecialtyFr string
+	SpecialtyAr string
+	PatientFr   string
+	PatientAr   string
+	AgeYears    *int
+	Items       []PrescriptionLine
+	Notes       string
+	VerifyURL   string // encoded in the QR code
+}
+
+// PrescriptionLine is one medication of an ordonnance.
+type PrescriptionLine struct {
+	Medication   string
+	DCI          string // printed after the brand name
+	Dosage       string
+	Frequency    string
+	Duration     string
+	Quantity     *int
+	Instructions string
+}
+
+// page is an A5 portrait sheet with the font helpers of one document.
+type page struct {
+	pdf    *gofpdf.Fpdf
+	arabic bool
+	latin  func(string) string // converts text for the core font
+	left   float64
+	right  float64 // x of the right margin
+}
+
+func (r *Renderer) newPage() *page {
+	pdf := gofpdf.New("P", "mm", "A5", "")
+	pdf.SetMargins(12, 12, 12)
+	pdf.SetAutoPageBreak(true, 14)
+	p := &page{pdf: pdf, latin: func(s string) string { return s }}
+	if r.Arabic() {
+		pdf.AddUTF8FontFromBytes("doc", "", r.font)
+		p.arabic = true
+	} else {
+		pdf.SetFont("Helvetica", "", 10)
+		p.latin = pdf.UnicodeTranslatorFromDescriptor("")
+	}
+	pdf.AddPage()
+	width, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	p.left, p.right = left, width-right
+	return p
+}
+
+// font sets the size, and bold where the core font allows it (the embedded
+// font has a single style).
+func (p *page) font(size float64, bold bool) {
+	if p.arabic {
+		p.pdf.SetFont("doc", "", size)
+		return
+	}
+	style := ""
+	if bold {
+		style = "B"
+	}
+	p.pdf.SetFont("Helvetica", style, size)
+}
+
+// text writes one line in a cell of width w: align is L, C or R. Arabic text
+// is laid out right to left, and left out when there is no Arabic font.
+func (p *page) text(w, h float64, s, align string) {
+	switch {
+	case HasArabic(s) && !p.arabic:
+		s = p.latin(strings.TrimSpace(strings.Map(func(r rune) rune {
+			if unicode.Is(unicode.Arabic, r) {
+				return -1
+			}
+			return r
+		}, s)))
+	case HasArabic(s):
+		s = Visual(s)
+	default:
+		s = p.latin(s)
+	}
+	p.pdf.CellFormat(w, h, s, "", 0, align, false, 0, "")
+}
+
+// paragraph writes wrapped text across the page: left aligned, or right
+// aligned and wrapped in reading order when it is Arabic.
+func (p *page) paragraph(h float64, s string) {
+	if !HasArabic(s) {
+		p.pdf.SetX(p.left)
+		p.pdf.MultiCell(p.right-p.left, h, p.latin(s), "", "L", false)
+		return
+	}
+	if !p.arabic {
+		return
+	}
+	width := p.right - p.left
+	var line []string
+	flush := func() {
+		if len(line) > 0 {
+			p.pdf.SetX(p.left)
+			p.pdf.CellFormat(width, h, Visual(strings.Join(line, " ")), "", 1, "R", false, 0, "")
+			line = nil
+		}
+	}
+	for _, word := range strings.Fields(s) {
+		if len(line) > 0 && p.pdf.GetStringWidth(Visual(strings.Join(append(line, word), " "))) > width {
+			flush()
+		}
+		line = append(line, word)
+	}
+	flush()
+}
+
+// pair writes a French text on the left and an Arabic one on the right of
+// the same line; the Arabic one is left out without an Arabic font.
+func (p *page) pair(h float64, fr, ar string) {
+	half := (p.right - p.left) / 2
+	p.pdf.SetX(p.left)
+	p.text(half, h, fr, "L")
+	if p.arabic {
+		p.text(half, h, ar, "R")
+	}
+	p.pdf.Ln(h)
+}
+
+// Prescription renders an ordonnance as a PDF.
+func (r *Renderer) Prescription(doc Prescription) ([]byte, error) {
+	p := r.newPage()
+	pdf := p.pdf
+	pdf.SetTitle("Ordonnance "+doc.Reference, true)
+	pdf.SetCreator("ClinicLab", true)
+	width := p.right - p.left
+
+	// Organization header
+	top := pdf.GetY()
+	if len(doc.Logo) > 0 {
+		opts := gofpdf.ImageOptions{ImageType: doc.LogoType}
+		pdf.RegisterImageOptionsReader("logo", opts, bytes.NewReader(doc.Logo))
+		if !pdf.Err() {
+			pdf.ImageOptions("logo", p.left, top, 0, 16, false, opts, 0, "")
+		} else {
+			// An unreadable logo must not prevent printing
+			pdf.ClearError()
+		}
+	}
+	pdf.SetXY(p.left, top)
+	p.font(13, true)
+	p.text(width, 7, doc.OrgName, "C")
+	pdf.Ln(7)
+	p.font(8.5, false)
+	for _, line := range []string{doc.OrgAddress, doc.OrgPhone} {
+		if line != "" {
+			pdf.SetX(p.left)
+			p.text(width, 4.5, line, "C")
+			pdf.Ln(4.5)
+		}
+	}
+	pdf.SetY(max(pdf.GetY(), top+17))
+	pdf.SetDrawColor(120, 120, 120)
+	pdf.Line(p.left, pdf.GetY()+1, p.right, pdf.GetY()+1)
+	pdf.Ln(4)
+
+	// Prescriber
+	p.font(10.5, true)
+	p.pair(5.5, prefixed("Dr ", doc.DoctorFr), prefixed("الدكتور ", doc.DoctorAr))
+	p.font(9, false)
+	p.pair(4.5, doc.SpecialtyFr, doc.SpecialtyAr)
+	pdf.Ln(4)
+
+	// Title
+	p.font(15, true)
+	pdf.SetX(p.left)
+	p.text(width, 7, "ORDONNANCE", "C")
+	pdf.Ln(7)
+	if p.arabic {
+		p.font(13, false)
+		pdf.SetX(p.left)
+		p.text(width, 7, "وصفة طبية", "C")
+		pdf.Ln(7)
+	}
+	pdf.Ln(2)
+
+	// Date and patient
+	date := doc.Date.Format("02/01/2006")
+	p.font(9.5, false)
+	p.pair(5, "Le "+date, "بتاريخ "+date)
+	patientFr, patientAr := "Patient : "+firstNonEmpty(doc.PatientFr, doc.PatientAr), "المريض: "+firstNonEmpty(doc.PatientAr, doc.PatientFr)
+	if doc.AgeYears != nil {
+		patientFr += fmt.Sprintf(" (%d ans)", *doc.AgeYears)
+		patientAr += fmt.Sprintf(" (%d سنة)", *doc.AgeYears)
+	}
+	p.pair(5, patientFr, patientAr)
+	if doc.RenewalOf != nil {
+		renewed := doc.RenewalOf.Format("02/01/2006")
+		p.font(8.5, false)
+		p.pair(4.5, "Renouvellement de l'ordonnance du "+renewed, "تجديد الوصفة المؤرخة في "+renewed)
+	}
+	pdf.Ln(5)
+
+	// Medications
+	for i, item := range doc.Items {
+		p.font(10.5, true)
+		pdf.SetX(p.left)
+		name := joinNonEmpty(" ", item.Medication, item.Dosage)
+		if item.DCI != "" && !strings.EqualFold(item.DCI, item.Medication) {
+			name += " (" + item.DCI + ")"
+		}
+		p.text(width, 5.5, strconv.Itoa(i+1)+". "+name, "L")
+		pdf.Ln(5.5)
+		p.font(9.5, false)
+		quantity := ""
+		if item.Quantity != nil {
+			quantity = "Qté : " + strconv.Itoa(*item.Quantity)
+		}
+		if line := joinNonEmpty(" - ", item.Frequency, item.Duration, quantity); line != "" {
+			pdf.SetX(p.left + 5)
+			p.text(width-5, 4.8, line, "L")
+			pdf.Ln(4.8)
+		}
+		if item.Instructions != "" {
+			p.paragraph(4.8, item.Instructions)
+		}
+		pdf.Ln(2.5)
+	}
+	if doc.Notes != "" {
+		pdf.Ln(2)
+		p.font(9, false)
+		p.paragraph(4.5, doc.Notes)
+	}
+
+	// Verification code and signature, kept together at the bottom
+	_, height := pdf.GetPageSize()
+	if pdf.GetY() > height-52 {
+		pdf.AddPage()
+	}
+	y := height - 48
+	if doc.VerifyURL != "" {
+		code, err := qr.Encode(doc.VerifyURL, qr.M, qr.Auto)
+		if err == nil {
+			code, err = barcode.Scale(code, 240, 240)
+		}
+		var buf bytes.Buffer
+		if err == nil {
+			// Barcodes are 16-bit gray, which PDF images do not support
+			gray := image.NewGray(code.Bounds())
+			draw.Draw(gray, gray.Bounds(), code, code.Bounds().Min, draw.Src)
+			err = png.Encode(&buf, gray)
+		}
+		if err != nil {
+			return nil, err
+		}
+		opts := gofpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader("qr", opts, &buf)
+		pdf.ImageOptions("qr", p.left, y, 26, 26, false, opts, 0, "")
+		p.font(7, false)
+		pdf.SetXY(p.left-2, y+26)
+		p.text(30, 3.5, doc.Reference, "C")
+		pdf.SetXY(p.left-2, y+29.5)
+		p.text(30, 3.5, "Vérification", "C")
+		if p.arabic {
+			pdf.SetXY(p.left-2, y+33)
+			p.text(30, 3.5, "التحقق", "C")
+		}
+	}
+	p.font(8.5, false)
+	pdf.SetXY(p.right-55, y)
+	p.text(55, 4.5, "Signature et cachet", "C")
+	if p.arabic {
+		pdf.SetXY(p.right-55, y+4.5)
+		p.text(55, 4.5, "الإمضاء والختم", "C")
+	}
+	pdf.SetDrawColor(180, 180, 180)
+	pdf.Rect(p.right-55, y+10, 55, 22, "D")
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func prefixed(prefix, s string) string {
+	if s == "" {
+		return ""
+	}
+	return prefix + s
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func joinNonEmpty(sep string, values ...string) string {
+	var parts []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/anis7x/cliniclab/internal/cache"
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/jackc/pgx/v5"
+)
+
+// canManageProvider reports whether the user owns the listing directly or is
+// an active member of the organization behind it.
+func canManageProvider(ctx context.Context, userID, providerID string) bool {
+	var ok bool
+	database.Pool.QueryRow(ctx,
+		`SELECT EXISTS(
+			SELECT 1 FROM providers p
+			WHERE p.id = $1 AND (p.user_id = $2 OR EXISTS(
+				SELECT 1 FROM org_members m
+				WHERE m.org_id = p.org_id AND m.user_id = $2 AND m.is_active)))`,
+		providerID, userID).Scan(&ok)
+	return ok
+}
+
+// activeOrgID returns the organization the user is currently working in.
+func activeOrgID(ctx context.Context, userID string) (string, error) {
+	var orgID *string
+	err := database.Pool.QueryRow(ctx,
+		`SELECT u.active_org_id FROM users u
+		 JOIN org_members m ON m.org_id = u.active_org_id AND m.user_id = u.id AND m.is_active
+		 WHERE u.id = $1`, userID).Scan(&orgID)
+	if err != nil {
+		return "", err
+	}
+	if orgID == nil {
+		return "", pgx.ErrNoRows
+	}
+	return *orgID, nil
+}
+
+// orgLanguageCache avoids a lookup on every authenticated request.
+var orgLanguageCache = cache.New(5 * time.Minute)
+
+// OrgDefaultLanguage returns the default_language of the user's active
+// organization, or "" for users without one. Used by middleware.InitLocale.
+func OrgDefaultLanguage(ctx context.Context, userID string) string {
+	if v, ok := orgLanguageCache.Get(userID); ok {
+		return v.(string)
+	}
+	var lang string
+	database.Pool.QueryRow(ctx,
+		`SELECT COALESCE(o.default_language, '') FROM users u
+		 JOIN org_members m ON m.org_id = u.active_org_id AND m.user_id = u.id AND m.is_active
+		 JOIN organizations o ON o.id = u.active_org_id
+		 WHERE u.id = $1`, userID).Scan(&lang)
+	orgLanguageCache.Set(userID, lang)
+	return lang
+}
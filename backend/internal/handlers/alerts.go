@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/anis7x/cliniclab/internal/notify"
+)
+
+// RunSavedSearchAlerts checks every saved search with alerts on each
+// interval until ctx is cancelled, and tells patients about new providers
+// and lower prices through n.
+func RunSavedSearchAlerts(ctx context.Context, n notify.Notifier, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkSavedSearches(ctx, n)
+		}
+	}
+}
+
+type savedSearchJob struct {
+	id, userID, email, name, query, lang string
+}
+
+// checkSavedSearches runs one pass over the saved searches. A search whose
+// notification fails keeps its baseline so the alert is retried next pass.
+func checkSavedSearches(ctx context.Context, n notify.Notifier) {
+	rows, err := database.Pool.Query(ctx,
+		`SELECT s.id, s.user_id, u.email, s.name, s.query, s.lang
+		 FROM saved_searches s
+		 JOIN users u ON u.id = s.user_id
+		 WHERE s.notify
+		 ORDER BY s.last_checked_at NULLS FIRST`)
+	if err != nil {
+		log.Printf("⚠️ Saved search alerts: %v", err)
+		return
+	}
+	var jobs []savedSearchJob
+	for rows.Next() {
+		var j savedSearchJob
+		if rows.Scan(&j.id, &j.userID, &j.email, &j.name, &j.query, &j.lang) == nil {
+			jobs = append(jobs, j)
+		}
+	}
+	rows.Close()
+
+	for _, j := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := checkSavedSearch(ctx, n, j); err != nil {
+			log.Printf("⚠️ Saved search %s: %v", j.id, err)
+		}
+	}
+}
+
+func checkSavedSearch(ctx context.Context, n notify.Notifier, j savedSearchJob) error {
+	matches, err := savedSearchMatches(ctx, j.query)
+	if err != nil {
+		return err
+	}
+
+	previous := map[string]*int{}
+	rows, err := database.Pool.Query(ctx,
+		`SELECT provider_id, min_price FROM saved_search_matches WHERE saved_search_id = $1`, j.id)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id string
+		var price *int
+		if rows.Scan(&id, &price) == nil {
+			previous[id] = price
+		}
+	}
+	rows.Close()
+
+	var alerts []models.SearchAlert
+	for _, m := range matches {
+		old, seen := previous[m.ProviderID]
+		switch {
+		case !seen:
+			alerts = append(alerts, models.SearchAlert{
+				Kind: models.AlertNewProvider, ProviderID: m.ProviderID, ProviderName: m.Name, Price: m.Price,
+			})
+		case old != nil && m.Price > 0 && m.Price < *old:
+			alerts = append(alerts, models.SearchAlert{
+				Kind: models.AlertLowerPrice, ProviderID: m.ProviderID, ProviderName: m.Name,
+				Price: m.Price, OldPrice: *old,
+			})
+		}
+	}
+
+	if len(alerts) > 0 {
+		if err := n.Notify(ctx, savedSearchMessage(j, alerts)); err != nil {
+			return err
+		}
+	}
+
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := replaceSavedSearchMatches(ctx, tx, j.id, matches); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE saved_searches SET last_checked_at = NOW() WHERE id = $1`, j.id); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// savedSearchMessage renders the alerts in the language the search was saved in.
+func savedSearchMessage(j savedSearchJob, alerts []models.SearchAlert) notify.Message {
+	lines := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		switch a.Kind {
+		case models.AlertNewProvider:
+			lines = append(lines, fmt.Sprintf(i18n.T(j.lang, "مزود جديد: %s"), a.ProviderName))
+		case models.AlertLowerPrice:
+			lines = append(lines, fmt.Sprintf(i18n.T(j.lang, "سعر أقل لدى %s: %d ← %d دج"),
+				a.ProviderName, a.OldPrice, a.Price))
+		}
+	}
+	return notify.Message{
+		UserID:  j.userID,
+		Email:   j.email,
+		Lang:    j.lang,
+		Kind:    "saved_search",
+		Subject: fmt.Sprintf(i18n.T(j.lang, "نتائج جديدة لبحثك \"%s\""), j.name),
+		Body:    strings.Join(lines, "\n"),
+		Data: map[string]interface{}{
+			"saved_search_id": j.id,
+			"query":           j.query,
+			"alerts":          alerts,
+		},
+	}
+}
@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// substanceAccents folds the accents of upper-case French names.
+var substanceAccents = strings.NewReplacer("É", "E", "È", "E", "Ê", "E", "Ë", "E", "À", "A", "Â", "A",
+	"Î", "I", "Ï", "I", "Ô", "O", "Û", "U", "Ù", "U", "Ü", "U", "Ç", "C")
+
+// normalizeSubstance writes a DCI, class or brand the way the formulary and
+// the rules store them: upper case, no accents, single spaces.
+func normalizeSubstance(s string) string {
+	return substanceAccents.Replace(strings.ToUpper(strings.Join(strings.Fields(s), " ")))
+}
+
+const allergySelect = `SELECT a.id, a.patient_id, a.substance, COALESCE(a.reaction, ''), a.severity,
+	COALESCE(s.full_name_ar, ''), a.created_at
+	FROM patient_allergies a
+	LEFT JOIN staff s ON s.id = a.recorded_by`
+
+func scanAllergy(row pgx.Row) (models.PatientAllergy, error) {
+	var a models.PatientAllergy
+	err := row.Scan(&a.ID, &a.PatientID, &a.Substance, &a.Reaction, &a.Severity, &a.RecordedBy, &a.CreatedAt)
+	return a, err
+}
+
+// ListPatientAllergies handles GET /api/org/patients/{id}/allergies
+func ListPatientAllergies(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	patient, err := loadERPPatient(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+
+	rows, err := database.Pool.Query(ctx,
+		allergySelect+` WHERE a.patient_id = $1 AND a.org_id = $2 ORDER BY a.substance`, patient.ID, orgID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الحساسيات")
+		return
+	}
+	defer rows.Close()
+
+	allergies := []models.PatientAllergy{}
+	for rows.Next() {
+		if a, err := scanAllergy(rows); err == nil {
+			allergies = append(allergies, a)
+		}
+	}
+	writeJSON(w, http.StatusOK, allergies)
+}
+
+// AddPatientAllergy handles POST /api/org/patients/{id}/allergies
+func AddPatientAllergy(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	patient, err := loadERPPatient(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+
+	var in models.PatientAllergyInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	in.Substance = normalizeSubstance(in.Substance)
+	in.Reaction = strings.TrimSpace(in.Reaction)
+	if in.Severity == "" {
+		in.Severity = models.AllergyModerate
+	}
+	switch {
+	case in.Substance == "" || utf8.RuneCountInString(in.Substance) > 255:
+		writeError(w, http.StatusBadRequest, "المادة المسببة للحساسية مطلوبة ولا تتجاوز 255 حرفاً")
+		return
+	case utf8.RuneCountInString(in.Reaction) > 255:
+		writeError(w, http.StatusBadRequest, "وصف رد الفعل لا يتجاوز 255 حرفاً")
+		return
+	case !containsString([]string{models.AllergyMild, models.AllergyModerate, models.AllergySevere}, in.Severity):
+		writeError(w, http.StatusBadRequest, "درجة الحساسية غير صالحة")
+		return
+	}
+
+	var exists bool
+	database.Pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM patient_allergies WHERE patient_id = $1 AND substance = $2)`,
+		patient.ID, in.Substance).Scan(&exists)
+	if exists {
+		writeError(w, http.StatusConflict, "هذه الحساسية مسجلة مسبقاً")
+		return
+	}
+
+	var id string
+	err = database.Pool.QueryRow(ctx,
+		`INSERT INTO patient_allergies (org_id, patient_id, substance, reaction, severity, recorded_by)
+		 VALUES ($1, $2, $3, NULLIF($4, ''), $5, (SELECT id FROM staff WHERE org_id = $1 AND user_id = $6 LIMIT 1))
+		 RETURNING id`,
+		orgID, patient.ID, in.Substance, in.Reaction, in.Severity, middleware.GetClaims(r).UserID).Scan(&id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	a, err := scanAllergy(database.Pool.QueryRow(ctx, allergySelect+` WHERE a.id = $1`, id))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	writeJSON(w, http.StatusCreated, a)
+}
+
+// DeletePatientAllergy handles DELETE /api/org/patients/{id}/allergies/{allergyId}
+// The removed entry is kept in audit_log.
+func DeletePatientAllergy(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	a, err := scanAllergy(database.Pool.QueryRow(ctx,
+		allergySelect+` WHERE a.id::text = $1 AND a.patient_id::text = $2 AND a.org_id = $3`,
+		chi.URLParam(r, "allergyId"), chi.URLParam(r, "id"), orgID))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "الحساسية غير موجودة")
+		return
+	}
+
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM patient_allergies WHERE id = $1`, a.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	if err := recordAudit(ctx, tx, r, orgID, "allergy.delete", "patient_allergy", a.ID, a, nil); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تم حذف الحساسية")})
+}
+
+const conditionSelect = `SELECT c.id, c.patient_id, c.label, c.icd10_code, to_char(c.since, 'YYYY-MM-DD'),
+	COALESCE(s.full_name_ar, ''), c.created_at
+	FROM patient_conditions c
+	LEFT JOIN staff s ON s.id = c.recorded_by`
+
+func scanCondition(row pgx.Row) (models.PatientCondition, error) {
+	var c models.PatientCondition
+	err := row.Scan(&c.ID, &c.PatientID, &c.Label, &c.ICD10Code, &c.Since, &c.RecordedBy, &c.CreatedAt)
+	return c, err
+}
+
+// ListPatientConditions handles GET /api/org/patients/{id}/conditions
+func ListPatientConditions(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	patient, err := loadERPPatient(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+
+	rows, err := database.Pool.Query(ctx,
+		conditionSelect+` WHERE c.patient_id = $1 AND c.org_id = $2 ORDER BY c.since NULLS LAST, c.label`,
+		patient.ID, orgID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الأمراض المزمنة")
+		return
+	}
+	defer rows.Close()
+
+	conditions := []models.PatientCondition{}
+	for rows.Next() {
+		if c, err := scanCondition(rows); err == nil {
+			conditions = append(conditions, c)
+		}
+	}
+	writeJSON(w, http.StatusOK, conditions)
+}
+
+// AddPatientCondition handles POST /api/org/patients/{id}/conditions
+func AddPatientCondition(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	patient, err := loadERPPatient(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+
+	var in models.PatientConditionInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	in.Label = strings.TrimSpace(in.Label)
+	if in.ICD10Code != nil && strings.TrimSpace(*in.ICD10Code) == "" {
+		in.ICD10Code = nil
+	}
+	if in.ICD10Code != nil {
+		code, ok := normalizeICD10(*in.ICD10Code)
+		var labelAr, labelFr, labelEn string
+		if ok {
+			err = database.Pool.QueryRow(ctx,
+				`SELECT COALESCE(label_ar, ''), COALESCE(label_fr, ''), COALESCE(label_en, '')
+				 FROM icd10_codes WHERE code = $1 AND is_active`, code).Scan(&labelAr, &labelFr, &labelEn)
+		}
+		if !ok || err != nil {
+			writeError(w, http.StatusBadRequest, "رمز التشخيص غير موجود في التصنيف")
+			return
+		}
+		in.ICD10Code = &code
+		if in.Label == "" {
+			in.Label = i18n.Pick(middleware.GetLang(r), labelAr, labelFr, labelEn)
+		}
+	}
+	if in.Label == "" || utf8.RuneCountInString(in.Label) > 255 {
+		writeError(w, http.StatusBadRequest, "اسم المرض مطلوب ولا يتجاوز 255 حرفاً")
+		return
+	}
+	if in.Since != nil && *in.Since == "" {
+		in.Since = nil
+	}
+	if in.Since != nil {
+		since, err := time.Parse("2006-01-02", *in.Since)
+		if err != nil || since.After(time.Now()) {
+			writeError(w, http.StatusBadRequest, "تاريخ بداية المرض غير صالح")
+			return
+		}
+	}
+
+	var id string
+	err = database.Pool.QueryRow(ctx,
+		`INSERT INTO patient_conditions (org_id, patient_id, label, icd10_code, since, recorded_by)
+		 VALUES ($1, $2, $3, $4, $5::date, (SELECT id FROM staff WHERE org_id = $1 AND user_id = $6 LIMIT 1))
+		 RETURNING id`,
+		orgID, patient.ID, in.Label, in.ICD10Code, in.Since, middleware.GetClaims(r).UserID).Scan(&id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	c, err := scanCondition(database.Pool.QueryRow(ctx, conditionSelect+` WHERE c.id = $1`, id))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	writeJSON(w, http.StatusCreated, c)
+}
+
+// DeletePatientCondition handles DELETE /api/org/patients/{id}/conditions/{conditionId}
+// The removed entry is kept in audit_log.
+func DeletePatientCondition(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	c, err := scanCondition(database.Pool.QueryRow(ctx,
+		conditionSelect+` WHERE c.id::text = $1 AND c.patient_id::text = $2 AND c.org_id = $3`,
+		chi.URLParam(r, "conditionId"), chi.URLParam(r, "id"), orgID))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المرض غير موجود")
+		return
+	}
+
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM patient_conditions WHERE id = $1`, c.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	if err := recordAudit(ctx, tx, r, orgID, "condition.delete", "patient_condition", c.ID, c, nil); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تم حذف المرض")})
+}
@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	searchLogBuffer        = 4096
+	searchLogBatch         = 200
+	searchLogFlushInterval = 5 * time.Second
+	searchLogRetention     = 365 * 24 * time.Hour
+	maxLoggedTermLength    = 100
+
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 365
+	zeroResultsPerWilaya = 5
+)
+
+var (
+	emailPattern      = regexp.MustCompile(`\S+@\S+`)
+	longNumberPattern = regexp.MustCompile(`[0-9][0-9 .\-]{5,}[0-9]`)
+	searchTermSpaces  = regexp.MustCompile(`\s+`)
+)
+
+// searchEvents is drained by RunSearchLogger.
+var searchEvents = make(chan searchEvent, searchLogBuffer)
+
+// analyticsQueryExpr is what a search looked for: its text, or the service
+// IDs when it used service_id. Searches by filters only yield NULL.
+const analyticsQueryExpr = `COALESCE(e.term, NULLIF(array_to_string(e.service_ids, ','), ''))`
+
+// searchEvent is one SearchProviders call, already anonymized.
+type searchEvent struct {
+	at         time.Time
+	term       string
+	serviceIDs []string
+	wilayas    []string
+	filters    map[string]interface{}
+	results    int
+	latency    time.Duration
+}
+
+// logSearch queues a search for the background writer. It never blocks the
+// request: events are dropped when the writer falls behind.
+func logSearch(q models.SearchQuery, f *searchFilter, results int, latency time.Duration) {
+	e := searchEvent{
+		at:      time.Now(),
+		term:    anonymizeSearchTerm(q.Service),
+		wilayas: q.Wilayas,
+		results: results,
+		latency: latency,
+		filters: map[string]interface{}{},
+	}
+	if f != nil && f.filterServices {
+		e.serviceIDs = f.serviceIDs
+	}
+	for name, values := range map[string][]string{
+		"commune": q.Communes, "type": q.Types, "category": q.Categories, "specialty": q.Specialties,
+		"equipment_type": q.EquipmentTypes, "origin": q.Origins, "price": q.Prices,
+	} {
+		if len(values) > 0 {
+			e.filters[name] = values
+		}
+	}
+	if q.MinExperience > 0 {
+		e.filters["min_experience"] = q.MinExperience
+	}
+	select {
+	case searchEvents <- e:
+	default:
+	}
+}
+
+// anonymizeSearchTerm normalizes free text and masks emails and phone-like
+// numbers, which patients sometimes paste into the search box.
+func anonymizeSearchTerm(s string) string {
+	s = emailPattern.ReplaceAllString(s, "*")
+	s = longNumberPattern.ReplaceAllString(s, "#")
+	s = strings.ToLower(strings.TrimSpace(searchTermSpaces.ReplaceAllString(s, " ")))
+	if utf8.RuneCountInString(s) > maxLoggedTermLength {
+		s = string([]rune(s)[:maxLoggedTermLength])
+	}
+	return s
+}
+
+// RunSearchLogger writes queued search events in batches until ctx is
+// cancelled, and purges events past the retention period once a day.
+func RunSearchLogger(ctx context.Context) {
+	flush := time.NewTicker(searchLogFlushInterval)
+	defer flush.Stop()
+	purge := time.NewTicker(24 * time.Hour)
+	defer purge.Stop()
+
+	batch := make([]searchEvent, 0, searchLogBatch)
+	write := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := writeSearchEvents(ctx, batch); err != nil {
+			log.Printf("⚠️ Search log: %v", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case e := <-searchEvents:
+			batch = append(batch, e)
+			if len(batch) >= searchLogBatch {
+				write(ctx)
+			}
+		case <-flush.C:
+			write(ctx)
+		case <-purge.C:
+			if _, err := database.Pool.Exec(ctx, `DELETE FROM search_events WHERE searched_at < $1`,
+				time.Now().Add(-searchLogRetention)); err != nil {
+				log.Printf("⚠️ Search log purge: %v", err)
+			}
+		case <-ctx.Done():
+			// Drain what is already queued before the server exits
+			for len(searchEvents) > 0 {
+				batch = append(batch, <-searchEvents)
+			}
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			write(shutdownCtx)
+			cancel()
+			return
+		}
+	}
+}
+
+// writeSearchEvents inserts a batch, resolving wilaya names to IDs.
+func writeSearchEvents(ctx context.Context, events []searchEvent) error {
+	b := &pgx.Batch{}
+	for _, e := range events {
+		b.Queue(
+			`INSERT INTO search_events (searched_at, term, service_ids, wilaya_ids, filters, result_count, latency_ms)
+			 VALUES ($1, NULLIF($2, ''), $3,
+			         ARRAY(SELECT DISTINCT COALESCE(
+			             (SELECT w.id FROM wilayas w WHERE w.id = v OR w.ar_name = v OR LOWER(w.name) = LOWER(v) LIMIT 1), v)
+			           FROM unnest($4::text[]) v),
+			         $5, $6, $7)`,
+			e.at, e.term, nonNilStrings(e.serviceIDs), nonNilStrings(e.wilayas), e.filters, e.results,
+			int(e.latency.Milliseconds()))
+	}
+	return database.Pool.SendBatch(ctx, b).Close()
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// GetSearchAnalytics handles GET /api/admin/search-analytics?days=&wilaya=&limit=
+// Reports the last `days` days (default 30), optionally for one wilaya ID.
+func GetSearchAnalytics(w http.ResponseWriter, r *http.Request) {
+	days, err := strconv.Atoi(r.URL.Query().Get("days"))
+	if err != nil || days < 1 {
+		days = defaultAnalyticsDays
+	}
+	if days > maxAnalyticsDays {
+		days = maxAnalyticsDays
+	}
+	_, limit, _ := parsePagination(r)
+	wilayaID := r.URL.Query().Get("wilaya")
+	lang := middleware.GetLang(r)
+
+	now := time.Now().UTC()
+	report := models.SearchAnalytics{
+		From:         now.AddDate(0, 0, -days),
+		To:           now,
+		WilayaID:     wilayaID,
+		TopQueries:   []models.QueryStat{},
+		ZeroResults:  []models.ZeroResultStat{},
+		DemandSupply: []models.DemandSupplyStat{},
+	}
+
+	// $1 from, $2 wilaya ('' for all)
+	ctx := context.Background()
+	const window = ` FROM search_events e
+		WHERE e.searched_at >= $1 AND ($2 = '' OR $2 = ANY(e.wilaya_ids))`
+	args := []interface{}{report.From, wilayaID}
+
+	var zero int
+	err = database.Pool.QueryRow(ctx,
+		`SELECT COUNT(*), COUNT(*) FILTER (WHERE e.result_count = 0), COALESCE(AVG(e.latency_ms), 0)`+window,
+		args...).Scan(&report.Searches, &zero, &report.AvgLatencyMs)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حساب الإحصائيات")
+		return
+	}
+	if report.Searches > 0 {
+		report.ZeroResultRate = float64(zero) / float64(report.Searches)
+	}
+
+	rows, err := database.Pool.Query(ctx,
+		`SELECT `+analyticsQueryExpr+` AS query, COUNT(*),
+		        COUNT(*) FILTER (WHERE e.result_count = 0), AVG(e.result_count)`+window+`
+		   AND `+analyticsQueryExpr+` IS NOT NULL
+		 GROUP BY query ORDER BY COUNT(*) DESC, query LIMIT $3`, append(args, limit)...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حساب الإحصائيات")
+		return
+	}
+	for rows.Next() {
+		var s models.QueryStat
+		if rows.Scan(&s.Query, &s.Searches, &s.ZeroResults, &s.AvgResults) == nil {
+			report.TopQueries = append(report.TopQueries, s)
+		}
+	}
+	rows.Close()
+
+	// Top zero-result queries per wilaya; searches without a wilaya count under ""
+	rows, err = database.Pool.Query(ctx,
+		`SELECT wilaya_id, COALESCE(wl.ar_name, ''), COALESCE(wl.name, ''), query, searches FROM (
+			SELECT wid AS wilaya_id, query, COUNT(*) AS searches,
+			       ROW_NUMBER() OVER (PARTITION BY wid ORDER BY COUNT(*) DESC, query) AS rank
+			FROM (
+				SELECT `+analyticsQueryExpr+` AS query,
+				       unnest(CASE WHEN cardinality(e.wilaya_ids) = 0 THEN ARRAY[''] ELSE e.wilaya_ids END) AS wid`+window+`
+				  AND e.result_count = 0
+			) z
+			WHERE query IS NOT NULL AND ($2 = '' OR wid = $2)
+			GROUP BY wid, query
+		 ) ranked
+		 LEFT JOIN wilayas wl ON wl.id = ranked.wilaya_id
+		 WHERE rank <= $3
+		 ORDER BY wilaya_id, searches DESC, query`, append(args, zeroResultsPerWilaya)...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حساب الإحصائيات")
+		return
+	}
+	for rows.Next() {
+		var s models.ZeroResultStat
+		var arName, latinName string
+		if rows.Scan(&s.WilayaID, &arName, &latinName, &s.Query, &s.Searches) == nil {
+			s.Wilaya = i18n.Pick(lang, arName, latinName, latinName)
+			report.ZeroResults = append(report.ZeroResults, s)
+		}
+	}
+	rows.Close()
+
+	// Demand: searches per resolved service. Supply: published providers
+	// offering it (in the wilaya, when one is selected).
+	rows, err = database.Pool.Query(ctx,
+		`WITH demand AS (
+			SELECT sid, COUNT(*) AS searches, COUNT(*) FILTER (WHERE result_count = 0) AS zero
+			FROM (SELECT unnest(e.service_ids) AS sid, e.result_count`+window+`) d
+			GROUP BY sid
+		 ), supply AS (
+			SELECT ps.service_id AS sid, COUNT(DISTINCT ps.provider_id) AS providers
+			FROM provider_services ps
+			JOIN providers p ON p.id = ps.provider_id
+			WHERE p.is_published AND ($2 = '' OR p.wilaya_id = $2)
+			GROUP BY ps.service_id
+		 )
+		 SELECT d.sid, COALESCE(ms.name, d.sid), COALESCE(ms.name_fr, ''), COALESCE(ms.name_en, ''),
+		        d.searches, d.zero, COALESCE(s.providers, 0)
+		 FROM demand d
+		 LEFT JOIN supply s ON s.sid = d.sid
+		 LEFT JOIN medical_services ms ON ms.id = d.sid
+		 ORDER BY COALESCE(s.providers, 0) = 0 DESC,
+		          d.searches::float / GREATEST(COALESCE(s.providers, 0), 1) DESC, d.sid
+		 LIMIT $3`, append(args, limit)...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حساب الإحصائيات")
+		return
+	}
+	for rows.Next() {
+		var s models.DemandSupplyStat
+		var ar, fr, en string
+		if rows.Scan(&s.ServiceID, &ar, &fr, &en, &s.Searches, &s.ZeroResults, &s.Providers) == nil {
+			s.Label = i18n.Pick(lang, ar, fr, en)
+			if s.Providers > 0 {
+				ratio := float64(s.Searches) / float64(s.Providers)
+				s.SearchesPerProvider = &ratio
+			}
+			report.DemandSupply = append(report.DemandSupply, s)
+		}
+	}
+	rows.Close()
+
+	writeJSON(w, http.StatusOK, report)
+}
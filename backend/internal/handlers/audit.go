@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// execer is a pgx.Tx or the pool.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// recordAudit writes an audit_log row. Changes pass their transaction, so the
+// entry exists if and only if the change does; reads pass the pool. staff_id
+// is filled when the user has a staff record in the organization.
+func recordAudit(ctx context.Context, db execer, r *http.Request, orgID, action, entityType, entityID string, oldValues, newValues interface{}) error {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	userID := middleware.GetClaims(r).UserID
+	_, err := db.Exec(ctx,
+		`INSERT INTO audit_log (org_id, user_id, staff_id, action, entity_type, entity_id, old_values, new_values, ip_address)
+		 VALUES ($1, $2, (SELECT id FROM staff WHERE org_id = $1 AND user_id = $2 LIMIT 1), $3, $4, $5, $6, $7, $8)`,
+		orgID, userID, action, entityType, entityID, oldValues, newValues, ip)
+	return err
+}
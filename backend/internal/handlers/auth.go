@@ -34,6 +34,11 @@ func RegisterPatient(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "كلمة المرور يجب أن تكون 8 أحرف على الأقل")
 		return
 	}
+	req.NIN = strings.TrimSpace(req.NIN)
+	if req.NIN != "" && !ninPattern.MatchString(req.NIN) {
+		writeError(w, http.StatusBadRequest, "رقم التعريف الوطني يجب أن يتكون من 18 رقما")
+		return
+	}
 
 	var exists bool
 	err := database.Pool.QueryRow(context.Background(),
@@ -70,9 +75,9 @@ func RegisterPatient(w http.ResponseWriter, r *http.Request) {
 	}
 
 	_, err = tx.Exec(context.Background(),
-		`INSERT INTO profiles_patient (user_id, full_name, phone, date_of_birth, gender)
-		 VALUES ($1, $2, $3, NULLIF($4, '')::DATE, NULLIF($5, ''))`,
-		userID, req.FullName, req.Phone, req.DateOfBirth, req.Gender)
+		`INSERT INTO profiles_patient (user_id, full_name, phone, date_of_birth, gender, nin)
+		 VALUES ($1, $2, $3, NULLIF($4, '')::DATE, NULLIF($5, ''), NULLIF($6, ''))`,
+		userID, req.FullName, req.Phone, req.DateOfBirth, req.Gender, req.NIN)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "خطأ في إنشاء الملف الشخصي")
 		return
@@ -325,7 +330,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	if lockedUntil != nil && time.Now().Before(*lockedUntil) {
 		remaining := time.Until(*lockedUntil).Minutes()
 		writeJSON(w, http.StatusTooManyRequests, map[string]interface{}{
-			"error":             "الحساب مقفل مؤقتاً بسبب محاولات فاشلة متعددة",
+			"error":             localize(w, "الحساب مقفل مؤقتاً بسبب محاولات فاشلة متعددة"),
 			"locked":            true,
 			"minutes_remaining": int(remaining) + 1,
 		})
@@ -342,7 +347,7 @@ func Login(w http.ResponseWriter, r *http.Request) {
 				`UPDATE users SET failed_login_attempts = $1, locked_until = $2 WHERE id = $3`,
 				newAttempts, lockUntil, user.ID)
 			writeJSON(w, http.StatusTooManyRequests, map[string]interface{}{
-				"error":             "تم قفل الحساب لمدة 15 دقيقة بسبب محاولات فاشلة متعددة",
+				"error":             localize(w, "تم قفل الحساب لمدة 15 دقيقة بسبب محاولات فاشلة متعددة"),
 				"locked":            true,
 				"minutes_remaining": 15,
 			})
@@ -498,7 +503,7 @@ func Setup2FA(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, http.StatusOK, models.Setup2FAResponse{
 		RecoveryCodes: recoveryCodes,
-		Message:       "تم تفعيل المصادقة الثنائية بنجاح",
+		Message:       localize(w, "تم تفعيل المصادقة الثنائية بنجاح"),
 	})
 }
 
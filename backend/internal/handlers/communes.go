@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetDairas handles GET /api/wilayas/{id}/dairas
+func GetDairas(w http.ResponseWriter, r *http.Request) {
+	wilayaID := chi.URLParam(r, "id")
+	lang := middleware.GetLang(r)
+	key := "dairas:" + wilayaID + ":" + lang
+	if v, ok := referenceCache.Get(key); ok {
+		writeCached(w, r, v.(cachedResponse), referenceCacheControl)
+		return
+	}
+
+	ctx := context.Background()
+	if !wilayaExists(ctx, wilayaID) {
+		writeError(w, http.StatusNotFound, "الولاية غير موجودة")
+		return
+	}
+	rows, err := database.Pool.Query(ctx,
+		`SELECT code, wilaya_id, name, ar_name FROM dairas WHERE wilaya_id = $1 ORDER BY code`, wilayaID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب البلديات")
+		return
+	}
+	defer rows.Close()
+
+	result := []models.Daira{}
+	for rows.Next() {
+		var d models.Daira
+		if rows.Scan(&d.Code, &d.WilayaID, &d.Name, &d.ArName) == nil {
+			d.Label = i18n.Pick(lang, d.ArName, d.Name, d.Name)
+			result = append(result, d)
+		}
+	}
+
+	res, err := newCachedResponse(result, time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في الخادم")
+		return
+	}
+	referenceCache.Set(key, res)
+	writeCached(w, r, res, referenceCacheControl)
+}
+
+// GetCommunes handles GET /api/wilayas/{id}/communes?daira=
+func GetCommunes(w http.ResponseWriter, r *http.Request) {
+	wilayaID := chi.URLParam(r, "id")
+	daira := r.URL.Query().Get("daira")
+	lang := middleware.GetLang(r)
+	key := "communes:" + wilayaID + ":" + daira + ":" + lang
+	if v, ok := referenceCache.Get(key); ok {
+		writeCached(w, r, v.(cachedResponse), referenceCacheControl)
+		return
+	}
+
+	ctx := context.Background()
+	if !wilayaExists(ctx, wilayaID) {
+		writeError(w, http.StatusNotFound, "الولاية غير موجودة")
+		return
+	}
+	rows, err := database.Pool.Query(ctx,
+		`SELECT code, daira_code, wilaya_id, name, ar_name FROM communes
+		 WHERE wilaya_id = $1 AND ($2 = '' OR daira_code = $2)
+		 ORDER BY code`, wilayaID, daira)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب البلديات")
+		return
+	}
+	defer rows.Close()
+
+	result := []models.Commune{}
+	for rows.Next() {
+		var c models.Commune
+		if rows.Scan(&c.Code, &c.DairaCode, &c.WilayaID, &c.Name, &c.ArName) == nil {
+			c.Label = i18n.Pick(lang, c.ArName, c.Name, c.Name)
+			result = append(result, c)
+		}
+	}
+
+	res, err := newCachedResponse(result, time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في الخادم")
+		return
+	}
+	referenceCache.Set(key, res)
+	writeCached(w, r, res, referenceCacheControl)
+}
+
+func wilayaExists(ctx context.Context, id string) bool {
+	var exists bool
+	database.Pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM wilayas WHERE id = $1)`, id).Scan(&exists)
+	return exists
+}
+
+// communeInWilaya reports whether a commune code belongs to a wilaya.
+func communeInWilaya(ctx context.Context, code, wilayaID string) bool {
+	var ok bool
+	database.Pool.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM communes WHERE code = $1 AND wilaya_id = $2)`, code, wilayaID).Scan(&ok)
+	return ok
+}
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+)
+
+const (
+	minCompared = 2
+	maxCompared = 5
+)
+
+// compareValue is a cell before ranking. Unranked cells (unknown or not
+// comparable values) never win a row.
+type compareValue struct {
+	value  interface{}
+	score  float64
+	ranked bool
+}
+
+// CompareProviders handles GET /api/providers/compare?ids=&services=&lat=&lng=
+// ids lists 2–5 providers; services lists taxonomy IDs and defaults to every
+// service offered by at least one of them. The distance row needs lat and lng.
+func CompareProviders(w http.ResponseWriter, r *http.Request) {
+	ids := uniqueStrings(multiParam(r, "ids"))
+	if len(ids) < minCompared || len(ids) > maxCompared {
+		writeError(w, http.StatusBadRequest, "اختر من 2 إلى 5 مزودين للمقارنة")
+		return
+	}
+
+	var origin *[2]float64
+	if latStr, lngStr := r.URL.Query().Get("lat"), r.URL.Query().Get("lng"); latStr != "" || lngStr != "" {
+		lat, err1 := strconv.ParseFloat(latStr, 64)
+		lng, err2 := strconv.ParseFloat(lngStr, 64)
+		if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			writeError(w, http.StatusBadRequest, "الإحداثيات غير صالحة")
+			return
+		}
+		origin = &[2]float64{lat, lng}
+	}
+
+	ctx := context.Background()
+	lang := middleware.GetLang(r)
+	providers := make([]models.Provider, 0, len(ids))
+	for _, id := range ids {
+		p, _, err := loadProvider(ctx, id, lang)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "المزود غير موجود")
+			return
+		}
+		providers = append(providers, p)
+	}
+
+	serviceIDs := uniqueStrings(multiParam(r, "services"))
+	if len(serviceIDs) == 0 {
+		for _, p := range providers {
+			for _, s := range p.Services {
+				if s.ServiceID != "" {
+					serviceIDs = append(serviceIDs, s.ServiceID)
+				}
+			}
+		}
+		serviceIDs = uniqueStrings(serviceIDs)
+	}
+	services, err := servicesByID(ctx, serviceIDs, lang)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الخدمة")
+		return
+	}
+	if len(services) != len(serviceIDs) {
+		writeError(w, http.StatusNotFound, "الخدمة غير موجودة")
+		return
+	}
+
+	result := models.Comparison{Providers: providers, Services: services, Rows: []models.ComparisonRow{}}
+	for _, svc := range services {
+		offers := make([]*models.ProviderService, len(providers))
+		for i := range providers {
+			offers[i] = cheapestOffer(&providers[i], svc.ID)
+		}
+		result.Rows = append(result.Rows,
+			compareRow(lang, models.CompareRowPrice, "السعر", svc.ID, providers, true, func(i int) compareValue {
+				if offers[i] == nil {
+					return compareValue{}
+				}
+				return compareValue{offers[i].Price, float64(offers[i].Price), true}
+			}),
+			compareRow(lang, models.CompareRowTurnaround, "مدة الإنجاز", svc.ID, providers, true, func(i int) compareValue {
+				if offers[i] == nil || offers[i].Turnaround == "" {
+					return compareValue{}
+				}
+				minutes, ok := turnaroundMinutes(offers[i].Turnaround)
+				return compareValue{offers[i].Turnaround, float64(minutes), ok}
+			}),
+			// Machines have no objective order, so this row never has a best cell
+			compareRow(lang, models.CompareRowEquipment, "الجهاز", svc.ID, providers, false, func(i int) compareValue {
+				if offers[i] == nil || offers[i].Equipment == nil {
+					return compareValue{}
+				}
+				return compareValue{value: offers[i].Equipment}
+			}),
+			compareRow(lang, models.CompareRowDoctor, "الطبيب", svc.ID, providers, false, func(i int) compareValue {
+				if offers[i] == nil || offers[i].Doctor == nil {
+					return compareValue{}
+				}
+				d := offers[i].Doctor
+				return compareValue{d, float64(d.ExperienceYears), d.ExperienceYears > 0}
+			}),
+		)
+	}
+
+	result.Rows = append(result.Rows,
+		compareRow(lang, models.CompareRowRating, "التقييم", "", providers, false, func(i int) compareValue {
+			p := providers[i]
+			return compareValue{p.Rating, p.Rating, p.ReviewsCount > 0}
+		}),
+		compareRow(lang, models.CompareRowOpenHours, "أوقات العمل", "", providers, false, func(i int) compareValue {
+			if providers[i].OpenHours == "" {
+				return compareValue{}
+			}
+			hours, ok := dailyOpenHours(providers[i].OpenHours)
+			return compareValue{providers[i].OpenHours, hours, ok}
+		}),
+	)
+	if origin != nil {
+		result.Rows = append(result.Rows,
+			compareRow(lang, models.CompareRowDistance, "المسافة (كم)", "", providers, true, func(i int) compareValue {
+				p := providers[i]
+				if p.Latitude == nil || p.Longitude == nil {
+					return compareValue{}
+				}
+				km := math.Round(haversineKm(origin[0], origin[1], *p.Latitude, *p.Longitude)*10) / 10
+				return compareValue{km, km, true}
+			}))
+	}
+
+	writeJSON(w, http.StatusOK, result)
+}
+
+// compareRow builds a row and flags every cell that ties for the best score.
+func compareRow(lang, key, label, serviceID string, providers []models.Provider, lowerIsBetter bool, cell func(i int) compareValue) models.ComparisonRow {
+	row := models.ComparisonRow{
+		Key:       key,
+		Label:     i18n.T(lang, label),
+		ServiceID: serviceID,
+		Cells:     make([]models.ComparisonCell, len(providers)),
+	}
+	values := make([]compareValue, len(providers))
+	best, found := 0.0, false
+	for i, p := range providers {
+		values[i] = cell(i)
+		row.Cells[i] = models.ComparisonCell{ProviderID: p.ID, Value: values[i].value}
+		if !values[i].ranked {
+			continue
+		}
+		if s := values[i].score; !found || (lowerIsBetter && s < best) || (!lowerIsBetter && s > best) {
+			best, found = s, true
+		}
+	}
+	for i := range values {
+		row.Cells[i].Best = values[i].ranked && values[i].score == best
+	}
+	return row
+}
+
+// cheapestOffer returns the provider's cheapest listing of a service, or nil.
+func cheapestOffer(p *models.Provider, serviceID string) *models.ProviderService {
+	var best *models.ProviderService
+	for i := range p.Services {
+		s := &p.Services[i]
+		if s.ServiceID == serviceID && (best == nil || s.Price < best.Price) {
+			best = s
+		}
+	}
+	return best
+}
+
+// servicesByID loads taxonomy services in the given order, skipping unknown IDs.
+func servicesByID(ctx context.Context, ids []string, lang string) ([]models.MedicalService, error) {
+	services := []models.MedicalService{}
+	if len(ids) == 0 {
+		return services, nil
+	}
+	rows, err := database.Pool.Query(ctx,
+		`SELECT id, name, category, COALESCE(name_fr, ''), COALESCE(name_en, ''), COALESCE(category_id, '')
+		 FROM medical_services WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := map[string]models.MedicalService{}
+	for rows.Next() {
+		var s models.MedicalService
+		if err := rows.Scan(&s.ID, &s.Name, &s.Category, &s.NameFr, &s.NameEn, &s.CategoryID); err != nil {
+			return nil, err
+		}
+		s.Label = i18n.Pick(lang, s.Name, s.NameFr, s.NameEn)
+		byID[s.ID] = s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if s, ok := byID[id]; ok {
+			services = append(services, s)
+		}
+	}
+	return services, nil
+}
+
+// turnaroundUnits maps the unit words of turnaround text to minutes.
+var turnaroundUnits = map[string]int{
+	"دقيقة": 1, "دقائق": 1, "min": 1, "mn": 1, "mins": 1, "minute": 1, "minutes": 1,
+	"ساعة": 60, "ساعات": 60, "h": 60, "hr": 60, "hrs": 60, "heure": 60, "heures": 60, "hour": 60, "hours": 60,
+	"يوم": 24 * 60, "أيام": 24 * 60, "j": 24 * 60, "jour": 24 * 60, "jours": 24 * 60, "day": 24 * 60, "days": 24 * 60,
+}
+
+// turnaroundMinutes converts turnaround text such as "فوري", "30 دقيقة",
+// "24 ساعة", "24h" or "2 jours" to minutes. Text without a duration
+// ("موعد مسبق") or in another unit ("3 months") cannot be ranked.
+func turnaroundMinutes(s string) (int, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, instant := range []string{"فوري", "immédiat", "immediat", "instant"} {
+		if strings.Contains(s, instant) {
+			return 0, true
+		}
+	}
+	n := parseExperience(s)
+	if n <= 0 {
+		return 0, false
+	}
+	// The unit is the word right after the number
+	start := strings.IndexAny(s, "0123456789")
+	rest := strings.TrimLeft(s[start:], "0123456789")
+	words := strings.FieldsFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) })
+	if len(words) == 0 {
+		return 0, false
+	}
+	minutes, ok := turnaroundUnits[words[0]]
+	if !ok {
+		return 0, false
+	}
+	return n * minutes, true
+}
+
+// dailyOpenHours returns how many hours a day a provider is open, from
+// "08:00 - 18:00" (overnight ranges wrap) or "24/7".
+func dailyOpenHours(s string) (float64, bool) {
+	if s == "24/7" {
+		return 24, true
+	}
+	from, to, ok := strings.Cut(s, " - ")
+	if !ok {
+		return 0, false
+	}
+	start, ok1 := clockMinutes(from)
+	end, ok2 := clockMinutes(to)
+	if !ok1 || !ok2 {
+		return 0, false
+	}
+	if end <= start {
+		end += 24 * 60
+	}
+	return float64(end-start) / 60, true
+}
+
+// clockMinutes parses "HH:MM" as minutes after midnight.
+func clockMinutes(s string) (int, bool) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, false
+	}
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return 0, false
+	}
+	return hour*60 + minute, true
+}
+
+// haversineKm is the great-circle distance between two points, in kilometres.
+func haversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadiusKm = 6371
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// uniqueStrings drops repeated values, keeping the first occurrence.
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	out := values[:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import "testing"
+
+func TestTurnaroundMinutes(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   int
+		wantOK bool
+	}{
+		{"فوري", 0, true},
+		{"Immédiat", 0, true},
+		{"30 دقيقة", 30, true},
+		{"45 min", 45, true},
+		{"1 ساعة", 60, true},
+		{"24 ساعة", 24 * 60, true},
+		{"24h", 24 * 60, true},
+		{"48 H", 48 * 60, true},
+		{"12 heures", 12 * 60, true},
+		{"6 hours", 6 * 60, true},
+		{"2 jours", 2 * 24 * 60, true},
+		{"3j", 3 * 24 * 60, true},
+		{"1 jour ouvré", 24 * 60, true},
+		{"5 days", 5 * 24 * 60, true},
+		{"3 أيام", 3 * 24 * 60, true},
+		{"3 months", 0, false},
+		{"2 semaines", 0, false},
+		{"5 juin", 0, false},
+		{"10 hj", 0, false},
+		{"موعد مسبق", 0, false},
+		{"جلسة واحدة", 0, false},
+		{"24", 0, false},
+		{"0 h", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := turnaroundMinutes(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("turnaroundMinutes(%q) = %d, %v, want %d, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
+
+	"github.com/anis7x/cliniclab/internal/i18n"
 )
 
 // JSON helper: write a JSON response.
@@ -15,9 +18,15 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	}
 }
 
-// Error helper: write a JSON error response.
+// Error helper: write a JSON error response in the language chosen by
+// middleware.Locale (announced in Content-Language).
 func writeError(w http.ResponseWriter, status int, message string) {
-	writeJSON(w, status, map[string]string{"error": message})
+	writeJSON(w, status, map[string]string{"error": localize(w, message)})
+}
+
+// Message helper: translate a catalog message for this response.
+func localize(w http.ResponseWriter, message string) string {
+	return i18n.T(w.Header().Get("Content-Language"), message)
 }
 
 // Decode helper: parse JSON request body.
@@ -25,3 +34,16 @@ func decodeJSON(r *http.Request, v interface{}) error {
 	defer r.Body.Close()
 	return json.NewDecoder(r.Body).Decode(v)
 }
+
+// Pagination helper: read ?page=&limit= with sane bounds.
+func parsePagination(r *http.Request) (page, limit, offset int) {
+	page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit, (page - 1) * limit
+}
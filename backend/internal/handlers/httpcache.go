@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anis7x/cliniclab/internal/cache"
+	"github.com/anis7x/cliniclab/internal/i18n"
+)
+
+// Cache-Control policies. Reference data only changes with a deploy;
+// provider pages change when owners edit them, so clients revalidate sooner.
+const (
+	referenceCacheControl = "public, max-age=3600"
+	providerCacheControl  = "public, max-age=60, must-revalidate"
+)
+
+var (
+	// referenceCache holds wilayas and services; it lives until restart
+	// unless invalidateReferenceData is called.
+	referenceCache = cache.New(24 * time.Hour)
+	// providerCache holds provider detail pages keyed by "<id>:<lang>".
+	providerCache = cache.New(5 * time.Minute)
+)
+
+// cachedResponse is an encoded JSON body with its validators.
+type cachedResponse struct {
+	body         []byte
+	etag         string
+	lastModified time.Time
+}
+
+func newCachedResponse(v interface{}, lastModified time.Time) (cachedResponse, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return cachedResponse{}, err
+	}
+	sum := sha256.Sum256(body)
+	return cachedResponse{
+		body:         body,
+		etag:         `"` + hex.EncodeToString(sum[:8]) + `"`,
+		lastModified: lastModified.UTC().Truncate(time.Second),
+	}, nil
+}
+
+// writeCached sends res with ETag, Last-Modified and Cache-Control, or a
+// 304 when the client's copy is still current.
+func writeCached(w http.ResponseWriter, r *http.Request, res cachedResponse, cacheControl string) {
+	h := w.Header()
+	h.Set("ETag", res.etag)
+	h.Set("Last-Modified", res.lastModified.Format(http.TimeFormat))
+	h.Set("Cache-Control", cacheControl)
+
+	if notModified(r, res) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(res.body)
+}
+
+// notModified applies If-None-Match, falling back to If-Modified-Since only
+// when no ETag was sent (RFC 9110 §13.2.2).
+func notModified(r *http.Request, res cachedResponse) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == res.etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !res.lastModified.After(t)
+		}
+	}
+	return false
+}
+
+// invalidateProvider drops the cached page of one provider and the price
+// stats that may include its services.
+func invalidateProvider(providerID string) {
+	for _, lang := range i18n.Languages {
+		providerCache.Delete(providerID + ":" + lang)
+	}
+	priceStatsCache.Clear()
+	suggestCache.Clear()
+}
+
+// invalidateProviders drops every cached provider page, for writes that
+// touch listings by organization rather than by provider ID.
+func invalidateProviders() {
+	providerCache.Clear()
+	priceStatsCache.Clear()
+	suggestCache.Clear()
+}
+
+// invalidateReferenceData drops cached wilayas and services.
+func invalidateReferenceData() {
+	referenceCache.Clear()
+	suggestCache.Clear()
+}
+
+// FlushCaches handles POST /api/admin/cache/flush (platform admins)
+// For reference or listing data edited directly in the database.
+func FlushCaches(w http.ResponseWriter, r *http.Request) {
+	invalidateReferenceData()
+	invalidateProviders()
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تم مسح الذاكرة المؤقتة")})
+}
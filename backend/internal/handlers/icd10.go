@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	maxICD10ImportBytes = 32 << 20
+	maxSkippedLines     = 20
+	maxVisitDiagnoses   = 10
+)
+
+// icd10Dotless is a code without its dot: a letter, two digits and up to four
+// subdivision characters (ATIH extensions use "+").
+var icd10Dotless = regexp.MustCompile(`^[A-Z][0-9]{2}[0-9A-Z+]{0,4}$`)
+
+// icd10Labels maps a language to its label column.
+var icd10Labels = map[string]string{"fr": "label_fr", "ar": "label_ar", "en": "label_en"}
+
+// normalizeICD10 returns code in catalog form: upper case, dagger and asterisk
+// dropped, dot after the category (j450, J45.0* → J45.0).
+func normalizeICD10(code string) (string, bool) {
+	code = strings.ToUpper(strings.Join(strings.Fields(code), ""))
+	code = strings.TrimRight(code, "*†")
+	code = strings.Replace(code, ".", "", 1)
+	if !icd10Dotless.MatchString(code) {
+		return "", false
+	}
+	if len(code) > 3 {
+		code = code[:3] + "." + code[3:]
+	}
+	return code, true
+}
+
+const icd10Select = `SELECT code, parent_code, COALESCE(label_fr, ''), COALESCE(label_ar, ''), COALESCE(label_en, ''),
+	is_billable, is_active FROM icd10_codes`
+
+func scanICD10(row pgx.Row, lang string) (models.ICD10Code, error) {
+	var c models.ICD10Code
+	err := row.Scan(&c.Code, &c.ParentCode, &c.LabelFr, &c.LabelAr, &c.LabelEn, &c.IsBillable, &c.IsActive)
+	c.Label = i18n.Pick(lang, c.LabelAr, c.LabelFr, c.LabelEn)
+	return c, err
+}
+
+// SearchICD10 handles GET /api/icd10?q=&parent=&billable=&page=&limit=
+// q matches a code prefix (J45, j450) or words of the labels in any language;
+// close spellings ("diabete") also match. parent lists the codes under one
+// code. Inactive codes are never listed.
+func SearchICD10(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	lang := middleware.GetLang(r)
+	page, limit, offset := parsePagination(r)
+	query := r.URL.Query()
+
+	args := sqlArgs{}
+	where := ` WHERE is_active`
+	var order []string
+	if query.Get("billable") == "true" {
+		where += ` AND is_billable`
+	}
+	if parent := query.Get("parent"); parent != "" {
+		code, _ := normalizeICD10(parent)
+		where += ` AND parent_code = ` + args.add(code)
+	}
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		var conds []string
+		// Code matches rank before label matches
+		if code, ok := normalizeICD10(q); ok {
+			p := args.add(code)
+			conds = append(conds, `code LIKE `+p+` || '%'`)
+			order = append(order, `code = `+p+` DESC`, `code LIKE `+p+` || '%' DESC`)
+		}
+		var words []string
+		for _, word := range strings.Fields(q) {
+			p := args.add("%" + word + "%")
+			words = append(words, `(label_fr ILIKE `+p+` OR label_ar ILIKE `+p+` OR label_en ILIKE `+p+`)`)
+		}
+		p := args.add(q)
+		conds = append(conds, `(`+strings.Join(words, " AND ")+`)`,
+			`(`+p+` <% label_fr OR `+p+` <% label_ar OR `+p+` <% label_en)`)
+		where += ` AND (` + strings.Join(conds, " OR ") + `)`
+		order = append(order, `GREATEST(word_similarity(`+p+`, COALESCE(label_fr, '')),
+			word_similarity(`+p+`, COALESCE(label_ar, '')), word_similarity(`+p+`, COALESCE(label_en, ''))) DESC`)
+	}
+	order = append(order, `code`)
+
+	var total int
+	if err := database.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM icd10_codes`+where, args...).Scan(&total); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب رموز التشخيص")
+		return
+	}
+	sql := icd10Select + where + ` ORDER BY ` + strings.Join(order, ", ") + ` LIMIT ` + args.add(limit) + ` OFFSET ` + args.add(offset)
+	rows, err := database.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب رموز التشخيص")
+		return
+	}
+	defer rows.Close()
+
+	codes := []models.ICD10Code{}
+	for rows.Next() {
+		if c, err := scanICD10(rows, lang); err == nil {
+			codes = append(codes, c)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, models.Page{Items: codes, Page: page, Limit: limit, Total: total})
+}
+
+// GetICD10Code handles GET /api/icd10/{code}
+func GetICD10Code(w http.ResponseWriter, r *http.Request) {
+	code, ok := normalizeICD10(chi.URLParam(r, "code"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "رمز التشخيص غير موجود في التصنيف")
+		return
+	}
+	c, err := scanICD10(database.Pool.QueryRow(context.Background(),
+		icd10Select+` WHERE code = $1`, code), middleware.GetLang(r))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "رمز التشخيص غير موجود في التصنيف")
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// icd10Entry is one code read from an import file; empty labels keep the
+// stored ones.
+type icd10Entry struct {
+	code                      string
+	labelFr, labelAr, labelEn string
+}
+
+// ImportICD10 handles POST /api/admin/icd10/import?format=who|atih|csv&lang=&replace=
+// The "file" part is one of:
+//   - who: the WHO codes file (icd102019syst_codes.txt), ";"-separated, code
+//     in the 6th field and title in the 9th; lang defaults to en
+//   - atih: the ATIH CIM-10 FR file (LIBCIM10MULTI.TXT), "|"-separated, code
+//     first and label last; lang defaults to fr
+//   - csv: a header with code and any of label_fr, label_ar, label_en,
+//     separated by "," or ";"
+//
+// Latin-1 files are converted. Codes are upserted; with replace=true the
+// active codes missing from the file are deactivated.
+func ImportICD10(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	lang := query.Get("lang")
+	switch format {
+	case "who":
+		if lang == "" {
+			lang = "en"
+		}
+	case "atih":
+		if lang == "" {
+			lang = "fr"
+		}
+	case "csv":
+		lang = ""
+	default:
+		writeError(w, http.StatusBadRequest, "صيغة الملف غير مدعومة (who أو atih أو csv)")
+		return
+	}
+	if lang != "" && icd10Labels[lang] == "" {
+		writeError(w, http.StatusBadRequest, "اللغة غير مدعومة")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxICD10ImportBytes+64<<10)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			writeError(w, http.StatusRequestEntityTooLarge, "حجم الملف يتجاوز الحد المسموح")
+		} else {
+			writeError(w, http.StatusBadRequest, "الملف مطلوب")
+		}
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "الملف مطلوب")
+		return
+	}
+	if !utf8.Valid(data) {
+		data = latin1ToUTF8(data)
+	}
+	data = bytes.TrimPrefix(data, []byte("\uFEFF"))
+
+	result := models.ICD10Import{Source: strings.ToUpper(format), Lang: lang, SkippedAt: []string{}}
+	skip := func(line int, text string) {
+		result.Skipped++
+		if len(result.SkippedAt) < maxSkippedLines {
+			result.SkippedAt = append(result.SkippedAt, "line "+strconv.Itoa(line)+": "+text)
+		}
+	}
+	var entries []icd10Entry
+	if format == "csv" {
+		entries, err = readICD10CSV(data, skip)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "ملف CSV غير صالح، يجب أن يحتوي على عمود code")
+			return
+		}
+	} else {
+		entries = readICD10Lines(data, format, lang, skip)
+	}
+	if len(entries) == 0 {
+		writeError(w, http.StatusBadRequest, "لم يتم العثور على أي رمز في الملف")
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في استيراد التصنيف")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	codes := make([]string, 0, len(entries))
+	for _, e := range entries {
+		batch.Queue(`INSERT INTO icd10_codes (code, label_fr, label_ar, label_en, source)
+			VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), $5)
+			ON CONFLICT (code) DO UPDATE SET
+				label_fr = COALESCE(EXCLUDED.label_fr, icd10_codes.label_fr),
+				label_ar = COALESCE(EXCLUDED.label_ar, icd10_codes.label_ar),
+				label_en = COALESCE(EXCLUDED.label_en, icd10_codes.label_en),
+				source = EXCLUDED.source, is_active = true, updated_at = NOW()`,
+			e.code, e.labelFr, e.labelAr, e.labelEn, result.Source)
+		codes = append(codes, e.code)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في استيراد التصنيف")
+		return
+	}
+	result.Imported = len(entries)
+	if query.Get("replace") == "true" {
+		tag, err := tx.Exec(ctx,
+			`UPDATE icd10_codes SET is_active = false, updated_at = NOW()
+			 WHERE is_active AND NOT (code = ANY($1))`, codes)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "خطأ في استيراد التصنيف")
+			return
+		}
+		result.Deactivated = int(tag.RowsAffected())
+	}
+	if err := database.RefreshICD10(ctx, tx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في استيراد التصنيف")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في استيراد التصنيف")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// readICD10Lines parses the WHO and ATIH files. Lines without a valid code
+// (headers, chapters, blocks) are skipped.
+func readICD10Lines(data []byte, format, lang string, skip func(int, string)) []icd10Entry {
+	sep, codeField, labelField := ";", 5, 8
+	if format == "atih" {
+		sep, codeField, labelField = "|", 0, -1
+	}
+	seen := map[string]bool{}
+	var entries []icd10Entry
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, sep)
+		label := ""
+		if labelField < 0 {
+			// Last non-empty field: the long label of LIBCIM10MULTI
+			for j := len(fields) - 1; j > 0 && label == ""; j-- {
+				label = strings.TrimSpace(fields[j])
+			}
+		} else if labelField < len(fields) {
+			label = fields[labelField]
+		}
+		var code string
+		ok := codeField < len(fields) && len(fields) > 1
+		if ok {
+			code, ok = normalizeICD10(fields[codeField])
+		}
+		label = strings.TrimSpace(label)
+		if !ok || label == "" {
+			skip(i+1, line)
+			continue
+		}
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		e := icd10Entry{code: code}
+		switch lang {
+		case "fr":
+			e.labelFr = label
+		case "ar":
+			e.labelAr = label
+		default:
+			e.labelEn = label
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// readICD10CSV parses a CSV with a header row.
+func readICD10CSV(data []byte, skip func(int, string)) ([]icd10Entry, error) {
+	header, _, _ := strings.Cut(string(data), "\n")
+	reader := csv.NewReader(bytes.NewReader(data))
+	if strings.Count(header, ";") > strings.Count(header, ",") {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil || len(records) == 0 {
+		return nil, errors.New("invalid csv")
+	}
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["code"]; !ok {
+		return nil, errors.New("missing code column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	seen := map[string]bool{}
+	var entries []icd10Entry
+	for i, record := range records[1:] {
+		code, ok := normalizeICD10(field(record, "code"))
+		e := icd10Entry{code: code, labelFr: field(record, "label_fr"),
+			labelAr: field(record, "label_ar"), labelEn: field(record, "label_en")}
+		if !ok || e.labelFr+e.labelAr+e.labelEn == "" {
+			skip(i+2, strings.Join(record, string(reader.Comma)))
+			continue
+		}
+		if !seen[code] {
+			seen[code] = true
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// latin1ToUTF8 converts ISO-8859-1 text, the encoding of the ATIH files.
+func latin1ToUTF8(data []byte) []byte {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return []byte(string(runes))
+}
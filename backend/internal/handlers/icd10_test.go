@@ -0,0 +1,34 @@
+package handlers
+
+import "testing"
+
+func TestNormalizeICD10(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{"J45.0", "J45.0", true},
+		{"j450", "J45.0", true},
+		{"J45", "J45", true},
+		{" j 45 . 0 ", "J45.0", true},
+		{"J45.0*", "J45.0", true},
+		{"A17.0†", "A17.0", true},
+		{"E11.65", "E11.65", true},
+		{"U07.1", "U07.1", true},
+		{"M21.6X1", "M21.6X1", true},
+		{"", "", false},
+		{"J4", "", false},
+		{"45.0", "", false},
+		{"J45..0", "", false},
+		{"J45.0.1", "", false},
+		{"J45.01234", "", false},
+		{"JJ5.0", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := normalizeICD10(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("normalizeICD10(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
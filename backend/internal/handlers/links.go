@@ -0,0 +1,472 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	linkCodeLength = 8
+	linkCodeTTL    = 15 * time.Minute
+
+	// No 0/O or 1/I, which are easily misread at a reception desk
+	linkCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+// linkSelect reads a link in the column order of scanPatientLink.
+const linkSelect = `SELECT l.id, l.org_id, o.name, l.patient_id, p.full_name_ar, l.user_id,
+	l.method, COALESCE(l.matched_by, ''), l.status, l.created_at, l.responded_at, l.revoked_at
+	FROM patient_links l
+	JOIN organizations o ON o.id = l.org_id
+	JOIN erp_patients p ON p.id = l.patient_id`
+
+// openLinkStatus matches links that still bind or may bind a record.
+const openLinkStatus = `l.status IN ('PENDING', 'ACTIVE')`
+
+var (
+	errLinkNotOpen      = errors.New("link is not open")
+	errAccountLinked    = errors.New("account already linked in organization")
+	errAmbiguousAccount = errors.New("several accounts match the patient")
+)
+
+func scanPatientLink(row pgx.Row) (models.PatientLink, error) {
+	var l models.PatientLink
+	err := row.Scan(&l.ID, &l.OrgID, &l.OrgName, &l.PatientID, &l.PatientName, &l.UserID,
+		&l.Method, &l.MatchedBy, &l.Status, &l.CreatedAt, &l.RespondedAt, &l.RevokedAt)
+	return l, err
+}
+
+// auditLinkAccess records an event of a link, or an access to the record
+// made through it, under entity type "patient_link".
+func auditLinkAccess(ctx context.Context, db execer, r *http.Request, l models.PatientLink, action string) error {
+	return recordAudit(ctx, db, r, l.OrgID, "patient_link."+action, "patient_link", l.ID, nil,
+		map[string]string{"patient_id": l.PatientID, "user_id": l.UserID})
+}
+
+// hashLinkCode normalizes a code as typed ("abcd-efgh") and hashes it.
+func hashLinkCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// --- Organization side ---
+
+// LinkERPPatient handles POST /api/org/patients/{id}/link
+// With a code the patient handed over, the link is active at once. Without
+// one, the account matching the record's NIN, email or phone is asked to
+// confirm it.
+func LinkERPPatient(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	patient, err := loadERPPatient(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+	var req models.LinkPatientRequest
+	if err := decodeJSON(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var open bool
+	database.Pool.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM patient_links l WHERE l.patient_id = $1 AND `+openLinkStatus+`)`,
+		patient.ID).Scan(&open)
+	if open || patient.PlatformUserID != nil {
+		writeError(w, http.StatusConflict, "المريض مرتبط بحساب أو لديه طلب ربط قائم")
+		return
+	}
+
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في ربط الحساب")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var userID, method, matchedBy, status, action string
+	if strings.TrimSpace(req.Code) != "" {
+		err = tx.QueryRow(ctx,
+			`UPDATE patient_link_codes SET used_at = NOW()
+			 WHERE code_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+			 RETURNING user_id`, hashLinkCode(req.Code)).Scan(&userID)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "رمز الربط غير صالح أو منتهي الصلاحية")
+			return
+		}
+		method, status, action = models.LinkByCode, models.LinkActive, "create"
+	} else {
+		userID, matchedBy, err = matchPatientAccount(ctx, patient)
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeError(w, http.StatusNotFound, "لا يوجد حساب مطابق لهذا المريض")
+			return
+		}
+		if errors.Is(err, errAmbiguousAccount) {
+			writeError(w, http.StatusConflict, "أكثر من حساب يطابق هذا المريض، استخدم رمز الربط")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "خطأ في ربط الحساب")
+			return
+		}
+		method, status, action = models.LinkByProposal, models.LinkPending, "propose"
+	}
+
+	var taken bool
+	tx.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM patient_links l WHERE l.org_id = $1 AND l.user_id = $2 AND `+openLinkStatus+`)`,
+		orgID, userID).Scan(&taken)
+	if taken {
+		writeError(w, http.StatusConflict, "هذا الحساب مرتبط بمريض آخر في المؤسسة")
+		return
+	}
+
+	var linkID string
+	err = tx.QueryRow(ctx,
+		`INSERT INTO patient_links (org_id, patient_id, user_id, method, matched_by, status, created_by, responded_at)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, CASE WHEN $6 = 'ACTIVE' THEN NOW() END)
+		 RETURNING id`,
+		orgID, patient.ID, userID, method, matchedBy, status, middleware.GetClaims(r).UserID).Scan(&linkID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في ربط الحساب")
+		return
+	}
+	if status == models.LinkActive {
+		if _, err := tx.Exec(ctx, `UPDATE erp_patients SET platform_user_id = $1, updated_at = NOW() WHERE id = $2`,
+			userID, patient.ID); err != nil {
+			writeError(w, http.StatusInternalServerError, "خطأ في ربط الحساب")
+			return
+		}
+	}
+	link, err := scanPatientLink(tx.QueryRow(ctx, linkSelect+` WHERE l.id = $1`, linkID))
+	if err != nil || auditLinkAccess(ctx, tx, r, link, action) != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في ربط الحساب")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في ربط الحساب")
+		return
+	}
+	writeJSON(w, http.StatusCreated, link)
+}
+
+// matchPatientAccount finds the patient account for a record, preferring
+// NIN over email over phone. Several accounts on the best criterion are
+// ambiguous: only a code can tell them apart.
+func matchPatientAccount(ctx context.Context, p models.ERPPatient) (userID, matchedBy string, err error) {
+	rows, err := database.Pool.Query(ctx,
+		`SELECT u.id,
+		        CASE WHEN $1 <> '' AND pp.nin = $1 THEN 'nin'
+		             WHEN $2 <> '' AND u.email = $2 THEN 'email'
+		             ELSE 'phone' END AS matched_by
+		 FROM users u
+		 JOIN profiles_patient pp ON pp.user_id = u.id
+		 WHERE u.role = 'PATIENT' AND (
+		       ($1 <> '' AND pp.nin = $1)
+		    OR ($2 <> '' AND u.email = $2)
+		    OR ($3 <> '' AND RIGHT(regexp_replace(COALESCE(pp.phone, ''), '[^0-9]', '', 'g'), 9) = $3))
+		 ORDER BY CASE WHEN $1 <> '' AND pp.nin = $1 THEN 0 WHEN $2 <> '' AND u.email = $2 THEN 1 ELSE 2 END
+		 LIMIT 2`,
+		p.NIN, strings.ToLower(p.Email), nationalPhone(p.Phone))
+	if err != nil {
+		return "", "", err
+	}
+	defer rows.Close()
+
+	type match struct{ id, by string }
+	var matches []match
+	for rows.Next() {
+		var m match
+		if err := rows.Scan(&m.id, &m.by); err != nil {
+			return "", "", err
+		}
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return "", "", err
+	}
+	switch {
+	case len(matches) == 0:
+		return "", "", pgx.ErrNoRows
+	case len(matches) > 1 && matches[1].by == matches[0].by:
+		return "", "", errAmbiguousAccount
+	}
+	return matches[0].id, matches[0].by, nil
+}
+
+// GetERPPatientLink handles GET /api/org/patients/{id}/link
+// Returns the latest link of the record, whatever its status.
+func GetERPPatientLink(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	link, err := scanPatientLink(database.Pool.QueryRow(ctx,
+		linkSelect+` WHERE l.patient_id::text = $1 AND l.org_id = $2 ORDER BY l.created_at DESC LIMIT 1`,
+		chi.URLParam(r, "id"), orgID))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "لا يوجد ربط لهذا المريض")
+		return
+	}
+	writeJSON(w, http.StatusOK, link)
+}
+
+// UnlinkERPPatient handles DELETE /api/org/patients/{id}/link
+func UnlinkERPPatient(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	link, err := scanPatientLink(database.Pool.QueryRow(ctx,
+		linkSelect+` WHERE l.patient_id::text = $1 AND l.org_id = $2 AND `+openLinkStatus,
+		chi.URLParam(r, "id"), orgID))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "لا يوجد ربط لهذا المريض")
+		return
+	}
+	writeLinkTransition(w, r, link, models.LinkRevoked, "revoke")
+}
+
+// --- Patient side ---
+
+// CreateLinkCode handles POST /api/link-codes
+// Issues a one-time code, valid 15 minutes, replacing any unused one.
+func CreateLinkCode(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	userID := middleware.GetClaims(r).UserID
+
+	buf := make([]byte, linkCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في الخادم")
+		return
+	}
+	code := make([]byte, linkCodeLength)
+	for i, b := range buf {
+		code[i] = linkCodeAlphabet[int(b)%len(linkCodeAlphabet)]
+	}
+	result := models.LinkCode{
+		Code:      string(code[:4]) + "-" + string(code[4:]),
+		ExpiresAt: time.Now().Add(linkCodeTTL).UTC(),
+	}
+
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في الخادم")
+		return
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM patient_link_codes WHERE user_id = $1 AND (used_at IS NULL OR expires_at < NOW())`, userID); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في الخادم")
+		return
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO patient_link_codes (code_hash, user_id, expires_at) VALUES ($1, $2, $3)`,
+		hashLinkCode(result.Code), userID, result.ExpiresAt); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في الخادم")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في الخادم")
+		return
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
+
+// ListMyLinks handles GET /api/links
+// The record's name is only shown once the patient has confirmed the link,
+// so a proposal matched on a shared phone reveals nothing about a relative.
+func ListMyLinks(w http.ResponseWriter, r *http.Request) {
+	rows, err := database.Pool.Query(context.Background(),
+		linkSelect+` WHERE l.user_id = $1 ORDER BY l.created_at DESC`, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الروابط")
+		return
+	}
+	defer rows.Close()
+
+	links := []models.PatientLink{}
+	for rows.Next() {
+		l, err := scanPatientLink(rows)
+		if err != nil {
+			continue
+		}
+		if l.Status != models.LinkActive {
+			l.PatientName = ""
+		}
+		links = append(links, l)
+	}
+	writeJSON(w, http.StatusOK, links)
+}
+
+// ConfirmLink handles POST /api/links/{id}/confirm
+func ConfirmLink(w http.ResponseWriter, r *http.Request) {
+	link, ok := myLink(w, r)
+	if !ok {
+		return
+	}
+	if link.Status != models.LinkPending {
+		writeError(w, http.StatusConflict, "طلب الربط لم يعد قائما")
+		return
+	}
+	writeLinkTransition(w, r, link, models.LinkActive, "confirm")
+}
+
+// DeclineLink handles POST /api/links/{id}/decline
+func DeclineLink(w http.ResponseWriter, r *http.Request) {
+	link, ok := myLink(w, r)
+	if !ok {
+		return
+	}
+	if link.Status != models.LinkPending {
+		writeError(w, http.StatusConflict, "طلب الربط لم يعد قائما")
+		return
+	}
+	writeLinkTransition(w, r, link, models.LinkDeclined, "decline")
+}
+
+// RevokeLink handles DELETE /api/links/{id}
+func RevokeLink(w http.ResponseWriter, r *http.Request) {
+	link, ok := myLink(w, r)
+	if !ok {
+		return
+	}
+	if link.Status != models.LinkPending && link.Status != models.LinkActive {
+		writeError(w, http.StatusConflict, "طلب الربط لم يعد قائما")
+		return
+	}
+	writeLinkTransition(w, r, link, models.LinkRevoked, "revoke")
+}
+
+// ListLinkAccess handles GET /api/links/{id}/access?page=&limit=
+// Every event of the link and every access made through it, newest first.
+func ListLinkAccess(w http.ResponseWriter, r *http.Request) {
+	link, ok := myLink(w, r)
+	if !ok {
+		return
+	}
+	page, limit, offset := parsePagination(r)
+	ctx := context.Background()
+
+	var total int
+	database.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM audit_log WHERE entity_type = 'patient_link' AND entity_id = $1`, link.ID).Scan(&total)
+	rows, err := database.Pool.Query(ctx,
+		`SELECT action, CASE WHEN user_id = $2 THEN 'patient' ELSE 'organization' END, created_at
+		 FROM audit_log WHERE entity_type = 'patient_link' AND entity_id = $1
+		 ORDER BY created_at DESC LIMIT $3 OFFSET $4`, link.ID, link.UserID, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب سجل الوصول")
+		return
+	}
+	defer rows.Close()
+
+	events := []models.LinkAccess{}
+	for rows.Next() {
+		var e models.LinkAccess
+		if rows.Scan(&e.Action, &e.Actor, &e.CreatedAt) == nil {
+			e.Action = strings.TrimPrefix(e.Action, "patient_link.")
+			events = append(events, e)
+		}
+	}
+	writeJSON(w, http.StatusOK, models.Page{Items: events, Page: page, Limit: limit, Total: total})
+}
+
+// myLink loads the {id} link of the calling patient, writing a 404 otherwise.
+func myLink(w http.ResponseWriter, r *http.Request) (models.PatientLink, bool) {
+	link, err := scanPatientLink(database.Pool.QueryRow(context.Background(),
+		linkSelect+` WHERE l.id::text = $1 AND l.user_id = $2`,
+		chi.URLParam(r, "id"), middleware.GetClaims(r).UserID))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "الربط غير موجود")
+		return link, false
+	}
+	return link, true
+}
+
+// writeLinkTransition moves an open link to status, keeps
+// erp_patients.platform_user_id in step, and audits it as action.
+func writeLinkTransition(w http.ResponseWriter, r *http.Request, link models.PatientLink, status, action string) {
+	ctx := context.Background()
+	updated, err := transitionLink(ctx, r, link, status, action)
+	switch {
+	case errors.Is(err, errLinkNotOpen):
+		writeError(w, http.StatusConflict, "طلب الربط لم يعد قائما")
+	case errors.Is(err, errAccountLinked):
+		writeError(w, http.StatusConflict, "هذا الحساب مرتبط بمريض آخر في المؤسسة")
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "خطأ في ربط الحساب")
+	default:
+		writeJSON(w, http.StatusOK, updated)
+	}
+}
+
+func transitionLink(ctx context.Context, r *http.Request, link models.PatientLink, status, action string) (models.PatientLink, error) {
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		return link, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE patient_links l SET status = $2,
+			responded_at = CASE WHEN $2 IN ('ACTIVE', 'DECLINED') THEN NOW() ELSE l.responded_at END,
+			revoked_at = CASE WHEN $2 = 'REVOKED' THEN NOW() END,
+			revoked_by = CASE WHEN $2 = 'REVOKED' THEN $3::UUID END
+		 WHERE l.id = $1 AND `+openLinkStatus,
+		link.ID, status, middleware.GetClaims(r).UserID)
+	if err != nil {
+		return link, err
+	}
+	if tag.RowsAffected() == 0 {
+		return link, errLinkNotOpen
+	}
+
+	switch status {
+	case models.LinkActive:
+		// The record may have been linked by code since the proposal
+		tag, err = tx.Exec(ctx,
+			`UPDATE erp_patients SET platform_user_id = $1, updated_at = NOW()
+			 WHERE id = $2 AND (platform_user_id IS NULL OR platform_user_id = $1)`, link.UserID, link.PatientID)
+		if err == nil && tag.RowsAffected() == 0 {
+			err = errAccountLinked
+		}
+	case models.LinkRevoked:
+		_, err = tx.Exec(ctx,
+			`UPDATE erp_patients SET platform_user_id = NULL, updated_at = NOW()
+			 WHERE id = $1 AND platform_user_id = $2`, link.PatientID, link.UserID)
+	}
+	if err != nil {
+		return link, err
+	}
+
+	updated, err := scanPatientLink(tx.QueryRow(ctx, linkSelect+` WHERE l.id = $1`, link.ID))
+	if err != nil {
+		return link, err
+	}
+	if err := auditLinkAccess(ctx, tx, r, updated, action); err != nil {
+		return link, err
+	}
+	return updated, tx.Commit(ctx)
+}
@@ -0,0 +1,655 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const maxListingImages = 10
+
+var (
+	listingApprovalRequired bool
+
+	phonePattern     = regexp.MustCompile(`^[0-9+() X-]{6,30}$`)
+	openHoursPattern = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9] - ([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+	errListingClaimed  = errors.New("listing already claimed")
+	errServiceNotFound = errors.New("provider service not found")
+)
+
+// InitListings configures whether listing changes need platform-admin approval.
+func InitListings(requireApproval bool) {
+	listingApprovalRequired = requireApproval
+}
+
+// MyListings handles GET /api/listings/mine
+func MyListings(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+
+	rows, err := database.Pool.Query(context.Background(),
+		`SELECT p.id, p.name, COALESCE(p.name_en, ''), p.type, p.wilaya, p.wilaya_id,
+			    COALESCE(p.city, ''), COALESCE(p.commune_code, ''), COALESCE(p.address, ''), COALESCE(p.phone, ''),
+			    p.rating, p.reviews_count, COALESCE(p.image, ''), COALESCE(p.open_hours, ''), COALESCE(p.images, '{}'),
+			    p.latitude, p.longitude
+		 FROM providers p
+		 WHERE p.user_id = $1 OR EXISTS(
+			SELECT 1 FROM org_members m WHERE m.org_id = p.org_id AND m.user_id = $1 AND m.is_active)
+		 ORDER BY p.name`, claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الصفحات")
+		return
+	}
+	defer rows.Close()
+
+	providers := []models.Provider{}
+	for rows.Next() {
+		var p models.Provider
+		if err := rows.Scan(&p.ID, &p.Name, &p.NameEn, &p.Type, &p.Wilaya, &p.WilayaID,
+			&p.City, &p.CommuneCode, &p.Address, &p.Phone, &p.Rating, &p.ReviewsCount, &p.Image, &p.OpenHours, &p.Images,
+			&p.Latitude, &p.Longitude); err != nil {
+			continue
+		}
+		p.Services = []models.ProviderService{}
+		providers = append(providers, p)
+	}
+	writeJSON(w, http.StatusOK, providers)
+}
+
+// ClaimListing handles POST /api/listings/{id}/claim
+// Requests to link an unclaimed provider to the caller and their active
+// organization; a platform admin approves the claim.
+func ClaimListing(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	providerID := chi.URLParam(r, "id")
+	ctx := context.Background()
+
+	var owner *string
+	err := database.Pool.QueryRow(ctx,
+		`SELECT user_id::TEXT FROM providers WHERE id = $1`, providerID).Scan(&owner)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المزود غير موجود")
+		return
+	}
+	if owner != nil {
+		writeError(w, http.StatusConflict, "هذه الصفحة مطالب بها مسبقاً")
+		return
+	}
+
+	var pending bool
+	database.Pool.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM listing_changes
+		 WHERE provider_id = $1 AND requested_by = $2 AND kind = 'CLAIM' AND status = 'PENDING')`,
+		providerID, claims.UserID).Scan(&pending)
+	if pending {
+		writeError(w, http.StatusConflict, "طلب المطالبة قيد المراجعة")
+		return
+	}
+
+	var claim models.ListingClaim
+	if orgID, err := activeOrgID(ctx, claims.UserID); err == nil {
+		claim.OrgID = &orgID
+	}
+
+	submitListingChange(w, claims.UserID, providerID, models.ChangeClaim, claim)
+}
+
+// UpdateListing handles PATCH /api/listings/{id}
+func UpdateListing(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	providerID := chi.URLParam(r, "id")
+	if !canManageProvider(context.Background(), claims.UserID, providerID) {
+		writeError(w, http.StatusForbidden, "غير مصرح")
+		return
+	}
+
+	var req models.ListingUpdate
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := validateListingUpdate(&req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	if req.CommuneCode != nil && *req.CommuneCode != "" {
+		var wilayaID string
+		database.Pool.QueryRow(context.Background(),
+			`SELECT wilaya_id FROM providers WHERE id = $1`, providerID).Scan(&wilayaID)
+		if !communeInWilaya(context.Background(), *req.CommuneCode, wilayaID) {
+			writeError(w, http.StatusBadRequest, "البلدية لا تنتمي إلى ولاية المزود")
+			return
+		}
+	}
+
+	submitListingChange(w, claims.UserID, providerID, models.ChangeUpdate, req)
+}
+
+// AddListingService handles POST /api/listings/{id}/services
+func AddListingService(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	providerID := chi.URLParam(r, "id")
+	if !canManageProvider(context.Background(), claims.UserID, providerID) {
+		writeError(w, http.StatusForbidden, "غير مصرح")
+		return
+	}
+
+	var req models.ProviderService
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.ID = 0
+	if msg := validateProviderService(&req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	submitListingChange(w, claims.UserID, providerID, models.ChangeServiceAdd, req)
+}
+
+// UpdateListingService handles PUT /api/listings/{id}/services/{serviceId}
+func UpdateListingService(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	providerID := chi.URLParam(r, "id")
+	if !canManageProvider(context.Background(), claims.UserID, providerID) {
+		writeError(w, http.StatusForbidden, "غير مصرح")
+		return
+	}
+
+	rowID, ok := providerServiceRowID(w, r, providerID)
+	if !ok {
+		return
+	}
+
+	var req models.ProviderService
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.ID = rowID
+	if msg := validateProviderService(&req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	submitListingChange(w, claims.UserID, providerID, models.ChangeServiceUpdate, req)
+}
+
+// DeleteListingService handles DELETE /api/listings/{id}/services/{serviceId}
+func DeleteListingService(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	providerID := chi.URLParam(r, "id")
+	if !canManageProvider(context.Background(), claims.UserID, providerID) {
+		writeError(w, http.StatusForbidden, "غير مصرح")
+		return
+	}
+
+	rowID, ok := providerServiceRowID(w, r, providerID)
+	if !ok {
+		return
+	}
+
+	submitListingChange(w, claims.UserID, providerID, models.ChangeServiceDelete, models.ProviderService{ID: rowID})
+}
+
+// ListListingChanges handles GET /api/listings/{id}/changes (owner history)
+func ListListingChanges(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	providerID := chi.URLParam(r, "id")
+	if !canManageProvider(context.Background(), claims.UserID, providerID) {
+		writeError(w, http.StatusForbidden, "غير مصرح")
+		return
+	}
+	page, limit, offset := parsePagination(r)
+
+	listChanges(w, `provider_id = $1`, []interface{}{providerID}, page, limit, offset)
+}
+
+// ListPendingListingChanges handles GET /api/admin/listing-changes?status=PENDING
+func ListPendingListingChanges(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = models.ChangePending
+	}
+	page, limit, offset := parsePagination(r)
+
+	listChanges(w, `status = $1`, []interface{}{status}, page, limit, offset)
+}
+
+// ApproveListingChange handles POST /api/admin/listing-changes/{id}/approve
+func ApproveListingChange(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	changeID := chi.URLParam(r, "id")
+
+	var req models.ReviewChangeRequest
+	decodeJSON(r, &req)
+
+	ctx := context.Background()
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في الخادم")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var providerID, requestedBy, kind string
+	var payload []byte
+	err = tx.QueryRow(ctx,
+		`SELECT provider_id, requested_by, kind, payload FROM listing_changes
+		 WHERE id = $1 AND status = 'PENDING' FOR UPDATE`, changeID).Scan(&providerID, &requestedBy, &kind, &payload)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "الطلب غير موجود أو تمت مراجعته")
+		return
+	}
+
+	if err := applyListingChange(ctx, tx, providerID, requestedBy, kind, payload); err != nil {
+		writeListingChangeError(w, err)
+		return
+	}
+
+	_, err = tx.Exec(ctx,
+		`UPDATE listing_changes SET status = 'APPROVED', reviewed_by = $1, reviewed_at = NOW(), review_note = NULLIF($2, '')
+		 WHERE id = $3`, claims.UserID, req.Note, changeID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	invalidateProvider(providerID)
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تم اعتماد التعديل")})
+}
+
+// RejectListingChange handles POST /api/admin/listing-changes/{id}/reject
+func RejectListingChange(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	changeID := chi.URLParam(r, "id")
+
+	var req models.ReviewChangeRequest
+	if err := decodeJSON(r, &req); err != nil || strings.TrimSpace(req.Note) == "" {
+		writeError(w, http.StatusBadRequest, "سبب الرفض مطلوب")
+		return
+	}
+
+	tag, err := database.Pool.Exec(context.Background(),
+		`UPDATE listing_changes SET status = 'REJECTED', reviewed_by = $1, reviewed_at = NOW(), review_note = $2
+		 WHERE id = $3 AND status = 'PENDING'`, claims.UserID, req.Note, changeID)
+	if err != nil || tag.RowsAffected() == 0 {
+		writeError(w, http.StatusNotFound, "الطلب غير موجود أو تمت مراجعته")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تم رفض التعديل")})
+}
+
+// submitListingChange records a change and, unless approval is required or
+// it is a claim, applies it in the same transaction.
+func submitListingChange(w http.ResponseWriter, userID, providerID, kind string, change interface{}) {
+	changeID, status, err := recordListingChange(context.Background(), userID, providerID, kind, change)
+	if err != nil {
+		writeListingChangeError(w, err)
+		return
+	}
+
+	code, message := http.StatusOK, "تم حفظ التعديل"
+	if status == models.ChangePending {
+		code, message = http.StatusAccepted, "تم إرسال التعديل للمراجعة"
+	}
+	writeJSON(w, code, map[string]string{
+		"id":      changeID,
+		"status":  status,
+		"message": localize(w, message),
+	})
+}
+
+// recordListingChange stores a change as PENDING when approval is required,
+// otherwise applies it and stores it as APPROVED. Claims transfer ownership
+// and always wait for a platform admin. Returns the change ID and status.
+func recordListingChange(ctx context.Context, userID, providerID, kind string, change interface{}) (string, string, error) {
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return "", "", err
+	}
+
+	var changeID string
+	if listingApprovalRequired || kind == models.ChangeClaim {
+		err := database.Pool.QueryRow(ctx,
+			`INSERT INTO listing_changes (provider_id, requested_by, kind, payload)
+			 VALUES ($1, $2, $3, $4) RETURNING id`,
+			providerID, userID, kind, payload).Scan(&changeID)
+		return changeID, models.ChangePending, err
+	}
+
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := applyListingChange(ctx, tx, providerID, userID, kind, payload); err != nil {
+		return "", "", err
+	}
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO listing_changes (provider_id, requested_by, kind, payload, status, reviewed_at)
+		 VALUES ($1, $2, $3, $4, 'APPROVED', NOW()) RETURNING id`,
+		providerID, userID, kind, payload).Scan(&changeID)
+	if err != nil {
+		return "", "", err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return "", "", err
+	}
+	invalidateProvider(providerID)
+	return changeID, models.ChangeApproved, nil
+}
+
+// applyListingChange writes a change to providers/provider_services.
+func applyListingChange(ctx context.Context, tx pgx.Tx, providerID, userID, kind string, payload []byte) error {
+	switch kind {
+	case models.ChangeClaim:
+		var c models.ListingClaim
+		if err := json.Unmarshal(payload, &c); err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx,
+			`UPDATE providers SET user_id = $1, org_id = COALESCE($2::UUID, org_id), updated_at = NOW()
+			 WHERE id = $3 AND user_id IS NULL`, userID, c.OrgID, providerID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return errListingClaimed
+		}
+		return nil
+
+	case models.ChangeUpdate:
+		var u models.ListingUpdate
+		if err := json.Unmarshal(payload, &u); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx,
+			`UPDATE providers SET
+				name = COALESCE($1, name),
+				name_en = COALESCE($2, name_en),
+				address = COALESCE($3, address),
+				phone = COALESCE($4, phone),
+				open_hours = COALESCE($5, open_hours),
+				image = COALESCE($6, image),
+				images = COALESCE($7::TEXT[], images),
+				latitude = COALESCE($8, latitude),
+				longitude = COALESCE($9, longitude),
+				commune_code = CASE WHEN $10::TEXT IS NULL THEN commune_code ELSE NULLIF($10, '') END,
+				updated_at = NOW()
+			 WHERE id = $11`,
+			u.Name, u.NameEn, u.Address, u.Phone, u.OpenHours, u.Image, u.Images,
+			u.Latitude, u.Longitude, u.CommuneCode, providerID)
+		return err
+
+	case models.ChangeServiceAdd, models.ChangeServiceUpdate:
+		var s models.ProviderService
+		if err := json.Unmarshal(payload, &s); err != nil {
+			return err
+		}
+		doctorID, equipmentID, err := upsertServiceStaff(ctx, tx, providerID, s)
+		if err != nil {
+			return err
+		}
+
+		if kind == models.ChangeServiceAdd {
+			_, err := tx.Exec(ctx,
+				`INSERT INTO provider_services (provider_id, service_id, name, price, turnaround, doctor_id, equipment_id)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+				providerID, s.ServiceID, s.Name, s.Price, s.Turnaround, doctorID, equipmentID)
+			if err != nil {
+				return err
+			}
+		} else {
+			tag, err := tx.Exec(ctx,
+				`UPDATE provider_services SET service_id = $1, name = $2, price = $3, turnaround = $4,
+				 doctor_id = $5, equipment_id = $6
+				 WHERE id = $7 AND provider_id = $8 AND source IS NULL`,
+				s.ServiceID, s.Name, s.Price, s.Turnaround, doctorID, equipmentID, s.ID, providerID)
+			if err != nil {
+				return err
+			}
+			if tag.RowsAffected() == 0 {
+				return errServiceNotFound
+			}
+		}
+		_, err = tx.Exec(ctx, `UPDATE providers SET updated_at = NOW() WHERE id = $1`, providerID)
+		return err
+
+	case models.ChangeServiceDelete:
+		var s models.ProviderService
+		if err := json.Unmarshal(payload, &s); err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx,
+			`DELETE FROM provider_services WHERE id = $1 AND provider_id = $2 AND source IS NULL`, s.ID, providerID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return errServiceNotFound
+		}
+		_, err = tx.Exec(ctx, `UPDATE providers SET updated_at = NOW() WHERE id = $1`, providerID)
+		return err
+	}
+
+	return errors.New("unknown listing change kind: " + kind)
+}
+
+// upsertServiceStaff creates or updates the doctor and equipment of a service,
+// matched by name within the provider, and returns their IDs (nil when absent).
+func upsertServiceStaff(ctx context.Context, tx pgx.Tx, providerID string, s models.ProviderService) (doctorID, equipmentID *int, err error) {
+	if s.Doctor != nil && s.Doctor.Name != "" {
+		years := s.Doctor.ExperienceYears
+		if years == 0 {
+			years = parseExperience(s.Doctor.Experience)
+		}
+		var id int
+		err = tx.QueryRow(ctx,
+			`INSERT INTO provider_doctors (provider_id, name, specialty, experience_years)
+			 VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, 0))
+			 ON CONFLICT (provider_id, name)
+			 DO UPDATE SET specialty = EXCLUDED.specialty, experience_years = EXCLUDED.experience_years
+			 RETURNING id`,
+			providerID, s.Doctor.Name, s.Doctor.Specialty, years).Scan(&id)
+		if err != nil {
+			return nil, nil, err
+		}
+		doctorID = &id
+	}
+	if s.Equipment != nil && s.Equipment.Name != "" {
+		var id int
+		err = tx.QueryRow(ctx,
+			`INSERT INTO provider_equipment (provider_id, name, type, origin)
+			 VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+			 ON CONFLICT (provider_id, name)
+			 DO UPDATE SET type = EXCLUDED.type, origin = EXCLUDED.origin
+			 RETURNING id`,
+			providerID, s.Equipment.Name, s.Equipment.Type, s.Equipment.Origin).Scan(&id)
+		if err != nil {
+			return nil, nil, err
+		}
+		equipmentID = &id
+	}
+	return doctorID, equipmentID, nil
+}
+
+func writeListingChangeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errListingClaimed):
+		writeError(w, http.StatusConflict, "هذه الصفحة مطالب بها مسبقاً")
+	case errors.Is(err, errServiceNotFound):
+		writeError(w, http.StatusNotFound, "الخدمة غير موجودة")
+	default:
+		writeError(w, http.StatusInternalServerError, "خطأ في تطبيق التعديل")
+	}
+}
+
+func listChanges(w http.ResponseWriter, where string, args []interface{}, page, limit, offset int) {
+	var total int
+	database.Pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM listing_changes WHERE `+where, args...).Scan(&total)
+
+	n := len(args)
+	rows, err := database.Pool.Query(context.Background(),
+		`SELECT id, provider_id, requested_by, kind, payload, status, COALESCE(review_note, ''), reviewed_at, created_at
+		 FROM listing_changes WHERE `+where+`
+		 ORDER BY created_at DESC
+		 LIMIT $`+itoa(n+1)+` OFFSET $`+itoa(n+2),
+		append(args, limit, offset)...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الطلبات")
+		return
+	}
+	defer rows.Close()
+
+	changes := []models.ListingChange{}
+	for rows.Next() {
+		var c models.ListingChange
+		if err := rows.Scan(&c.ID, &c.ProviderID, &c.RequestedBy, &c.Kind, &c.Payload,
+			&c.Status, &c.ReviewNote, &c.ReviewedAt, &c.CreatedAt); err != nil {
+			continue
+		}
+		changes = append(changes, c)
+	}
+
+	writeJSON(w, http.StatusOK, models.Page{Items: changes, Page: page, Limit: limit, Total: total})
+}
+
+// providerServiceRowID parses {serviceId} and checks it is a hand-entered
+// service of the provider (derived org services are managed through prices).
+func providerServiceRowID(w http.ResponseWriter, r *http.Request, providerID string) (int, bool) {
+	rowID, err := strconv.Atoi(chi.URLParam(r, "serviceId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "معرف الخدمة غير صالح")
+		return 0, false
+	}
+	var exists bool
+	database.Pool.QueryRow(context.Background(),
+		`SELECT EXISTS(SELECT 1 FROM provider_services WHERE id = $1 AND provider_id = $2 AND source IS NULL)`,
+		rowID, providerID).Scan(&exists)
+	if !exists {
+		writeError(w, http.StatusNotFound, "الخدمة غير موجودة")
+		return 0, false
+	}
+	return rowID, true
+}
+
+// validateListingUpdate trims the update in place and returns an error message, or "".
+func validateListingUpdate(u *models.ListingUpdate) string {
+	for _, f := range []*string{u.Name, u.NameEn, u.Address, u.Phone, u.OpenHours, u.Image, u.CommuneCode} {
+		if f != nil {
+			*f = strings.TrimSpace(*f)
+		}
+	}
+	if u.Name != nil && (*u.Name == "" || utf8.RuneCountInString(*u.Name) > 255) {
+		return "اسم المزود مطلوب ولا يتجاوز 255 حرفاً"
+	}
+	if u.NameEn != nil && utf8.RuneCountInString(*u.NameEn) > 255 {
+		return "الاسم بالإنجليزية طويل جداً"
+	}
+	if u.Address != nil && utf8.RuneCountInString(*u.Address) > 500 {
+		return "العنوان طويل جداً"
+	}
+	if u.Phone != nil && !phonePattern.MatchString(*u.Phone) {
+		return "رقم الهاتف غير صالح"
+	}
+	if u.OpenHours != nil && *u.OpenHours != "24/7" && !openHoursPattern.MatchString(*u.OpenHours) {
+		return "أوقات العمل يجب أن تكون بالشكل 08:00 - 18:00 أو 24/7"
+	}
+	if u.Image != nil && *u.Image != "" && !isHTTPURL(*u.Image) {
+		return "رابط الصورة غير صالح"
+	}
+	if (u.Latitude == nil) != (u.Longitude == nil) ||
+		u.Latitude != nil && (*u.Latitude < -90 || *u.Latitude > 90 || *u.Longitude < -180 || *u.Longitude > 180) {
+		return "الإحداثيات غير صالحة"
+	}
+	if len(u.Images) > maxListingImages {
+		return "عدد الصور يتجاوز الحد المسموح"
+	}
+	for _, img := range u.Images {
+		if !isHTTPURL(img) {
+			return "رابط الصورة غير صالح"
+		}
+	}
+	return ""
+}
+
+// validateProviderService trims the service in place and returns an error message, or "".
+func validateProviderService(s *models.ProviderService) string {
+	s.ServiceID = strings.TrimSpace(s.ServiceID)
+	s.Name = strings.TrimSpace(s.Name)
+	s.Turnaround = strings.TrimSpace(s.Turnaround)
+
+	// Every provider service must reference the taxonomy
+	if s.ServiceID == "" {
+		return "يجب اختيار الخدمة من الدليل"
+	}
+	var catalogName string
+	err := database.Pool.QueryRow(context.Background(),
+		`SELECT name FROM medical_services WHERE id = $1`, s.ServiceID).Scan(&catalogName)
+	if err != nil {
+		return "الخدمة غير موجودة في الدليل"
+	}
+	if s.Name == "" {
+		s.Name = catalogName
+	}
+	if s.Name == "" || utf8.RuneCountInString(s.Name) > 255 {
+		return "اسم الخدمة مطلوب ولا يتجاوز 255 حرفاً"
+	}
+	if s.Price < 0 || s.Price > 10000000 {
+		return "السعر غير صالح"
+	}
+	if utf8.RuneCountInString(s.Turnaround) > 50 {
+		return "مدة الإنجاز طويلة جداً"
+	}
+	if s.Doctor != nil {
+		if s.Doctor.Name == "" {
+			s.Doctor = nil
+		} else if utf8.RuneCountInString(s.Doctor.Name) > 100 || utf8.RuneCountInString(s.Doctor.Specialty) > 100 ||
+			utf8.RuneCountInString(s.Doctor.Experience) > 50 {
+			return "بيانات الطبيب طويلة جداً"
+		} else {
+			if s.Doctor.ExperienceYears == 0 {
+				s.Doctor.ExperienceYears = parseExperience(s.Doctor.Experience)
+			}
+			if s.Doctor.ExperienceYears < 0 || s.Doctor.ExperienceYears > 70 {
+				return "سنوات الخبرة غير صالحة"
+			}
+		}
+	}
+	if s.Equipment != nil {
+		if s.Equipment.Name == "" {
+			s.Equipment = nil
+		} else if utf8.RuneCountInString(s.Equipment.Name) > 100 || utf8.RuneCountInString(s.Equipment.Type) > 100 ||
+			utf8.RuneCountInString(s.Equipment.Origin) > 100 {
+			return "بيانات الجهاز طويلة جداً"
+		}
+	}
+	return ""
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
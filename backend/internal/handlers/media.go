@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/imaging"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/anis7x/cliniclab/internal/storage"
+	"github.com/go-chi/chi/v5"
+)
+
+// thumbnailSize is the longest side of generated thumbnails, in pixels.
+const thumbnailSize = 320
+
+var (
+	mediaStore     storage.Storage
+	mediaBaseURL   string
+	maxUploadBytes int64 = 5 << 20
+)
+
+// InitMedia sets the upload backend, the base of public media URLs and the
+// upload size limit. Must be called at startup.
+func InitMedia(store storage.Storage, publicBaseURL string, maxUploadMB int) {
+	mediaStore = store
+	mediaBaseURL = strings.TrimRight(publicBaseURL, "/")
+	maxUploadBytes = int64(maxUploadMB) << 20
+}
+
+// ServeMedia handles GET /media/*
+// Local storage streams the file; S3 redirects to a public or signed URL.
+func ServeMedia(w http.ResponseWriter, r *http.Request) {
+	mediaStore.Serve(w, r, chi.URLParam(r, "*"))
+}
+
+// UploadListingImage handles POST /api/listings/{id}/images (multipart "file", ?cover=true)
+// The image is stored right away; attaching it to the gallery (or as the
+// cover with cover=true) goes through the usual listing change flow.
+func UploadListingImage(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	providerID := chi.URLParam(r, "id")
+	ctx := context.Background()
+	if !canManageProvider(ctx, claims.UserID, providerID) {
+		writeError(w, http.StatusForbidden, "غير مصرح")
+		return
+	}
+
+	cover := r.URL.Query().Get("cover") == "true"
+	var images []string
+	database.Pool.QueryRow(ctx,
+		`SELECT COALESCE(images, '{}') FROM providers WHERE id = $1`, providerID).Scan(&images)
+	if !cover && len(images) >= maxListingImages {
+		writeError(w, http.StatusBadRequest, "عدد الصور يتجاوز الحد المسموح")
+		return
+	}
+
+	m, ok := storeUpload(w, r, "providers/"+providerID, claims.UserID, providerID, "")
+	if !ok {
+		return
+	}
+
+	var update models.ListingUpdate
+	if cover {
+		update.Image = &m.URL
+	} else {
+		update.Images = append(images, m.URL)
+	}
+	changeID, status, err := recordListingChange(ctx, claims.UserID, providerID, models.ChangeUpdate, update)
+	if err != nil {
+		writeListingChangeError(w, err)
+		return
+	}
+
+	code := http.StatusCreated
+	if status == models.ChangePending {
+		code = http.StatusAccepted
+	}
+	writeJSON(w, code, models.ListingImageUpload{Media: m, ChangeID: changeID, Status: status})
+}
+
+// UploadOrgLogo handles POST /api/org/logo (multipart "file")
+// Also replaces the image of the published listing if it still shows the old logo.
+func UploadOrgLogo(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+
+	m, ok := storeUpload(w, r, "orgs/"+orgID, claims.UserID, "", orgID)
+	if !ok {
+		return
+	}
+
+	var oldLogo string
+	err = database.Pool.QueryRow(ctx,
+		`UPDATE organizations o SET logo_url = $1, updated_at = NOW()
+		 FROM (SELECT id, COALESCE(logo_url, '') AS logo_url FROM organizations WHERE id = $2) old
+		 WHERE o.id = old.id RETURNING old.logo_url`, m.URL, orgID).Scan(&oldLogo)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في تحديث المؤسسة")
+		return
+	}
+	database.Pool.Exec(ctx,
+		`UPDATE providers SET image = $1, updated_at = NOW()
+		 WHERE org_id = $2 AND COALESCE(image, '') IN ('', $3)`, m.URL, orgID, oldLogo)
+	invalidateProviders()
+
+	writeJSON(w, http.StatusCreated, m)
+}
+
+// storeUpload reads the "file" part, checks its size and real type, stores
+// it with a thumbnail under prefix and records it in media. On failure it
+// writes the error response and returns false.
+func storeUpload(w http.ResponseWriter, r *http.Request, prefix, ownerID, providerID, orgID string) (models.Media, bool) {
+	var m models.Media
+	// Allow some room for the multipart envelope around the file
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes+64<<10)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			writeError(w, http.StatusRequestEntityTooLarge, "حجم الملف يتجاوز الحد المسموح")
+		} else {
+			writeError(w, http.StatusBadRequest, "الملف مطلوب")
+		}
+		return m, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxUploadBytes+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "الملف مطلوب")
+		return m, false
+	}
+	if int64(len(data)) > maxUploadBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, "حجم الملف يتجاوز الحد المسموح")
+		return m, false
+	}
+
+	info, err := imaging.Sniff(data)
+	switch {
+	case errors.Is(err, imaging.ErrTooLarge):
+		writeError(w, http.StatusBadRequest, "أبعاد الصورة كبيرة جداً")
+		return m, false
+	case err != nil:
+		writeError(w, http.StatusUnsupportedMediaType, "نوع الملف غير مدعوم (JPEG أو PNG أو GIF)")
+		return m, false
+	}
+	thumb, err := imaging.Thumbnail(data, thumbnailSize)
+	if err != nil {
+		writeError(w, http.StatusUnsupportedMediaType, "نوع الملف غير مدعوم (JPEG أو PNG أو GIF)")
+		return m, false
+	}
+
+	name := randomName()
+	key := prefix + "/" + name + imaging.Extensions[info.ContentType]
+	thumbKey := prefix + "/" + name + "_thumb.jpg"
+
+	ctx := context.Background()
+	if err := mediaStore.Put(ctx, key, info.ContentType, data); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الملف")
+		return m, false
+	}
+	if err := mediaStore.Put(ctx, thumbKey, "image/jpeg", thumb); err != nil {
+		mediaStore.Delete(ctx, key)
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الملف")
+		return m, false
+	}
+
+	m = models.Media{
+		URL:          mediaURL(key),
+		ThumbnailURL: mediaURL(thumbKey),
+		ContentType:  info.ContentType,
+		Size:         len(data),
+		Width:        info.Width,
+		Height:       info.Height,
+	}
+	err = database.Pool.QueryRow(ctx,
+		`INSERT INTO media (owner_id, provider_id, org_id, storage_key, thumbnail_key, content_type, size_bytes, width, height)
+		 VALUES ($1, NULLIF($2, ''), NULLIF($3, '')::UUID, $4, $5, $6, $7, $8, $9)
+		 RETURNING id, created_at`,
+		ownerID, providerID, orgID, key, thumbKey, m.ContentType, m.Size, m.Width, m.Height).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		mediaStore.Delete(ctx, key)
+		mediaStore.Delete(ctx, thumbKey)
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الملف")
+		return m, false
+	}
+	return m, true
+}
+
+// mediaURL is the stable public URL of a stored object.
+func mediaURL(key string) string {
+	return mediaBaseURL + "/media/" + key
+}
+
+func randomName() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	maxMedicationImportBytes = 32 << 20
+	maxDCIResults            = 20
+	maxDCIBrands             = 10
+)
+
+const medicationSelect = `SELECT id, registration_number, dci, brand, COALESCE(form, ''), COALESCE(strength, ''),
+	COALESCE(packaging, ''), COALESCE(laboratory, ''), COALESCE(country, ''), is_generic, is_reimbursable,
+	reference_price::float8, is_active FROM medications`
+
+func scanMedication(row pgx.Row) (models.Medication, error) {
+	var m models.Medication
+	err := row.Scan(&m.ID, &m.RegistrationNumber, &m.DCI, &m.Brand, &m.Form, &m.Strength,
+		&m.Packaging, &m.Laboratory, &m.Country, &m.IsGeneric, &m.IsReimbursable,
+		&m.ReferencePrice, &m.IsActive)
+	return m, err
+}
+
+// medicationMatch returns the condition matching q against brands and DCIs,
+// word by word or by close spelling ("amoxiciline"), and a relevance score
+// where name prefixes rank first.
+func medicationMatch(args *sqlArgs, q string) (cond, score string) {
+	var words []string
+	for _, word := range strings.Fields(q) {
+		p := args.add("%" + word + "%")
+		words = append(words, `(brand ILIKE `+p+` OR dci ILIKE `+p+`)`)
+	}
+	p := args.add(q)
+	cond = `((` + strings.Join(words, " AND ") + `) OR ` + p + ` <% brand OR ` + p + ` <% dci)`
+	score = `(CASE WHEN brand ILIKE ` + p + ` || '%' OR dci ILIKE ` + p + ` || '%' THEN 1 ELSE 0 END
+		+ GREATEST(word_similarity(` + p + `, brand), word_similarity(` + p + `, dci)))`
+	return cond, score
+}
+
+// SearchMedications handles GET /api/medications?q=&dci=&form=&reimbursable=&page=&limit=
+// q matches brand names and DCIs; dci lists the presentations of one DCI
+// (a brand and its generics). Withdrawn presentations are never listed.
+func SearchMedications(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	page, limit, offset := parsePagination(r)
+	query := r.URL.Query()
+
+	args := sqlArgs{}
+	where := ` WHERE is_active`
+	var order []string
+	if dci := strings.TrimSpace(query.Get("dci")); dci != "" {
+		where += ` AND lower(dci) = lower(` + args.add(dci) + `)`
+	}
+	if form := strings.TrimSpace(query.Get("form")); form != "" {
+		where += ` AND form ILIKE ` + args.add("%"+form+"%")
+	}
+	if query.Get("reimbursable") == "true" {
+		where += ` AND is_reimbursable`
+	}
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		cond, score := medicationMatch(&args, q)
+		where += ` AND ` + cond
+		order = append(order, `lower(brand) = lower(`+args.add(q)+`) DESC`, score+` DESC`)
+	}
+	order = append(order, `brand`, `strength`, `form`)
+
+	var total int
+	if err := database.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM medications`+where, args...).Scan(&total); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الأدوية")
+		return
+	}
+	sql := medicationSelect + where + ` ORDER BY ` + strings.Join(order, ", ") + ` LIMIT ` + args.add(limit) + ` OFFSET ` + args.add(offset)
+	rows, err := database.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الأدوية")
+		return
+	}
+	defer rows.Close()
+
+	medications := []models.Medication{}
+	for rows.Next() {
+		if m, err := scanMedication(rows); err == nil {
+			medications = append(medications, m)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, models.Page{Items: medications, Page: page, Limit: limit, Total: total})
+}
+
+// LookupDCI handles GET /api/medications/dci?q=
+// Resolves brand names to their DCI: each DCI matching q by name or through
+// one of its brands, with the brands it is sold under.
+func LookupDCI(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		writeJSON(w, http.StatusOK, []models.MedicationDCI{})
+		return
+	}
+
+	args := sqlArgs{}
+	cond, score := medicationMatch(&args, q)
+	sql := `WITH matched AS (
+			SELECT dci, MAX(` + score + `) AS score FROM medications
+			WHERE is_active AND ` + cond + `
+			GROUP BY dci ORDER BY score DESC, dci LIMIT ` + args.add(maxDCIResults) + `)
+		 SELECT m.dci, (array_agg(DISTINCT x.brand ORDER BY x.brand))[1:` + strconv.Itoa(maxDCIBrands) + `], COUNT(*)
+		 FROM matched m JOIN medications x ON x.dci = m.dci AND x.is_active
+		 GROUP BY m.dci, m.score
+		 ORDER BY m.score DESC, m.dci`
+	rows, err := database.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الأدوية")
+		return
+	}
+	defer rows.Close()
+
+	dcis := []models.MedicationDCI{}
+	for rows.Next() {
+		var d models.MedicationDCI
+		if rows.Scan(&d.DCI, &d.Brands, &d.Count) == nil {
+			dcis = append(dcis, d)
+		}
+	}
+	writeJSON(w, http.StatusOK, dcis)
+}
+
+// GetMedication handles GET /api/medications/{id}
+func GetMedication(w http.ResponseWriter, r *http.Request) {
+	m, err := scanMedication(database.Pool.QueryRow(context.Background(),
+		medicationSelect+` WHERE id::text = $1`, chi.URLParam(r, "id")))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "الدواء غير موجود في قائمة الأدوية")
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// resolveMedications checks the formulary references of prescription items
+// and fills the name and strength left empty from the formulary. Returns an
+// error message, or "".
+func resolveMedications(ctx context.Context, items []models.PrescriptionItem) (string, error) {
+	var ids []string
+	for i := range items {
+		if id := items[i].MedicationID; id != nil && strings.TrimSpace(*id) == "" {
+			items[i].MedicationID = nil
+		} else if id != nil {
+			ids = append(ids, *id)
+		}
+	}
+	if len(ids) == 0 {
+		return "", nil
+	}
+
+	type entry struct{ brand, strength string }
+	found := map[string]entry{}
+	rows, err := database.Pool.Query(ctx,
+		`SELECT id::text, brand, COALESCE(strength, '') FROM medications
+		 WHERE id::text = ANY($1) AND is_active`, ids)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var e entry
+		if err := rows.Scan(&id, &e.brand, &e.strength); err != nil {
+			return "", err
+		}
+		found[id] = e
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	for i := range items {
+		it := &items[i]
+		if it.MedicationID == nil {
+			continue
+		}
+		e, ok := found[*it.MedicationID]
+		if !ok {
+			return "الدواء غير موجود في قائمة الأدوية", nil
+		}
+		if strings.TrimSpace(it.MedicationName) == "" {
+			it.MedicationName = e.brand
+		}
+		if strings.TrimSpace(it.Dosage) == "" {
+			it.Dosage = e.strength
+		}
+	}
+	return "", nil
+}
+
+// medicationEntry is one presentation read from an import file. reimbursable
+// is nil and hasPrice false when the file has no such column, so that the
+// recorded values are kept.
+type medicationEntry struct {
+	registration, dci, brand, form, strength, packaging, laboratory, country string
+	generic                                                                  bool
+	reimbursable                                                             *bool
+	hasPrice                                                                 bool
+	price                                                                    *float64
+}
+
+// medicationColumns recognizes the columns of the nomenclature export (N°
+// ENREGISTREMENT, DENOMINATION COMMUNE INTERNATIONALE, NOM DE MARQUE, FORME,
+// DOSAGE, COND, LABORATOIRES..., PAYS..., TYPE) and their English names.
+// Keys are headers in upper case with accents, spaces and punctuation removed.
+var medicationColumns = []struct {
+	name  string
+	match func(key string) bool
+}{
+	{"registration", func(k string) bool {
+		return k == "REGISTRATIONNUMBER" || (strings.Contains(k, "ENREGISTREMENT") && !strings.Contains(k, "DATE"))
+	}},
+	{"dci", func(k string) bool { return k == "DCI" || strings.HasPrefix(k, "DENOMINATIONCOMMUNE") }},
+	{"brand", func(k string) bool { return k == "BRAND" || k == "MARQUE" || k == "NOMDEMARQUE" }},
+	{"form", func(k string) bool { return k == "FORM" || k == "FORME" }},
+	{"strength", func(k string) bool { return k == "STRENGTH" || k == "DOSAGE" }},
+	{"packaging", func(k string) bool { return k == "PACKAGING" || strings.HasPrefix(k, "COND") }},
+	{"laboratory", func(k string) bool { return k == "LABORATORY" || strings.HasPrefix(k, "LABORATOIRE") }},
+	{"country", func(k string) bool { return k == "COUNTRY" || strings.HasPrefix(k, "PAYS") }},
+	{"type", func(k string) bool { return k == "TYPE" || k == "ISGENERIC" }},
+	{"reimbursable", func(k string) bool { return strings.Contains(k, "REMBOURS") || strings.Contains(k, "REIMBURS") }},
+	{"price", func(k string) bool { return strings.Contains(k, "TARIF") || k == "REFERENCEPRICE" }},
+}
+
+// medicationHeaderKey reduces a header to the form medicationColumns matches.
+func medicationHeaderKey(header string) string {
+	accents := strings.NewReplacer("É", "E", "È", "E", "Ê", "E", "À", "A", "Â", "A", "Ô", "O", "Ç", "C", "Î", "I", "Û", "U")
+	header = accents.Replace(strings.ToUpper(header))
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return -1
+	}, header)
+}
+
+// ImportMedications handles POST /api/admin/medications/import?replace=
+// The "file" part is a CSV export of the national nomenclature, separated by
+// "," or ";"; title rows above the header are ignored. Reimbursement columns
+// (REMBOURSABLE, TARIF DE REFERENCE) from the CNAS list are read when
+// present; without them the recorded reimbursement is kept. Latin-1 files are converted. Presentations are upserted by
+// registration number; with replace=true the active ones missing from the
+// file are deactivated.
+func ImportMedications(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxMedicationImportBytes+64<<10)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var tooBig *http.MaxBytesError
+		if errors.As(err, &tooBig) {
+			writeError(w, http.StatusRequestEntityTooLarge, "حجم الملف يتجاوز الحد المسموح")
+		} else {
+			writeError(w, http.StatusBadRequest, "الملف مطلوب")
+		}
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "الملف مطلوب")
+		return
+	}
+	if !utf8.Valid(data) {
+		data = latin1ToUTF8(data)
+	}
+	data = bytes.TrimPrefix(data, []byte("\uFEFF"))
+
+	result := models.MedicationImport{SkippedAt: []string{}}
+	skip := func(line int, text string) {
+		result.Skipped++
+		if len(result.SkippedAt) < maxSkippedLines {
+			result.SkippedAt = append(result.SkippedAt, "line "+strconv.Itoa(line)+": "+text)
+		}
+	}
+	entries, err := readMedicationCSV(data, skip)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "ملف CSV غير صالح، يجب أن يحتوي على أعمدة رقم التسجيل والتسمية المشتركة والعلامة التجارية")
+		return
+	}
+	if len(entries) == 0 {
+		writeError(w, http.StatusBadRequest, "لم يتم العثور على أي دواء في الملف")
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في استيراد قائمة الأدوية")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	registrations := make([]string, 0, len(entries))
+	for _, e := range entries {
+		batch.Queue(`INSERT INTO medications (registration_number, dci, brand, form, strength, packaging, laboratory,
+				country, is_generic, is_reimbursable, reference_price, source)
+			VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''),
+				$9, COALESCE($10, false), $11, $12)
+			ON CONFLICT (registration_number) DO UPDATE SET
+				dci = EXCLUDED.dci, brand = EXCLUDED.brand, form = EXCLUDED.form, strength = EXCLUDED.strength,
+				packaging = EXCLUDED.packaging, laboratory = EXCLUDED.laboratory, country = EXCLUDED.country,
+				is_generic = EXCLUDED.is_generic,
+				is_reimbursable = CASE WHEN $10::boolean IS NULL THEN medications.is_reimbursable ELSE EXCLUDED.is_reimbursable END,
+				reference_price = CASE WHEN $13 THEN EXCLUDED.reference_price ELSE medications.reference_price END,
+				source = EXCLUDED.source,
+				is_active = true, updated_at = NOW()`,
+			e.registration, e.dci, e.brand, e.form, e.strength, e.packaging, e.laboratory, e.country,
+			e.generic, e.reimbursable, e.price, models.MedicationSourceCSV, e.hasPrice)
+		registrations = append(registrations, e.registration)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في استيراد قائمة الأدوية")
+		return
+	}
+	result.Imported = len(entries)
+	if r.URL.Query().Get("replace") == "true" {
+		tag, err := tx.Exec(ctx,
+			`UPDATE medications SET is_active = false, updated_at = NOW()
+			 WHERE is_active AND NOT (registration_number = ANY($1))`, registrations)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "خطأ في استيراد قائمة الأدوية")
+			return
+		}
+		result.Deactivated = int(tag.RowsAffected())
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في استيراد قائمة الأدوية")
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// readMedicationCSV parses the nomenclature. The header is the first of the
+// first ten rows naming the registration number, DCI and brand columns.
+func readMedicationCSV(data []byte, skip func(int, string)) ([]medicationEntry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	if sample := string(data[:min(len(data), 4096)]); strings.Count(sample, ";") > strings.Count(sample, ",") {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	header := -1
+	var columns map[string]int
+	for i := 0; i < len(records) && i < 10 && header < 0; i++ {
+		columns = map[string]int{}
+		for j, name := range records[i] {
+			key := medicationHeaderKey(name)
+			for _, c := range medicationColumns {
+				if _, taken := columns[c.name]; !taken && c.match(key) {
+					columns[c.name] = j
+					break
+				}
+			}
+		}
+		_, hasRegistration := columns["registration"]
+		_, hasDCI := columns["dci"]
+		_, hasBrand := columns["brand"]
+		if hasRegistration && hasDCI && hasBrand {
+			header = i
+		}
+	}
+	if header < 0 {
+		return nil, errors.New("missing registration, dci or brand column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.Join(strings.Fields(record[i]), " ")
+		}
+		return ""
+	}
+	yes := func(v string) bool {
+		switch strings.ToUpper(v) {
+		case "O", "OUI", "Y", "YES", "X", "1", "TRUE":
+			return true
+		}
+		return false
+	}
+
+	seen := map[string]bool{}
+	var entries []medicationEntry
+	for i, record := range records[header+1:] {
+		line := header + i + 2
+		e := medicationEntry{
+			registration: field(record, "registration"),
+			dci:          field(record, "dci"),
+			brand:        field(record, "brand"),
+			form:         field(record, "form"),
+			strength:     field(record, "strength"),
+			packaging:    field(record, "packaging"),
+			laboratory:   field(record, "laboratory"),
+			country:      field(record, "country"),
+		}
+		if e.registration+e.dci+e.brand == "" {
+			continue // blank separator row
+		}
+		if _, ok := columns["reimbursable"]; ok {
+			reimbursable := yes(field(record, "reimbursable"))
+			e.reimbursable = &reimbursable
+		}
+		_, e.hasPrice = columns["price"]
+		kind := strings.ToUpper(field(record, "type"))
+		e.generic = kind == "GE" || yes(kind)
+		if price := strings.ReplaceAll(strings.ReplaceAll(field(record, "price"), " ", ""), ",", "."); price != "" {
+			if v, err := strconv.ParseFloat(price, 64); err == nil && v >= 0 {
+				e.price = &v
+			}
+		}
+		if e.registration == "" || e.dci == "" || e.brand == "" ||
+			len(e.registration) > 50 || utf8.RuneCountInString(e.dci) > 255 || utf8.RuneCountInString(e.brand) > 255 ||
+			utf8.RuneCountInString(e.form) > 100 || utf8.RuneCountInString(e.strength) > 100 ||
+			utf8.RuneCountInString(e.packaging) > 100 || utf8.RuneCountInString(e.laboratory) > 255 ||
+			utf8.RuneCountInString(e.country) > 100 {
+			skip(line, strings.Join(record, string(reader.Comma)))
+			continue
+		}
+		if !seen[e.registration] {
+			seen[e.registration] = true
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
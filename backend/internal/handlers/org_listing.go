@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// GetOrgListing handles GET /api/org/listing
+func GetOrgListing(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	orgID, err := activeOrgID(context.Background(), claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+
+	var listing models.OrgListing
+	database.Pool.QueryRow(context.Background(),
+		`SELECT p.id, p.is_published,
+		        (SELECT COUNT(*) FROM provider_services ps WHERE ps.provider_id = p.id)
+		 FROM providers p WHERE p.org_id = $1
+		 ORDER BY p.is_published DESC LIMIT 1`, orgID).Scan(&listing.ProviderID, &listing.IsPublished, &listing.Services)
+
+	writeJSON(w, http.StatusOK, listing)
+}
+
+// PublishOrgListing handles POST /api/org/listing
+// Creates (or refreshes) the public provider row for the caller's organization
+// and derives its services from the org's act and lab test prices.
+func PublishOrgListing(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+
+	var req models.PublishListingRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var ownerID, name, orgType, phone, address, wilayaID, city, communeCode, logo string
+	err = database.Pool.QueryRow(ctx,
+		`SELECT owner_id, name, org_type, COALESCE(phone, ''), COALESCE(address, ''),
+		        COALESCE(wilaya_id, ''), COALESCE(city, ''), COALESCE(commune_code, ''), COALESCE(logo_url, '')
+		 FROM organizations WHERE id = $1 AND is_active`, orgID).Scan(
+		&ownerID, &name, &orgType, &phone, &address, &wilayaID, &city, &communeCode, &logo)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المؤسسة غير موجودة")
+		return
+	}
+
+	// Request fields override the org record
+	pick := func(v, fallback string) string {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+		return fallback
+	}
+	wilayaID = pick(req.WilayaID, wilayaID)
+	city = pick(req.City, city)
+	communeCode = pick(req.CommuneCode, communeCode)
+	address = pick(req.Address, address)
+	phone = pick(req.Phone, phone)
+	image := pick(req.Image, logo)
+	openHours := strings.TrimSpace(req.OpenHours)
+
+	if wilayaID == "" {
+		writeError(w, http.StatusBadRequest, "الولاية مطلوبة لنشر الصفحة")
+		return
+	}
+	upd := models.ListingUpdate{Phone: &phone, Image: &image}
+	if openHours != "" {
+		upd.OpenHours = &openHours
+	}
+	if phone == "" {
+		upd.Phone = nil
+	}
+	if image == "" {
+		upd.Image = nil
+	}
+	if msg := validateListingUpdate(&upd); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	var wilayaName string
+	err = database.Pool.QueryRow(ctx,
+		`SELECT ar_name FROM wilayas WHERE id = $1`, wilayaID).Scan(&wilayaName)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "الولاية غير موجودة")
+		return
+	}
+	if communeCode != "" && !communeInWilaya(ctx, communeCode, wilayaID) {
+		if req.CommuneCode != "" {
+			writeError(w, http.StatusBadRequest, "البلدية لا تنتمي إلى ولاية المزود")
+			return
+		}
+		// The wilaya changed and the stored commune no longer fits
+		communeCode = ""
+	}
+
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في الخادم")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// Keep the org record in step with what it publishes
+	_, err = tx.Exec(ctx,
+		`UPDATE organizations SET wilaya_id = $1, city = NULLIF($2, ''), address = NULLIF($3, ''),
+		        phone = NULLIF($4, ''), commune_code = NULLIF($5, ''), updated_at = NOW()
+		 WHERE id = $6`, wilayaID, city, address, phone, communeCode, orgID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في تحديث المؤسسة")
+		return
+	}
+
+	// Org-backed listings get a stable ID derived from the org UUID
+	providerID := "org_" + strings.ReplaceAll(orgID, "-", "")[:16]
+	database.Pool.QueryRow(ctx,
+		`SELECT id FROM providers WHERE org_id = $1 ORDER BY is_published DESC LIMIT 1`, orgID).Scan(&providerID)
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO providers (id, name, type, wilaya, wilaya_id, city, address, phone, image, open_hours,
+		                        user_id, org_id, commune_code, is_published, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''),
+		         $11, $12, NULLIF($13, ''), TRUE, NOW())
+		 ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name, wilaya = EXCLUDED.wilaya, wilaya_id = EXCLUDED.wilaya_id,
+			city = EXCLUDED.city, commune_code = EXCLUDED.commune_code,
+			address = EXCLUDED.address, phone = EXCLUDED.phone,
+			image = COALESCE(EXCLUDED.image, providers.image),
+			open_hours = COALESCE(EXCLUDED.open_hours, providers.open_hours),
+			org_id = EXCLUDED.org_id, is_published = TRUE, updated_at = NOW()`,
+		providerID, name, strings.ToLower(orgType), wilayaName, wilayaID, city, address, phone, image, openHours,
+		ownerID, orgID, communeCode)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في نشر الصفحة")
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `SELECT sync_org_provider_services($1)`, orgID); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في مزامنة الخدمات")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	invalidateProvider(providerID)
+
+	var listing models.OrgListing
+	database.Pool.QueryRow(ctx,
+		`SELECT id, is_published, (SELECT COUNT(*) FROM provider_services WHERE provider_id = $1)
+		 FROM providers WHERE id = $1`, providerID).Scan(&listing.ProviderID, &listing.IsPublished, &listing.Services)
+
+	writeJSON(w, http.StatusOK, listing)
+}
+
+// UnpublishOrgListing handles DELETE /api/org/listing
+// The provider row is kept (with its reviews) but hidden from search.
+func UnpublishOrgListing(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	orgID, err := activeOrgID(context.Background(), claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+
+	_, err = database.Pool.Exec(context.Background(),
+		`UPDATE providers SET is_published = FALSE, updated_at = NOW() WHERE org_id = $1`, orgID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في إخفاء الصفحة")
+		return
+	}
+	invalidateProviders()
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تم إخفاء الصفحة من البحث")})
+}
+
+// ListOrgPrices handles GET /api/org/prices
+// Returns the full act and lab test catalogs with the org's own prices.
+func ListOrgPrices(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	lang := middleware.GetLang(r)
+	orgID, err := activeOrgID(context.Background(), claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+
+	rows, err := database.Pool.Query(context.Background(),
+		`SELECT 'act', a.code, a.name_ar, a.name_fr, COALESCE(a.category_ar, ''), COALESCE(a.category_fr, ''),
+		        a.base_price::FLOAT8, oap.custom_price::FLOAT8
+		 FROM ngap_acts a
+		 LEFT JOIN org_act_prices oap ON oap.ngap_act_id = a.id AND oap.org_id = $1
+		 WHERE a.is_active
+		 UNION ALL
+		 SELECT 'lab_test', t.code, t.name_ar, t.name_fr, COALESCE(t.category_ar, ''), COALESCE(t.category_fr, ''),
+		        COALESCE(t.price, 0)::FLOAT8, olp.custom_price::FLOAT8
+		 FROM lab_tests_catalog t
+		 LEFT JOIN org_lab_test_prices olp ON olp.test_id = t.id AND olp.org_id = $1
+		 WHERE t.is_active
+		 ORDER BY 1, 2`, orgID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الأسعار")
+		return
+	}
+	defer rows.Close()
+
+	prices := []models.OrgPrice{}
+	for rows.Next() {
+		var p models.OrgPrice
+		if err := rows.Scan(&p.Kind, &p.Code, &p.NameAr, &p.NameFr, &p.CategoryAr, &p.CategoryFr,
+			&p.BasePrice, &p.Price); err != nil {
+			continue
+		}
+		p.Label = i18n.Pick(lang, p.NameAr, p.NameFr, p.NameFr)
+		prices = append(prices, p)
+	}
+	writeJSON(w, http.StatusOK, prices)
+}
+
+// SetOrgActPrice handles PUT /api/org/prices/acts/{code}
+func SetOrgActPrice(w http.ResponseWriter, r *http.Request) {
+	setOrgPrice(w, r,
+		`INSERT INTO org_act_prices (org_id, ngap_act_id, custom_price)
+		 SELECT $1, id, $3 FROM ngap_acts WHERE code = $2 AND is_active
+		 ON CONFLICT (org_id, ngap_act_id) DO UPDATE SET custom_price = EXCLUDED.custom_price`)
+}
+
+// DeleteOrgActPrice handles DELETE /api/org/prices/acts/{code}
+func DeleteOrgActPrice(w http.ResponseWriter, r *http.Request) {
+	deleteOrgPrice(w, r,
+		`DELETE FROM org_act_prices oap USING ngap_acts a
+		 WHERE oap.ngap_act_id = a.id AND oap.org_id = $1 AND a.code = $2`)
+}
+
+// SetOrgLabTestPrice handles PUT /api/org/prices/lab-tests/{code}
+func SetOrgLabTestPrice(w http.ResponseWriter, r *http.Request) {
+	setOrgPrice(w, r,
+		`INSERT INTO org_lab_test_prices (org_id, test_id, custom_price)
+		 SELECT $1, id, $3 FROM lab_tests_catalog WHERE code = $2 AND is_active
+		 ON CONFLICT (org_id, test_id) DO UPDATE SET custom_price = EXCLUDED.custom_price`)
+}
+
+// DeleteOrgLabTestPrice handles DELETE /api/org/prices/lab-tests/{code}
+func DeleteOrgLabTestPrice(w http.ResponseWriter, r *http.Request) {
+	deleteOrgPrice(w, r,
+		`DELETE FROM org_lab_test_prices olp USING lab_tests_catalog t
+		 WHERE olp.test_id = t.id AND olp.org_id = $1 AND t.code = $2`)
+}
+
+// setOrgPrice upserts a price. Published listings are re-synced by the
+// database trigger on the price tables.
+func setOrgPrice(w http.ResponseWriter, r *http.Request, upsert string) {
+	claims := middleware.GetClaims(r)
+	orgID, err := activeOrgID(context.Background(), claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+
+	var req models.SetPriceRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Price < 0 || req.Price > 10000000 {
+		writeError(w, http.StatusBadRequest, "السعر غير صالح")
+		return
+	}
+
+	tag, err := database.Pool.Exec(context.Background(), upsert, orgID, chi.URLParam(r, "code"), req.Price)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ السعر")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		writeError(w, http.StatusNotFound, "الرمز غير موجود في الدليل")
+		return
+	}
+	invalidateProviders()
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تم حفظ السعر")})
+}
+
+func deleteOrgPrice(w http.ResponseWriter, r *http.Request, del string) {
+	claims := middleware.GetClaims(r)
+	orgID, err := activeOrgID(context.Background(), claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+
+	tag, err := database.Pool.Exec(context.Background(), del, orgID, chi.URLParam(r, "code"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حذف السعر")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		writeError(w, http.StatusNotFound, "السعر غير موجود")
+		return
+	}
+	invalidateProviders()
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تم حذف السعر")})
+}
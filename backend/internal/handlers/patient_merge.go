@@ -0,0 +1,425 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultDuplicateScore = 40
+	maxDuplicates         = 20
+
+	// Points per matching field. The total is capped at 100.
+	duplicateNINPoints   = 60
+	duplicateChifaPoints = 50
+	duplicateDOBPoints   = 20
+	duplicatePhonePoints = 20
+	duplicateNamePoints  = 30
+	minNameSimilarity    = 0.75
+
+	// Names with more words are compared in their written order only.
+	maxNameWords = 5
+)
+
+// patientTables lists every table with a patient_id referencing erp_patients.
+// A merge moves their rows to the surviving record; a table missing here
+// would lose its rows when the merged patient is deleted.
+var patientTables = []string{
+	"medical_visits", "prescriptions", "appointments", "admissions",
+	"surgeries", "invoices", "cnas_claims", "lab_orders", "patient_links",
+	"patient_allergies", "patient_conditions",
+}
+
+// FindDuplicatePatients handles GET /api/org/patients/{id}/duplicates?min_score=
+// Scores the other patients of the organization on NIN, Chifa number, date of
+// birth, phone and name (across Arabic and French spellings).
+func FindDuplicatePatients(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	patient, err := loadERPPatient(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+	minScore, err := strconv.Atoi(r.URL.Query().Get("min_score"))
+	if err != nil || minScore < 1 || minScore > 100 {
+		minScore = defaultDuplicateScore
+	}
+
+	rows, err := database.Pool.Query(ctx, erpPatientSelect+` WHERE org_id = $1 AND id <> $2`, orgID, patient.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب المرضى")
+		return
+	}
+	defer rows.Close()
+
+	target := newPatientKeys(patient)
+	candidates := []models.DuplicateCandidate{}
+	for rows.Next() {
+		other, err := scanERPPatient(rows)
+		if err != nil {
+			continue
+		}
+		score, reasons := target.compare(newPatientKeys(other))
+		if score >= minScore {
+			candidates = append(candidates, models.DuplicateCandidate{Patient: other, Score: score, Reasons: reasons})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب المرضى")
+		return
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].Score > candidates[j].Score })
+	if len(candidates) > maxDuplicates {
+		candidates = candidates[:maxDuplicates]
+	}
+	writeJSON(w, http.StatusOK, candidates)
+}
+
+// MergePatients handles POST /api/org/patients/{id}/merge
+// Moves every record of source_id to the patient of the URL, fills the
+// survivor's empty fields from the source, deletes the source and writes
+// the merge to audit_log, all in one transaction.
+func MergePatients(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+
+	var req models.MergePatientsRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	survivor, err := loadERPPatient(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+	source, err := loadERPPatient(ctx, orgID, req.SourceID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+	if source.ID == survivor.ID {
+		writeError(w, http.StatusBadRequest, "لا يمكن دمج المريض مع نفسه")
+		return
+	}
+	if source.NIN != "" && survivor.NIN != "" && source.NIN != survivor.NIN {
+		writeError(w, http.StatusConflict, "لا يمكن دمج مريضين برقمي تعريف وطني مختلفين")
+		return
+	}
+	if source.PlatformUserID != nil && survivor.PlatformUserID != nil && *source.PlatformUserID != *survivor.PlatformUserID {
+		writeError(w, http.StatusConflict, "لا يمكن دمج ملفين مرتبطين بحسابين مختلفين")
+		return
+	}
+
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في دمج الملفين")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	// A record has at most one open account link. An active link wins over
+	// a pending one; between equals the survivor's is kept.
+	_, err = tx.Exec(ctx,
+		`UPDATE patient_links SET status = 'REVOKED', revoked_at = NOW(), revoked_by = $3
+		 WHERE patient_id = $1 AND status = 'PENDING'
+		   AND EXISTS(SELECT 1 FROM patient_links WHERE patient_id = $2 AND status = 'ACTIVE')`,
+		survivor.ID, source.ID, middleware.GetClaims(r).UserID)
+	if err == nil {
+		_, err = tx.Exec(ctx,
+			`UPDATE patient_links SET status = 'REVOKED', revoked_at = NOW(), revoked_by = $3
+			 WHERE patient_id = $2 AND status IN ('PENDING', 'ACTIVE')
+			   AND EXISTS(SELECT 1 FROM patient_links WHERE patient_id = $1 AND status IN ('PENDING', 'ACTIVE'))`,
+			survivor.ID, source.ID, middleware.GetClaims(r).UserID)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في دمج الملفين")
+		return
+	}
+
+	moved := map[string]int64{}
+	for _, table := range patientTables {
+		tag, err := tx.Exec(ctx, `UPDATE `+table+` SET patient_id = $1 WHERE patient_id = $2`, survivor.ID, source.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "خطأ في دمج الملفين")
+			return
+		}
+		moved[table] = tag.RowsAffected()
+	}
+
+	// Clinical free text is kept from both records; everything else only
+	// fills what the survivor lacks.
+	_, err = tx.Exec(ctx,
+		`UPDATE erp_patients s SET
+			platform_user_id = COALESCE(s.platform_user_id, o.platform_user_id),
+			nin = COALESCE(s.nin, o.nin),
+			chifa_number = COALESCE(s.chifa_number, o.chifa_number),
+			full_name_fr = COALESCE(s.full_name_fr, o.full_name_fr),
+			date_of_birth = COALESCE(s.date_of_birth, o.date_of_birth),
+			gender = COALESCE(s.gender, o.gender),
+			blood_type = COALESCE(s.blood_type, o.blood_type),
+			marital_status = COALESCE(s.marital_status, o.marital_status),
+			phone = COALESCE(s.phone, o.phone),
+			phone_secondary = COALESCE(s.phone_secondary, CASE WHEN o.phone <> s.phone THEN o.phone END, o.phone_secondary),
+			email = COALESCE(s.email, o.email),
+			address = COALESCE(s.address, o.address),
+			wilaya_id = COALESCE(s.wilaya_id, o.wilaya_id),
+			commune_code = CASE WHEN s.wilaya_id IS NULL THEN o.commune_code ELSE s.commune_code END,
+			city = COALESCE(s.city, o.city),
+			insurance_type = CASE WHEN COALESCE(s.insurance_type, 'NONE') = 'NONE' THEN o.insurance_type ELSE s.insurance_type END,
+			insurance_number = COALESCE(s.insurance_number, o.insurance_number),
+			insurance_holder_name = COALESCE(s.insurance_holder_name, o.insurance_holder_name),
+			is_ald = COALESCE(s.is_ald, false) OR COALESCE(o.is_ald, false),
+			allergies = `+mergedText("allergies")+`,
+			chronic_conditions = `+mergedText("chronic_conditions")+`,
+			notes = `+mergedText("notes")+`,
+			emergency_name = COALESCE(s.emergency_name, o.emergency_name),
+			emergency_phone = COALESCE(s.emergency_phone, o.emergency_phone),
+			emergency_relation = COALESCE(s.emergency_relation, o.emergency_relation),
+			updated_at = NOW()
+		 FROM erp_patients o
+		 WHERE s.id = $1 AND o.id = $2`, survivor.ID, source.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في دمج الملفين")
+		return
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM erp_patients WHERE id = $1`, source.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في دمج الملفين")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		// Merged by someone else since it was loaded
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+
+	merged, err := scanERPPatient(tx.QueryRow(ctx, erpPatientSelect+` WHERE id = $1`, survivor.ID))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في دمج الملفين")
+		return
+	}
+	err = recordAudit(ctx, tx, r, orgID, "patient.merge", "erp_patient", survivor.ID,
+		map[string]interface{}{"survivor": survivor, "merged": source},
+		map[string]interface{}{"survivor": merged, "moved": moved})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في دمج الملفين")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في دمج الملفين")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.PatientMerge{Patient: merged, MergedID: source.ID, Moved: moved})
+}
+
+// mergedText joins a text column of the survivor (s) and the source (o),
+// keeping one copy when they are equal.
+func mergedText(column string) string {
+	s, o := "s."+column, "o."+column
+	return `CASE WHEN COALESCE(` + o + `, '') = '' OR ` + o + ` = ` + s + ` THEN ` + s +
+		` WHEN COALESCE(` + s + `, '') = '' THEN ` + o +
+		` ELSE ` + s + ` || E'\n' || ` + o + ` END`
+}
+
+// patientKeys are the normalized fields duplicates are matched on.
+type patientKeys struct {
+	nin, chifa, dob string
+	phones          []string
+	names           [][]string // name skeleton tokens, one entry per spelling
+}
+
+func newPatientKeys(p models.ERPPatient) patientKeys {
+	k := patientKeys{nin: p.NIN, chifa: p.ChifaNumber, dob: p.DateOfBirth}
+	for _, phone := range []string{p.Phone, p.PhoneSecondary} {
+		if n := nationalPhone(phone); n != "" {
+			k.phones = append(k.phones, n)
+		}
+	}
+	for _, name := range []string{p.FullNameAr, p.FullNameFr} {
+		if tokens := nameSkeleton(name); len(strings.Join(tokens, "")) >= 3 {
+			k.names = append(k.names, tokens)
+		}
+	}
+	return k
+}
+
+// compare scores how likely two patients are the same person.
+func (k patientKeys) compare(o patientKeys) (int, []string) {
+	score := 0
+	reasons := []string{}
+	match := func(ok bool, points int, reason string) {
+		if ok {
+			score += points
+			reasons = append(reasons, reason)
+		}
+	}
+	match(k.nin != "" && k.nin == o.nin, duplicateNINPoints, models.DuplicateNIN)
+	match(k.chifa != "" && k.chifa == o.chifa, duplicateChifaPoints, models.DuplicateChifa)
+	match(k.dob != "" && k.dob == o.dob, duplicateDOBPoints, models.DuplicateDateOfBirth)
+
+	samePhone := false
+	for _, a := range k.phones {
+		for _, b := range o.phones {
+			samePhone = samePhone || a == b
+		}
+	}
+	match(samePhone, duplicatePhonePoints, models.DuplicatePhone)
+
+	best := 0.0
+	for _, a := range k.names {
+		for _, b := range o.names {
+			if s := nameSimilarity(a, b); s > best {
+				best = s
+			}
+		}
+	}
+	if best >= minNameSimilarity {
+		score += int(best * duplicateNamePoints)
+		reasons = append(reasons, models.DuplicateName)
+	}
+
+	if score > 100 {
+		score = 100
+	}
+	return score, reasons
+}
+
+// nationalPhone keeps the last nine digits, so "0550 12 34 56" and
+// "+213 550123456" compare equal.
+func nationalPhone(s string) string {
+	digits := nonDigits.ReplaceAllString(s, "")
+	if len(digits) < 9 {
+		return ""
+	}
+	return digits[len(digits)-9:]
+}
+
+// arabicSkeleton transliterates Arabic letters to the consonants a French
+// spelling of the same name would use. Long vowels, hamza and ain map to
+// nothing, as Latin vowels do.
+var arabicSkeleton = strings.NewReplacer(
+	"ب", "b", "ت", "t", "ث", "t", "ج", "j", "ح", "h", "خ", "kh", "د", "d",
+	"ذ", "d", "ر", "r", "ز", "z", "س", "s", "ش", "sh", "ص", "s", "ض", "d",
+	"ط", "t", "ظ", "d", "غ", "gh", "ف", "f", "ق", "k", "ك", "k", "ل", "l",
+	"م", "m", "ن", "n", "ه", "h",
+	"ا", "", "و", "", "ي", "", "ء", "", "ع", "",
+)
+
+// latinSkeleton rewrites French spellings of Algerian names to the same
+// consonants ("Youcef" and "يوسف" both become "sf").
+var latinSkeleton = strings.NewReplacer(
+	"ch", "sh", "dj", "j", "th", "t", "ph", "f", "ou", "",
+	"ce", "s", "ci", "s", "c", "k", "q", "k", "x", "ks",
+	"a", "", "e", "", "i", "", "o", "", "u", "", "y", "", "w", "",
+)
+
+// nameSkeleton reduces each word of a name to its consonants, with doubled
+// letters collapsed ("Hassan" → "hsn"). The article is dropped: "الزهراء"
+// and "Zohra" are the same word.
+func nameSkeleton(name string) []string {
+	var tokens []string
+	for _, word := range strings.Fields(foldText(strings.ReplaceAll(name, "ة", ""))) {
+		if word == "el" || word == "al" {
+			continue
+		}
+		if strings.HasPrefix(word, "ال") && utf8.RuneCountInString(word) > 3 {
+			word = strings.TrimPrefix(word, "ال")
+		}
+		s := latinSkeleton.Replace(arabicSkeleton.Replace(word))
+		var b strings.Builder
+		var last rune
+		for _, r := range s {
+			if r >= 'a' && r <= 'z' && r != last {
+				b.WriteRune(r)
+				last = r
+			}
+		}
+		if b.Len() > 0 {
+			tokens = append(tokens, b.String())
+		}
+	}
+	return tokens
+}
+
+// nameSimilarity compares two skeletons as whole strings, trying every order
+// of b's words so "Khaled Abdelkader" matches "عبد القادر خالد" and
+// "Ben Ali" matches "Benali"; 1 means identical.
+func nameSimilarity(a, b []string) float64 {
+	target := strings.Join(a, "")
+	if len(b) > maxNameWords {
+		return textSimilarity(target, strings.Join(b, ""))
+	}
+	best := 0.0
+	permute(append([]string(nil), b...), 0, func(order []string) {
+		if s := textSimilarity(target, strings.Join(order, "")); s > best {
+			best = s
+		}
+	})
+	return best
+}
+
+// permute calls fn with every ordering of words[k:], in place.
+func permute(words []string, k int, fn func([]string)) {
+	if k >= len(words)-1 {
+		fn(words)
+		return
+	}
+	for i := k; i < len(words); i++ {
+		words[k], words[i] = words[i], words[k]
+		permute(words, k+1, fn)
+		words[k], words[i] = words[i], words[k]
+	}
+}
+
+// textSimilarity is 1 minus the edit distance relative to the longer string.
+func textSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	longest := len(a)
+	if len(b) > longest {
+		longest = len(b)
+	}
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,426 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ninPattern    = regexp.MustCompile(`^[0-9]{18}$`)
+	chifaPattern  = regexp.MustCompile(`^[0-9]{12}$`)
+	emailAddress  = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+	nonDigits     = regexp.MustCompile(`[^0-9]`)
+	bloodTypes    = []string{"A+", "A-", "B+", "B-", "AB+", "AB-", "O+", "O-"}
+	maritalStates = []string{"SINGLE", "MARRIED", "DIVORCED", "WIDOWED"}
+	insuranceKind = []string{"CNAS", "CASNOS", "MUTUAL", "PRIVATE", "NONE"}
+)
+
+// erpPatientSelect reads a patient in the column order of scanERPPatient.
+const erpPatientSelect = `SELECT id, platform_user_id::text, COALESCE(nin, ''), COALESCE(chifa_number, ''),
+	full_name_ar, COALESCE(full_name_fr, ''), COALESCE(to_char(date_of_birth, 'YYYY-MM-DD'), ''),
+	COALESCE(gender::text, ''), COALESCE(blood_type::text, ''), COALESCE(marital_status::text, ''),
+	COALESCE(phone, ''), COALESCE(phone_secondary, ''), COALESCE(email, ''), COALESCE(address, ''),
+	COALESCE(wilaya_id, ''), COALESCE(commune_code, ''), COALESCE(city, ''),
+	COALESCE(insurance_type::text, 'NONE'), COALESCE(insurance_number, ''), COALESCE(insurance_holder_name, ''),
+	COALESCE(insurance_coverage_pct, 0), COALESCE(is_ald, false),
+	COALESCE(allergies, ''), COALESCE(chronic_conditions, ''), COALESCE(notes, ''),
+	COALESCE(emergency_name, ''), COALESCE(emergency_phone, ''), COALESCE(emergency_relation, ''),
+	created_at, updated_at
+	FROM erp_patients`
+
+func scanERPPatient(row pgx.Row) (models.ERPPatient, error) {
+	var p models.ERPPatient
+	err := row.Scan(&p.ID, &p.PlatformUserID, &p.NIN, &p.ChifaNumber,
+		&p.FullNameAr, &p.FullNameFr, &p.DateOfBirth,
+		&p.Gender, &p.BloodType, &p.MaritalStatus,
+		&p.Phone, &p.PhoneSecondary, &p.Email, &p.Address,
+		&p.WilayaID, &p.CommuneCode, &p.City,
+		&p.InsuranceType, &p.InsuranceNumber, &p.InsuranceHolderName,
+		&p.InsuranceCoveragePct, &p.IsALD,
+		&p.Allergies, &p.ChronicConditions, &p.Notes,
+		&p.EmergencyName, &p.EmergencyPhone, &p.EmergencyRelation,
+		&p.CreatedAt, &p.UpdatedAt)
+	return p, err
+}
+
+// loadERPPatient reads a patient of the organization; other tenants' patients
+// are reported as missing.
+func loadERPPatient(ctx context.Context, orgID, id string) (models.ERPPatient, error) {
+	return scanERPPatient(database.Pool.QueryRow(ctx,
+		erpPatientSelect+` WHERE id::text = $1 AND org_id = $2`, id, orgID))
+}
+
+// patientColumn maps an input field to its column. cast is the SQL type of
+// enum and date columns.
+type patientColumn struct {
+	name  string
+	cast  string
+	value interface{} // *string, *int or *bool
+}
+
+func patientColumns(in *models.ERPPatientInput) []patientColumn {
+	return []patientColumn{
+		{"nin", "", in.NIN},
+		{"chifa_number", "", in.ChifaNumber},
+		{"full_name_ar", "", in.FullNameAr},
+		{"full_name_fr", "", in.FullNameFr},
+		{"date_of_birth", "date", in.DateOfBirth},
+		{"gender", "gender_type", in.Gender},
+		{"blood_type", "blood_type", in.BloodType},
+		{"marital_status", "marital_status", in.MaritalStatus},
+		{"phone", "", in.Phone},
+		{"phone_secondary", "", in.PhoneSecondary},
+		{"email", "", in.Email},
+		{"address", "", in.Address},
+		{"wilaya_id", "", in.WilayaID},
+		{"commune_code", "", in.CommuneCode},
+		{"city", "", in.City},
+		{"insurance_type", "insurance_type", in.InsuranceType},
+		{"insurance_number", "", in.InsuranceNumber},
+		{"insurance_holder_name", "", in.InsuranceHolderName},
+		{"insurance_coverage_pct", "", in.InsuranceCoveragePct},
+		{"is_ald", "", in.IsALD},
+		{"allergies", "", in.Allergies},
+		{"chronic_conditions", "", in.ChronicConditions},
+		{"notes", "", in.Notes},
+		{"emergency_name", "", in.EmergencyName},
+		{"emergency_phone", "", in.EmergencyPhone},
+		{"emergency_relation", "", in.EmergencyRelation},
+	}
+}
+
+// patientAssignments returns the columns set by the input with their value
+// placeholders. Empty strings are stored as NULL.
+func patientAssignments(in *models.ERPPatientInput, args *sqlArgs) (names, values []string) {
+	for _, c := range patientColumns(in) {
+		var expr string
+		switch v := c.value.(type) {
+		case *string:
+			if v == nil {
+				continue
+			}
+			expr = "NULLIF(" + args.add(*v) + ", '')"
+		case *int:
+			if v == nil {
+				continue
+			}
+			expr = args.add(*v)
+		case *bool:
+			if v == nil {
+				continue
+			}
+			expr = args.add(*v)
+		}
+		if c.cast != "" {
+			expr += "::" + c.cast
+		}
+		names = append(names, c.name)
+		values = append(values, expr)
+	}
+	return names, values
+}
+
+// ListERPPatients handles GET /api/org/patients?q=&name=&phone=&nin=&chifa=&page=&limit=
+// q matches any of name, phone, NIN or Chifa number; the other filters
+// narrow on one field. Names match anywhere, numbers by prefix.
+func ListERPPatients(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	page, limit, offset := parsePagination(r)
+	query := r.URL.Query()
+
+	args := sqlArgs{orgID}
+	where := ` WHERE org_id = $1`
+	nameMatch := func(term string) string {
+		p := args.add("%" + term + "%")
+		return `(full_name_ar ILIKE ` + p + ` OR full_name_fr ILIKE ` + p + `)`
+	}
+	phoneMatch := func(digits string) string {
+		p := args.add("%" + digits + "%")
+		return `(regexp_replace(COALESCE(phone, ''), '[^0-9]', '', 'g') LIKE ` + p +
+			` OR regexp_replace(COALESCE(phone_secondary, ''), '[^0-9]', '', 'g') LIKE ` + p + `)`
+	}
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		conds := []string{nameMatch(q)}
+		if digits := nonDigits.ReplaceAllString(q, ""); len(digits) >= 3 {
+			p := args.add(digits + "%")
+			conds = append(conds, phoneMatch(digits), `nin LIKE `+p, `chifa_number LIKE `+p)
+		}
+		where += ` AND (` + strings.Join(conds, " OR ") + `)`
+	}
+	if name := strings.TrimSpace(query.Get("name")); name != "" {
+		where += ` AND ` + nameMatch(name)
+	}
+	if phone := nonDigits.ReplaceAllString(query.Get("phone"), ""); phone != "" {
+		where += ` AND ` + phoneMatch(phone)
+	}
+	if nin := nonDigits.ReplaceAllString(query.Get("nin"), ""); nin != "" {
+		where += ` AND nin LIKE ` + args.add(nin+"%")
+	}
+	if chifa := nonDigits.ReplaceAllString(query.Get("chifa"), ""); chifa != "" {
+		where += ` AND chifa_number LIKE ` + args.add(chifa+"%")
+	}
+
+	var total int
+	if err := database.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM erp_patients`+where, args...).Scan(&total); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب المرضى")
+		return
+	}
+	sql := erpPatientSelect + where + ` ORDER BY updated_at DESC, id LIMIT ` + args.add(limit) + ` OFFSET ` + args.add(offset)
+	rows, err := database.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب المرضى")
+		return
+	}
+	defer rows.Close()
+
+	patients := []models.ERPPatient{}
+	for rows.Next() {
+		if p, err := scanERPPatient(rows); err == nil {
+			patients = append(patients, p)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, models.Page{Items: patients, Page: page, Limit: limit, Total: total})
+}
+
+// GetERPPatient handles GET /api/org/patients/{id}
+func GetERPPatient(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	p, err := loadERPPatient(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// CreateERPPatient handles POST /api/org/patients
+func CreateERPPatient(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+
+	var in models.ERPPatientInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if in.FullNameAr == nil || strings.TrimSpace(*in.FullNameAr) == "" {
+		writeError(w, http.StatusBadRequest, "اسم المريض بالعربية مطلوب")
+		return
+	}
+	if msg := validateERPPatient(ctx, orgID, "", &in, models.ERPPatient{}); msg != "" {
+		writeERPPatientError(w, msg)
+		return
+	}
+
+	args := sqlArgs{orgID}
+	names, values := patientAssignments(&in, &args)
+	var id string
+	err = database.Pool.QueryRow(ctx,
+		`INSERT INTO erp_patients (org_id, `+strings.Join(names, ", ")+`)
+		 VALUES ($1, `+strings.Join(values, ", ")+`) RETURNING id`, args...).Scan(&id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ المريض")
+		return
+	}
+
+	p, err := loadERPPatient(ctx, orgID, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ المريض")
+		return
+	}
+	writeJSON(w, http.StatusCreated, p)
+}
+
+// UpdateERPPatient handles PATCH /api/org/patients/{id}
+// Only the fields present in the body change; "" clears an optional field.
+func UpdateERPPatient(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	current, err := loadERPPatient(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+
+	var in models.ERPPatientInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if in.FullNameAr != nil && strings.TrimSpace(*in.FullNameAr) == "" {
+		writeError(w, http.StatusBadRequest, "اسم المريض بالعربية مطلوب")
+		return
+	}
+	if msg := validateERPPatient(ctx, orgID, current.ID, &in, current); msg != "" {
+		writeERPPatientError(w, msg)
+		return
+	}
+
+	args := sqlArgs{current.ID, orgID}
+	names, values := patientAssignments(&in, &args)
+	if len(names) == 0 {
+		writeJSON(w, http.StatusOK, current)
+		return
+	}
+	set := make([]string, len(names))
+	for i := range names {
+		set[i] = names[i] + " = " + values[i]
+	}
+	_, err = database.Pool.Exec(ctx,
+		`UPDATE erp_patients SET `+strings.Join(set, ", ")+`, updated_at = NOW()
+		 WHERE id = $1 AND org_id = $2`, args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ المريض")
+		return
+	}
+
+	p, err := loadERPPatient(ctx, orgID, current.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ المريض")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// duplicatePatientMessages are answered with 409 rather than 400.
+var duplicatePatientMessages = map[string]bool{
+	"يوجد مريض بنفس رقم التعريف الوطني": true,
+	"يوجد مريض بنفس رقم الشفاء":         true,
+}
+
+func writeERPPatientError(w http.ResponseWriter, msg string) {
+	if duplicatePatientMessages[msg] {
+		writeError(w, http.StatusConflict, msg)
+		return
+	}
+	writeError(w, http.StatusBadRequest, msg)
+}
+
+// validateERPPatient trims the input and checks every field it sets. current
+// is the stored patient on update (zero on create), so a commune is checked
+// against the wilaya it will end up in. Returns an error message, or "".
+func validateERPPatient(ctx context.Context, orgID, patientID string, in *models.ERPPatientInput, current models.ERPPatient) string {
+	for _, c := range patientColumns(in) {
+		if s, ok := c.value.(*string); ok && s != nil {
+			*s = strings.TrimSpace(*s)
+		}
+	}
+	set := func(s *string) bool { return s != nil && *s != "" }
+
+	if set(in.NIN) {
+		*in.NIN = nonDigits.ReplaceAllString(*in.NIN, "")
+		if !ninPattern.MatchString(*in.NIN) {
+			return "رقم التعريف الوطني يجب أن يتكون من 18 رقما"
+		}
+		if patientNumberTaken(ctx, orgID, patientID, "nin", *in.NIN) {
+			return "يوجد مريض بنفس رقم التعريف الوطني"
+		}
+	}
+	if set(in.ChifaNumber) {
+		*in.ChifaNumber = nonDigits.ReplaceAllString(*in.ChifaNumber, "")
+		if !chifaPattern.MatchString(*in.ChifaNumber) {
+			return "رقم الشفاء يجب أن يتكون من 12 رقما"
+		}
+		if patientNumberTaken(ctx, orgID, patientID, "chifa_number", *in.ChifaNumber) {
+			return "يوجد مريض بنفس رقم الشفاء"
+		}
+	}
+	if set(in.DateOfBirth) {
+		dob, err := time.Parse("2006-01-02", *in.DateOfBirth)
+		if err != nil || dob.After(time.Now()) || dob.Year() < 1900 {
+			return "تاريخ الميلاد غير صالح"
+		}
+	}
+	if set(in.Gender) && *in.Gender != "male" && *in.Gender != "female" {
+		return "الجنس غير صالح"
+	}
+	if set(in.BloodType) {
+		*in.BloodType = strings.ToUpper(*in.BloodType)
+		if !containsString(bloodTypes, *in.BloodType) {
+			return "فصيلة الدم غير صالحة"
+		}
+	}
+	if set(in.MaritalStatus) && !containsString(maritalStates, *in.MaritalStatus) {
+		return "الحالة العائلية غير صالحة"
+	}
+	if in.InsuranceType != nil && !containsString(insuranceKind, *in.InsuranceType) {
+		return "نوع التأمين غير صالح"
+	}
+	if in.InsuranceCoveragePct != nil && (*in.InsuranceCoveragePct < 0 || *in.InsuranceCoveragePct > 100) {
+		return "نسبة التغطية يجب أن تكون بين 0 و 100"
+	}
+	for _, phone := range []*string{in.Phone, in.PhoneSecondary, in.EmergencyPhone} {
+		if set(phone) && !phonePattern.MatchString(*phone) {
+			return "رقم الهاتف غير صالح"
+		}
+	}
+	if set(in.Email) {
+		*in.Email = strings.ToLower(*in.Email)
+		if !emailAddress.MatchString(*in.Email) {
+			return "البريد الإلكتروني غير صالح"
+		}
+	}
+
+	wilayaID := current.WilayaID
+	if in.WilayaID != nil {
+		wilayaID = *in.WilayaID
+		if wilayaID != "" && !wilayaExists(ctx, wilayaID) {
+			return "الولاية غير موجودة"
+		}
+	}
+	communeCode := current.CommuneCode
+	if in.CommuneCode != nil {
+		communeCode = *in.CommuneCode
+	}
+	if communeCode != "" && (in.CommuneCode != nil || in.WilayaID != nil) && !communeInWilaya(ctx, communeCode, wilayaID) {
+		return "البلدية لا تنتمي إلى الولاية"
+	}
+	return ""
+}
+
+// patientNumberTaken reports whether another patient of the organization
+// already has this NIN or Chifa number.
+func patientNumberTaken(ctx context.Context, orgID, patientID, column, value string) bool {
+	var taken bool
+	database.Pool.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM erp_patients
+		 WHERE org_id = $1 AND `+column+` = $2 AND id::text <> $3)`, orgID, value, patientID).Scan(&taken)
+	return taken
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,449 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+const maxResultDelayHours = 7 * 24
+
+// portalLinked lists the calling patient's ($1) active links with the
+// release rules of each organization. Portal queries start with it and join
+// their table on linked (k) by patient and organization.
+const portalLinked = `WITH linked AS (
+	SELECT l.patient_id, l.org_id, o.name AS org_name,
+	       COALESCE(s.show_appointments, TRUE) AS show_appointments,
+	       COALESCE(s.show_lab_results, TRUE) AS show_lab_results,
+	       COALESCE(s.lab_release, 'VALIDATED') AS lab_release,
+	       COALESCE(s.result_delay_hours, 0) AS result_delay_hours,
+	       COALESCE(s.show_prescriptions, TRUE) AS show_prescriptions,
+	       COALESCE(s.show_discharges, TRUE) AS show_discharges,
+	       COALESCE(s.show_invoices, TRUE) AS show_invoices
+	FROM patient_links l
+	JOIN organizations o ON o.id = l.org_id
+	LEFT JOIN org_portal_settings s ON s.org_id = l.org_id
+	WHERE l.user_id = $1 AND l.status = 'ACTIVE')
+`
+
+// labResultReleased is true for a lab_order_items row (i) of an order (lo)
+// the organization lets the patient see: validated or delivered, as its
+// lab_release rule allows, once the result delay has passed.
+const labResultReleased = `k.show_lab_results
+	AND i.status IN ('VALIDATED', 'DELIVERED')
+	AND (k.lab_release = 'VALIDATED' OR i.status = 'DELIVERED')
+	AND COALESCE(i.validated_at, i.completed_at, lo.ordered_at) <= NOW() - make_interval(hours => k.result_delay_hours)`
+
+// auditPortalAccess records a read of the patient's records through each of
+// their active links. The read is refused when it cannot be recorded.
+func auditPortalAccess(ctx context.Context, r *http.Request, what string) error {
+	rows, err := database.Pool.Query(ctx,
+		linkSelect+` WHERE l.user_id = $1 AND l.status = 'ACTIVE'`, middleware.GetClaims(r).UserID)
+	if err != nil {
+		return err
+	}
+	var links []models.PatientLink
+	for rows.Next() {
+		l, err := scanPatientLink(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		links = append(links, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, l := range links {
+		if err := auditLinkAccess(ctx, database.Pool, r, l, "view_"+what); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PortalAppointments handles GET /api/portal/appointments?upcoming=&page=&limit=
+func PortalAppointments(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	userID := middleware.GetClaims(r).UserID
+	lang := middleware.GetLang(r)
+	page, limit, offset := parsePagination(r)
+	if err := auditPortalAccess(ctx, r, "appointments"); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+
+	from := ` FROM appointments a
+		JOIN linked k ON k.patient_id = a.patient_id AND k.org_id = a.org_id
+		LEFT JOIN staff st ON st.id = a.doctor_id
+		WHERE k.show_appointments`
+	order := `a.scheduled_at DESC`
+	if r.URL.Query().Get("upcoming") == "true" {
+		from += ` AND a.scheduled_at >= NOW() AND a.status NOT IN ('CANCELLED', 'NO_SHOW', 'COMPLETED')`
+		order = `a.scheduled_at`
+	}
+
+	var total int
+	if err := database.Pool.QueryRow(ctx, portalLinked+`SELECT COUNT(*)`+from, userID).Scan(&total); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+	rows, err := database.Pool.Query(ctx,
+		portalLinked+`SELECT a.id, k.org_id, k.org_name, a.scheduled_at, COALESCE(a.duration_minutes, 30),
+		        a.status::text, COALESCE(a.reason, ''), COALESCE(st.full_name_ar, ''), COALESCE(st.full_name_fr, '')`+
+			from+` ORDER BY `+order+` LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+	defer rows.Close()
+
+	items := []models.PortalAppointment{}
+	for rows.Next() {
+		var a models.PortalAppointment
+		var doctorAr, doctorFr string
+		if rows.Scan(&a.ID, &a.OrgID, &a.OrgName, &a.ScheduledAt, &a.DurationMinutes,
+			&a.Status, &a.Reason, &doctorAr, &doctorFr) == nil {
+			a.Doctor = i18n.Pick(lang, doctorAr, doctorFr, doctorFr)
+			items = append(items, a)
+		}
+	}
+	writeJSON(w, http.StatusOK, models.Page{Items: items, Page: page, Limit: limit, Total: total})
+}
+
+// PortalLabResults handles GET /api/portal/lab-results?page=&limit=
+// Lists lab orders with at least one released result, and only those results.
+func PortalLabResults(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	userID := middleware.GetClaims(r).UserID
+	lang := middleware.GetLang(r)
+	page, limit, offset := parsePagination(r)
+	if err := auditPortalAccess(ctx, r, "lab_results"); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+
+	from := ` FROM lab_orders lo
+		JOIN linked k ON k.patient_id = lo.patient_id AND k.org_id = lo.org_id
+		WHERE EXISTS(SELECT 1 FROM lab_order_items i WHERE i.lab_order_id = lo.id AND ` + labResultReleased + `)`
+	var total int
+	if err := database.Pool.QueryRow(ctx, portalLinked+`SELECT COUNT(*)`+from, userID).Scan(&total); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+	rows, err := database.Pool.Query(ctx,
+		portalLinked+`SELECT lo.id, k.org_id, k.org_name, lo.order_number, lo.ordered_at`+
+			from+` ORDER BY lo.ordered_at DESC LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+	orders := []models.PortalLabOrder{}
+	index := map[string]int{}
+	var ids []string
+	for rows.Next() {
+		o := models.PortalLabOrder{Results: []models.PortalLabResult{}}
+		if rows.Scan(&o.ID, &o.OrgID, &o.OrgName, &o.OrderNumber, &o.OrderedAt) == nil {
+			index[o.ID] = len(orders)
+			ids = append(ids, o.ID)
+			orders = append(orders, o)
+		}
+	}
+	rows.Close()
+
+	if len(ids) > 0 {
+		rows, err = database.Pool.Query(ctx,
+			portalLinked+`SELECT i.lab_order_id, t.code, t.name_ar, t.name_fr,
+			        COALESCE(i.result_value, ''), COALESCE(i.result_unit, t.unit, ''), COALESCE(t.normal_range, ''),
+			        COALESCE(i.is_abnormal, FALSE), i.status::text, i.validated_at
+			 FROM lab_order_items i
+			 JOIN lab_orders lo ON lo.id = i.lab_order_id
+			 JOIN linked k ON k.patient_id = lo.patient_id AND k.org_id = lo.org_id
+			 JOIN lab_tests_catalog t ON t.id = i.test_id
+			 WHERE lo.id::text = ANY($2) AND `+labResultReleased+`
+			 ORDER BY t.code`, userID, ids)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+			return
+		}
+		for rows.Next() {
+			var orderID, nameAr, nameFr string
+			var res models.PortalLabResult
+			if rows.Scan(&orderID, &res.TestCode, &nameAr, &nameFr, &res.Value, &res.Unit, &res.NormalRange,
+				&res.IsAbnormal, &res.Status, &res.ValidatedAt) == nil {
+				res.TestName = i18n.Pick(lang, nameAr, nameFr, nameFr)
+				o := &orders[index[orderID]]
+				o.Results = append(o.Results, res)
+			}
+		}
+		rows.Close()
+	}
+	writeJSON(w, http.StatusOK, models.Page{Items: orders, Page: page, Limit: limit, Total: total})
+}
+
+// PortalPrescriptions handles GET /api/portal/prescriptions?page=&limit=
+func PortalPrescriptions(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	userID := middleware.GetClaims(r).UserID
+	lang := middleware.GetLang(r)
+	page, limit, offset := parsePagination(r)
+	if err := auditPortalAccess(ctx, r, "prescriptions"); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+
+	from := ` FROM prescriptions p
+		JOIN linked k ON k.patient_id = p.patient_id AND k.org_id = p.org_id
+		LEFT JOIN staff st ON st.id = p.doctor_id
+		WHERE k.show_prescriptions`
+	var total int
+	if err := database.Pool.QueryRow(ctx, portalLinked+`SELECT COUNT(*)`+from, userID).Scan(&total); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+	rows, err := database.Pool.Query(ctx,
+		portalLinked+`SELECT p.id, k.org_id, k.org_name, COALESCE(to_char(p.prescription_date, 'YYYY-MM-DD'), ''),
+		        COALESCE(st.full_name_ar, ''), COALESCE(st.full_name_fr, ''), COALESCE(p.notes, '')`+
+			from+` ORDER BY p.prescription_date DESC, p.created_at DESC LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+	prescriptions := []models.PortalPrescription{}
+	index := map[string]int{}
+	var ids []string
+	for rows.Next() {
+		p := models.PortalPrescription{Items: []models.PortalPrescriptionItem{}}
+		var doctorAr, doctorFr string
+		if rows.Scan(&p.ID, &p.OrgID, &p.OrgName, &p.Date, &doctorAr, &doctorFr, &p.Notes) == nil {
+			p.Doctor = i18n.Pick(lang, doctorAr, doctorFr, doctorFr)
+			index[p.ID] = len(prescriptions)
+			ids = append(ids, p.ID)
+			prescriptions = append(prescriptions, p)
+		}
+	}
+	rows.Close()
+
+	if len(ids) > 0 {
+		rows, err = database.Pool.Query(ctx,
+			`SELECT prescription_id, medication_name, COALESCE(dosage, ''), COALESCE(frequency, ''),
+			        COALESCE(duration, ''), quantity, COALESCE(instructions, '')
+			 FROM prescription_items WHERE prescription_id::text = ANY($1) ORDER BY position, medication_name`, ids)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+			return
+		}
+		for rows.Next() {
+			var prescriptionID string
+			var it models.PortalPrescriptionItem
+			if rows.Scan(&prescriptionID, &it.MedicationName, &it.Dosage, &it.Frequency,
+				&it.Duration, &it.Quantity, &it.Instructions) == nil {
+				p := &prescriptions[index[prescriptionID]]
+				p.Items = append(p.Items, it)
+			}
+		}
+		rows.Close()
+	}
+	writeJSON(w, http.StatusOK, models.Page{Items: prescriptions, Page: page, Limit: limit, Total: total})
+}
+
+// PortalDischarges handles GET /api/portal/discharges?page=&limit=
+// Only stays that ended with a discharge are shown.
+func PortalDischarges(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	userID := middleware.GetClaims(r).UserID
+	lang := middleware.GetLang(r)
+	page, limit, offset := parsePagination(r)
+	if err := auditPortalAccess(ctx, r, "discharges"); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+
+	from := ` FROM admissions a
+		JOIN linked k ON k.patient_id = a.patient_id AND k.org_id = a.org_id
+		LEFT JOIN staff st ON st.id = a.admitting_doctor_id
+		WHERE k.show_discharges AND a.status = 'DISCHARGED' AND a.discharged_at IS NOT NULL`
+	var total int
+	if err := database.Pool.QueryRow(ctx, portalLinked+`SELECT COUNT(*)`+from, userID).Scan(&total); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+	rows, err := database.Pool.Query(ctx,
+		portalLinked+`SELECT a.id, k.org_id, k.org_name, a.admitted_at, a.discharged_at,
+		        COALESCE(st.full_name_ar, ''), COALESCE(st.full_name_fr, ''), COALESCE(a.diagnosis, ''),
+		        COALESCE(a.discharge_summary, ''), COALESCE(a.discharge_prescriptions, ''), COALESCE(a.follow_up_notes, '')`+
+			from+` ORDER BY a.discharged_at DESC LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+	defer rows.Close()
+
+	items := []models.PortalDischarge{}
+	for rows.Next() {
+		var d models.PortalDischarge
+		var doctorAr, doctorFr string
+		if rows.Scan(&d.AdmissionID, &d.OrgID, &d.OrgName, &d.AdmittedAt, &d.DischargedAt,
+			&doctorAr, &doctorFr, &d.Diagnosis, &d.DischargeSummary, &d.DischargePrescriptions, &d.FollowUpNotes) == nil {
+			d.Doctor = i18n.Pick(lang, doctorAr, doctorFr, doctorFr)
+			items = append(items, d)
+		}
+	}
+	writeJSON(w, http.StatusOK, models.Page{Items: items, Page: page, Limit: limit, Total: total})
+}
+
+// PortalInvoices handles GET /api/portal/invoices?page=&limit=
+func PortalInvoices(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	userID := middleware.GetClaims(r).UserID
+	lang := middleware.GetLang(r)
+	page, limit, offset := parsePagination(r)
+	if err := auditPortalAccess(ctx, r, "invoices"); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+
+	from := ` FROM invoices inv
+		JOIN linked k ON k.patient_id = inv.patient_id AND k.org_id = inv.org_id
+		WHERE k.show_invoices AND inv.status <> 'DRAFT'`
+	var total int
+	if err := database.Pool.QueryRow(ctx, portalLinked+`SELECT COUNT(*)`+from, userID).Scan(&total); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+	rows, err := database.Pool.Query(ctx,
+		portalLinked+`SELECT inv.id, k.org_id, k.org_name, inv.invoice_number, inv.status::text, inv.issued_at, inv.due_at,
+		        COALESCE(inv.total, 0)::float8, COALESCE(inv.cnas_coverage, 0)::float8, COALESCE(inv.mutual_coverage, 0)::float8,
+		        COALESCE(inv.patient_amount, 0)::float8, COALESCE(inv.paid_amount, 0)::float8, COALESCE(inv.remaining, 0)::float8`+
+			from+` ORDER BY inv.issued_at DESC LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+	invoices := []models.PortalInvoice{}
+	index := map[string]int{}
+	var ids []string
+	for rows.Next() {
+		inv := models.PortalInvoice{Items: []models.PortalInvoiceItem{}}
+		if rows.Scan(&inv.ID, &inv.OrgID, &inv.OrgName, &inv.InvoiceNumber, &inv.Status, &inv.IssuedAt, &inv.DueAt,
+			&inv.Total, &inv.CNASCoverage, &inv.MutualCoverage, &inv.PatientAmount, &inv.PaidAmount, &inv.Remaining) == nil {
+			index[inv.ID] = len(invoices)
+			ids = append(ids, inv.ID)
+			invoices = append(invoices, inv)
+		}
+	}
+	rows.Close()
+
+	if len(ids) > 0 {
+		rows, err = database.Pool.Query(ctx,
+			`SELECT invoice_id, description_ar, COALESCE(description_fr, ''), COALESCE(quantity, 1),
+			        unit_price::float8, total::float8
+			 FROM invoice_items WHERE invoice_id::text = ANY($1) ORDER BY id`, ids)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+			return
+		}
+		for rows.Next() {
+			var invoiceID, descAr, descFr string
+			var it models.PortalInvoiceItem
+			if rows.Scan(&invoiceID, &descAr, &descFr, &it.Quantity, &it.UnitPrice, &it.Total) == nil {
+				it.Description = i18n.Pick(lang, descAr, descFr, descFr)
+				inv := &invoices[index[invoiceID]]
+				inv.Items = append(inv.Items, it)
+			}
+		}
+		rows.Close()
+	}
+	writeJSON(w, http.StatusOK, models.Page{Items: invoices, Page: page, Limit: limit, Total: total})
+}
+
+// GetPortalSettings handles GET /api/org/portal-settings
+func GetPortalSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	s, err := loadPortalSettings(ctx, orgID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الإعدادات")
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// UpdatePortalSettings handles PATCH /api/org/portal-settings
+func UpdatePortalSettings(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	var u models.PortalSettingsUpdate
+	if err := decodeJSON(r, &u); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if u.LabRelease != nil && *u.LabRelease != models.LabReleaseValidated && *u.LabRelease != models.LabReleaseDelivered {
+		writeError(w, http.StatusBadRequest, "قاعدة نشر النتائج غير صالحة")
+		return
+	}
+	if u.ResultDelayHours != nil && (*u.ResultDelayHours < 0 || *u.ResultDelayHours > maxResultDelayHours) {
+		writeError(w, http.StatusBadRequest, "مدة تأخير النتائج يجب أن تكون بين 0 و 168 ساعة")
+		return
+	}
+
+	_, err = database.Pool.Exec(ctx,
+		`INSERT INTO org_portal_settings AS s (org_id, show_appointments, show_lab_results, lab_release,
+			result_delay_hours, show_prescriptions, show_discharges, show_invoices)
+		 VALUES ($1, COALESCE($2, TRUE), COALESCE($3, TRUE), COALESCE($4, 'VALIDATED'),
+			COALESCE($5, 0), COALESCE($6, TRUE), COALESCE($7, TRUE), COALESCE($8, TRUE))
+		 ON CONFLICT (org_id) DO UPDATE SET
+			show_appointments = COALESCE($2, s.show_appointments),
+			show_lab_results = COALESCE($3, s.show_lab_results),
+			lab_release = COALESCE($4, s.lab_release),
+			result_delay_hours = COALESCE($5, s.result_delay_hours),
+			show_prescriptions = COALESCE($6, s.show_prescriptions),
+			show_discharges = COALESCE($7, s.show_discharges),
+			show_invoices = COALESCE($8, s.show_invoices),
+			updated_at = NOW()`,
+		orgID, u.ShowAppointments, u.ShowLabResults, u.LabRelease, u.ResultDelayHours,
+		u.ShowPrescriptions, u.ShowDischarges, u.ShowInvoices)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الإعدادات")
+		return
+	}
+	s, err := loadPortalSettings(ctx, orgID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الإعدادات")
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// loadPortalSettings returns the organization's rules, or the defaults.
+func loadPortalSettings(ctx context.Context, orgID string) (models.PortalSettings, error) {
+	s := models.PortalSettings{
+		ShowAppointments: true, ShowLabResults: true, LabRelease: models.LabReleaseValidated,
+		ShowPrescriptions: true, ShowDischarges: true, ShowInvoices: true,
+	}
+	err := database.Pool.QueryRow(ctx,
+		`SELECT show_appointments, show_lab_results, lab_release, result_delay_hours,
+		        show_prescriptions, show_discharges, show_invoices
+		 FROM org_portal_settings WHERE org_id = $1`, orgID).Scan(
+		&s.ShowAppointments, &s.ShowLabResults, &s.LabRelease, &s.ResultDelayHours,
+		&s.ShowPrescriptions, &s.ShowDischarges, &s.ShowInvoices)
+	if errors.Is(err, pgx.ErrNoRows) {
+		err = nil
+	}
+	return s, err
+}
@@ -0,0 +1,531 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// currentTreatmentDays is how far back the patient's prescriptions count as
+// their current treatment for duplicate and interaction checks.
+const currentTreatmentDays = 30
+
+// drug is a medication as the checks see it.
+type drug struct {
+	label      string
+	components []string        // DCIs (two for AMOXICILLINE/ACIDE CLAVULANIQUE)
+	substances map[string]bool // components and their classes
+	dupClasses []string        // classes checked for duplicate therapy
+}
+
+func (d drug) has(substance string) bool {
+	return d.substances[substance]
+}
+
+// splitDCI returns the components of a combination DCI.
+func splitDCI(dci string) []string {
+	var parts []string
+	for _, part := range strings.FieldsFunc(dci, func(r rune) bool { return r == '/' || r == '+' }) {
+		if part = normalizeSubstance(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// nameKey reduces a free-text medication name to what precedes its strength,
+// in lower case without accents (Augmentin 1g/125mg → augmentin), to look it
+// up among brands and DCIs.
+func nameKey(name string) string {
+	if i := strings.IndexFunc(name, unicode.IsDigit); i > 0 {
+		name = name[:i]
+	}
+	return strings.ToLower(normalizeSubstance(name))
+}
+
+// drugResolver finds the DCI of formulary references, brand names and DCI
+// names, and the classes of DCIs.
+type drugResolver struct {
+	byID   map[string]string // medication id → DCI
+	byName map[string]string // lower-case brand or DCI → DCI
+	class  map[string][]string
+	dup    map[string]bool // classes checked for duplicate therapy
+}
+
+func newDrugResolver(ctx context.Context, ids, names []string) (*drugResolver, error) {
+	res := &drugResolver{byID: map[string]string{}, byName: map[string]string{},
+		class: map[string][]string{}, dup: map[string]bool{}}
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		keys = append(keys, nameKey(name))
+	}
+	rows, err := database.Pool.Query(ctx,
+		`SELECT id::text, lower(brand), dci FROM medications
+		 WHERE id::text = ANY($1) OR lower(brand) = ANY($2) OR lower(dci) = ANY($2)
+		 ORDER BY is_active DESC, brand`, ids, keys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, brand, dci string
+		if err := rows.Scan(&id, &brand, &dci); err != nil {
+			return nil, err
+		}
+		res.byID[id] = dci
+		if _, ok := res.byName[brand]; !ok {
+			res.byName[brand] = dci
+		}
+		res.byName[strings.ToLower(dci)] = dci
+	}
+	return res, rows.Err()
+}
+
+// components returns the DCIs of a medication: from the formulary when it
+// is referenced or its name is a known brand or DCI, else its name taken as
+// a DCI.
+func (res *drugResolver) components(medicationID *string, name string) []string {
+	if medicationID != nil {
+		if dci, ok := res.byID[*medicationID]; ok {
+			return splitDCI(dci)
+		}
+	}
+	if dci, ok := res.byName[nameKey(name)]; ok {
+		return splitDCI(dci)
+	}
+	return splitDCI(nameKey(name))
+}
+
+// loadClasses reads the classes of the given DCIs.
+func (res *drugResolver) loadClasses(ctx context.Context, dcis []string) error {
+	rows, err := database.Pool.Query(ctx,
+		`SELECT m.dci, c.name, c.duplicate_check FROM drug_class_members m
+		 JOIN drug_classes c ON c.name = m.class_name
+		 WHERE m.dci = ANY($1)`, dcis)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var dci, class string
+		var dup bool
+		if err := rows.Scan(&dci, &class, &dup); err != nil {
+			return err
+		}
+		res.class[dci] = append(res.class[dci], class)
+		res.dup[class] = dup
+	}
+	return rows.Err()
+}
+
+func (res *drugResolver) drug(label string, components []string) drug {
+	d := drug{label: label, components: components, substances: map[string]bool{}}
+	for _, c := range components {
+		d.substances[c] = true
+		for _, class := range res.class[c] {
+			if !d.substances[class] && res.dup[class] {
+				d.dupClasses = append(d.dupClasses, class)
+			}
+			d.substances[class] = true
+		}
+	}
+	return d
+}
+
+// interactionRule is a drug_interactions row.
+type interactionRule struct {
+	a, b, condition, severity string
+	message                   string
+}
+
+// checkPrescription finds the conflicts of items with the patient's
+// allergies, conditions and current treatment, and between themselves.
+// exclude is a prescription left out of the current treatment (the one
+// being renewed). Messages are in lang; blocking alerts come first.
+func checkPrescription(ctx context.Context, orgID, patientID string, items []models.PrescriptionItem, exclude *string, lang string) ([]models.PrescriptionAlert, error) {
+	type entry struct {
+		medicationID *string
+		name, label  string
+	}
+	var prescribed, current []entry
+	for _, it := range items {
+		prescribed = append(prescribed, entry{it.MedicationID, it.MedicationName, joinWords(it.MedicationName, it.Dosage)})
+	}
+	rows, err := database.Pool.Query(ctx,
+		`SELECT i.medication_id::text, i.medication_name, COALESCE(i.dosage, ''), to_char(p.prescription_date, 'YYYY-MM-DD')
+		 FROM prescription_items i
+		 JOIN prescriptions p ON p.id = i.prescription_id
+		 WHERE p.patient_id = $1 AND p.org_id = $2 AND p.prescription_date >= CURRENT_DATE - $3::int
+		   AND ($4::uuid IS NULL OR p.id <> $4)`,
+		patientID, orgID, currentTreatmentDays, exclude)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var e entry
+		var dosage, date string
+		if err := rows.Scan(&e.medicationID, &e.name, &dosage, &date); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		e.label = joinWords(e.name, dosage) + " (" + date + ")"
+		current = append(current, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	type allergy struct {
+		substance, reaction, severity string
+	}
+	var allergies []allergy
+	rows, err = database.Pool.Query(ctx,
+		`SELECT substance, COALESCE(reaction, ''), severity FROM patient_allergies WHERE patient_id = $1`, patientID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var a allergy
+		if err := rows.Scan(&a.substance, &a.reaction, &a.severity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		allergies = append(allergies, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	type condition struct{ label, code string }
+	var conditions []condition
+	rows, err = database.Pool.Query(ctx,
+		`SELECT label, icd10_code FROM patient_conditions WHERE patient_id = $1 AND icd10_code IS NOT NULL`, patientID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var c condition
+		if err := rows.Scan(&c.label, &c.code); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		conditions = append(conditions, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Resolve every medication and allergy to DCIs and classes
+	var ids, names []string
+	for _, e := range append(append([]entry{}, prescribed...), current...) {
+		if e.medicationID != nil {
+			ids = append(ids, *e.medicationID)
+		}
+		names = append(names, e.name)
+	}
+	for _, a := range allergies {
+		names = append(names, a.substance)
+	}
+	res, err := newDrugResolver(ctx, ids, names)
+	if err != nil {
+		return nil, err
+	}
+	componentsOf := func(e entry) []string { return res.components(e.medicationID, e.name) }
+	var dcis []string
+	for _, e := range append(append([]entry{}, prescribed...), current...) {
+		dcis = append(dcis, componentsOf(e)...)
+	}
+	if err := res.loadClasses(ctx, dcis); err != nil {
+		return nil, err
+	}
+	newDrugs := make([]drug, len(prescribed))
+	for i, e := range prescribed {
+		newDrugs[i] = res.drug(e.label, componentsOf(e))
+	}
+	currentDrugs := make([]drug, len(current))
+	for i, e := range current {
+		currentDrugs[i] = res.drug(e.label, componentsOf(e))
+	}
+
+	var substances []string
+	for _, d := range append(append([]drug{}, newDrugs...), currentDrugs...) {
+		for s := range d.substances {
+			substances = append(substances, s)
+		}
+	}
+	var rules []interactionRule
+	rows, err = database.Pool.Query(ctx,
+		`SELECT substance_a, COALESCE(substance_b, ''), COALESCE(condition_code, ''), severity,
+		        COALESCE(description_ar, ''), COALESCE(description_fr, ''), COALESCE(description_en, '')
+		 FROM drug_interactions WHERE substance_a = ANY($1) OR substance_b = ANY($1)`, substances)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var rule interactionRule
+		var ar, fr, en string
+		if err := rows.Scan(&rule.a, &rule.b, &rule.condition, &rule.severity, &ar, &fr, &en); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rule.message = i18n.Pick(lang, ar, fr, en)
+		rules = append(rules, rule)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	alerts := []models.PrescriptionAlert{}
+	seen := map[string]bool{}
+	raise := func(a models.PrescriptionAlert) {
+		key := a.Type + "|" + a.Medication + "|" + a.With + "|" + a.Message
+		if !seen[key] {
+			seen[key] = true
+			alerts = append(alerts, a)
+		}
+	}
+	message := func(msg string, args ...interface{}) string {
+		return fmt.Sprintf(i18n.T(lang, msg), args...)
+	}
+
+	for _, d := range newDrugs {
+		// Allergies, to the substance, its class or a brand of it
+		for _, a := range allergies {
+			keys := append([]string{a.substance}, res.components(nil, a.substance)...)
+			for _, k := range keys {
+				if d.has(k) {
+					text := message("حساسية مسجلة لدى المريض تجاه %s", a.substance)
+					if a.reaction != "" {
+						text += " (" + a.reaction + ")"
+					}
+					raise(models.PrescriptionAlert{Level: models.AlertBlocking, Type: models.AlertAllergy,
+						Medication: d.label, With: a.substance, Severity: a.severity, Message: text})
+					break
+				}
+			}
+		}
+		// Contraindications with conditions
+		for _, rule := range rules {
+			if rule.condition == "" || !d.has(rule.a) {
+				continue
+			}
+			for _, c := range conditions {
+				if strings.HasPrefix(c.code, rule.condition) {
+					raise(models.PrescriptionAlert{Level: interactionLevel(rule.severity), Type: models.AlertCondition,
+						Medication: d.label, With: c.label, Severity: rule.severity, Message: rule.message})
+				}
+			}
+		}
+	}
+
+	// Pairs within the prescription, then with the current treatment
+	pair := func(x, y drug, sameList bool) {
+		if shared := sharedStrings(x.components, y.components); len(shared) > 0 {
+			level, text := models.AlertBlocking, message("المادة %s موصوفة مرتين", strings.Join(shared, ", "))
+			if !sameList {
+				level, text = models.AlertWarning, message("المادة %s موصوفة في العلاج الحالي", strings.Join(shared, ", "))
+			}
+			raise(models.PrescriptionAlert{Level: level, Type: models.AlertDuplicate, Medication: x.label, With: y.label, Message: text})
+		} else if shared := sharedStrings(x.dupClasses, y.dupClasses); len(shared) > 0 {
+			raise(models.PrescriptionAlert{Level: models.AlertWarning, Type: models.AlertDuplicate, Medication: x.label, With: y.label,
+				Message: message("دواءان من نفس الفئة العلاجية: %s", strings.Join(shared, ", "))})
+		}
+		for _, rule := range rules {
+			if rule.b == "" {
+				continue
+			}
+			if (x.has(rule.a) && y.has(rule.b)) || (x.has(rule.b) && y.has(rule.a)) {
+				raise(models.PrescriptionAlert{Level: interactionLevel(rule.severity), Type: models.AlertInteraction,
+					Medication: x.label, With: y.label, Severity: rule.severity, Message: rule.message})
+			}
+		}
+	}
+	for i, x := range newDrugs {
+		for _, y := range newDrugs[i+1:] {
+			pair(x, y, true)
+		}
+		for _, y := range currentDrugs {
+			pair(x, y, false)
+		}
+	}
+
+	sort.SliceStable(alerts, func(i, j int) bool {
+		return alerts[i].Level == models.AlertBlocking && alerts[j].Level != models.AlertBlocking
+	})
+	return alerts, nil
+}
+
+// interactionLevel blocks major and contraindicated interactions.
+func interactionLevel(severity string) string {
+	if severity == models.InteractionMajor || severity == models.InteractionContraindicated {
+		return models.AlertBlocking
+	}
+	return models.AlertWarning
+}
+
+func sharedStrings(a, b []string) []string {
+	var shared []string
+	for _, s := range a {
+		if containsString(b, s) && !containsString(shared, s) {
+			shared = append(shared, s)
+		}
+	}
+	return shared
+}
+
+func joinWords(values ...string) string {
+	var parts []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+const interactionSelect = `SELECT id, substance_a, substance_b, condition_code, severity,
+	COALESCE(description_fr, ''), COALESCE(description_ar, ''), COALESCE(description_en, '')
+	FROM drug_interactions`
+
+func scanInteraction(row pgx.Row) (models.DrugInteraction, error) {
+	var i models.DrugInteraction
+	err := row.Scan(&i.ID, &i.SubstanceA, &i.SubstanceB, &i.ConditionCode, &i.Severity,
+		&i.DescriptionFr, &i.DescriptionAr, &i.DescriptionEn)
+	return i, err
+}
+
+// ListDrugInteractions handles GET /api/admin/drug-interactions?substance=&page=&limit=
+func ListDrugInteractions(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	page, limit, offset := parsePagination(r)
+
+	args := sqlArgs{}
+	where := ``
+	if substance := normalizeSubstance(r.URL.Query().Get("substance")); substance != "" {
+		p := args.add(substance)
+		where = ` WHERE substance_a = ` + p + ` OR substance_b = ` + p
+	}
+	var total int
+	if err := database.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM drug_interactions`+where, args...).Scan(&total); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب قواعد التداخل الدوائي")
+		return
+	}
+	sql := interactionSelect + where + ` ORDER BY substance_a, substance_b NULLS LAST, condition_code
+		LIMIT ` + args.add(limit) + ` OFFSET ` + args.add(offset)
+	rows, err := database.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب قواعد التداخل الدوائي")
+		return
+	}
+	defer rows.Close()
+
+	interactions := []models.DrugInteraction{}
+	for rows.Next() {
+		if i, err := scanInteraction(rows); err == nil {
+			interactions = append(interactions, i)
+		}
+	}
+	writeJSON(w, http.StatusOK, models.Page{Items: interactions, Page: page, Limit: limit, Total: total})
+}
+
+// CreateDrugInteraction handles POST /api/admin/drug-interactions
+// substance_a and substance_b name DCIs or drug classes; a condition rule
+// gives condition_code (an ICD-10 code or category) instead of substance_b.
+func CreateDrugInteraction(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	var in models.DrugInteraction
+	if err := decodeJSON(r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	in.SubstanceA = normalizeSubstance(in.SubstanceA)
+	if in.SubstanceB != nil {
+		if b := normalizeSubstance(*in.SubstanceB); b != "" {
+			in.SubstanceB = &b
+		} else {
+			in.SubstanceB = nil
+		}
+	}
+	if in.ConditionCode != nil && strings.TrimSpace(*in.ConditionCode) != "" {
+		code, ok := normalizeICD10(*in.ConditionCode)
+		if !ok {
+			writeError(w, http.StatusBadRequest, "رمز التشخيص غير صالح")
+			return
+		}
+		in.ConditionCode = &code
+	} else {
+		in.ConditionCode = nil
+	}
+	if in.SubstanceA == "" || utf8.RuneCountInString(in.SubstanceA) > 255 ||
+		(in.SubstanceB != nil && utf8.RuneCountInString(*in.SubstanceB) > 255) ||
+		(in.SubstanceB == nil) == (in.ConditionCode == nil) {
+		writeError(w, http.StatusBadRequest, "القاعدة تربط مادة بمادة أخرى أو بمرض")
+		return
+	}
+	if !containsString([]string{models.InteractionMinor, models.InteractionModerate,
+		models.InteractionMajor, models.InteractionContraindicated}, in.Severity) {
+		writeError(w, http.StatusBadRequest, "درجة الخطورة غير صالحة")
+		return
+	}
+	in.DescriptionFr = strings.TrimSpace(in.DescriptionFr)
+	in.DescriptionAr = strings.TrimSpace(in.DescriptionAr)
+	in.DescriptionEn = strings.TrimSpace(in.DescriptionEn)
+	if in.DescriptionFr+in.DescriptionAr+in.DescriptionEn == "" {
+		writeError(w, http.StatusBadRequest, "وصف التداخل مطلوب")
+		return
+	}
+
+	var id string
+	err := database.Pool.QueryRow(ctx,
+		`INSERT INTO drug_interactions (substance_a, substance_b, condition_code, severity,
+			description_fr, description_ar, description_en, source)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), 'ADMIN')
+		 ON CONFLICT DO NOTHING
+		 RETURNING id`,
+		in.SubstanceA, in.SubstanceB, in.ConditionCode, in.Severity,
+		in.DescriptionFr, in.DescriptionAr, in.DescriptionEn).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(w, http.StatusConflict, "هذه القاعدة موجودة مسبقاً")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	i, err := scanInteraction(database.Pool.QueryRow(ctx, interactionSelect+` WHERE id = $1`, id))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	writeJSON(w, http.StatusCreated, i)
+}
+
+// DeleteDrugInteraction handles DELETE /api/admin/drug-interactions/{id}
+func DeleteDrugInteraction(w http.ResponseWriter, r *http.Request) {
+	tag, err := database.Pool.Exec(context.Background(),
+		`DELETE FROM drug_interactions WHERE id::text = $1`, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		writeError(w, http.StatusNotFound, "قاعدة التداخل غير موجودة")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تم حذف قاعدة التداخل")})
+}
@@ -0,0 +1,519 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/document"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const maxPrescriptionItems = 20
+
+var (
+	documents    *document.Renderer
+	publicAPIURL string // base of the verification links in QR codes
+)
+
+// InitPrescriptions sets the renderer of printed ordonnances and the public
+// API base their verification links point to. Must be called at startup.
+func InitPrescriptions(renderer *document.Renderer, apiURL string) {
+	documents = renderer
+	publicAPIURL = strings.TrimRight(apiURL, "/")
+}
+
+// prescriptionSelect reads a prescription in the column order of
+// scanPrescription.
+const prescriptionSelect = `SELECT p.id, p.patient_id, p.visit_id::text, p.doctor_id::text, COALESCE(s.full_name_ar, ''),
+	COALESCE(to_char(p.prescription_date, 'YYYY-MM-DD'), ''), COALESCE(p.notes, ''), p.renewed_from::text,
+	COALESCE(p.verification_code, ''), p.created_at
+	FROM prescriptions p
+	LEFT JOIN staff s ON s.id = p.doctor_id`
+
+func scanPrescription(row pgx.Row) (models.Prescription, error) {
+	p := models.Prescription{Items: []models.PrescriptionItem{}}
+	err := row.Scan(&p.ID, &p.PatientID, &p.VisitID, &p.DoctorID, &p.DoctorName,
+		&p.PrescriptionDate, &p.Notes, &p.RenewedFrom,
+		&p.VerificationCode, &p.CreatedAt)
+	p.VerificationCode = formatVerificationCode(p.VerificationCode)
+	return p, err
+}
+
+// formatVerificationCode groups a stored code as printed (XXXX-XXXX-XXXX).
+func formatVerificationCode(code string) string {
+	var groups []string
+	for len(code) > 4 {
+		groups, code = append(groups, code[:4]), code[4:]
+	}
+	return strings.Join(append(groups, code), "-")
+}
+
+// loadPrescriptionItems fills the medications of the given prescriptions.
+func loadPrescriptionItems(ctx context.Context, prescriptions []models.Prescription) error {
+	if len(prescriptions) == 0 {
+		return nil
+	}
+	index := make(map[string]int, len(prescriptions))
+	ids := make([]string, len(prescriptions))
+	for i, p := range prescriptions {
+		index[p.ID] = i
+		ids[i] = p.ID
+	}
+	rows, err := database.Pool.Query(ctx,
+		`SELECT i.prescription_id, i.medication_id::text, COALESCE(m.dci, ''), i.medication_name,
+		        COALESCE(i.dosage, ''), COALESCE(i.frequency, ''), COALESCE(i.duration, ''), i.quantity,
+		        COALESCE(i.instructions, '')
+		 FROM prescription_items i
+		 LEFT JOIN medications m ON m.id = i.medication_id
+		 WHERE i.prescription_id::text = ANY($1) ORDER BY i.position, i.medication_name`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var prescriptionID string
+		var it models.PrescriptionItem
+		if rows.Scan(&prescriptionID, &it.MedicationID, &it.DCI, &it.MedicationName, &it.Dosage,
+			&it.Frequency, &it.Duration, &it.Quantity, &it.Instructions) == nil {
+			p := &prescriptions[index[prescriptionID]]
+			p.Items = append(p.Items, it)
+		}
+	}
+	return rows.Err()
+}
+
+// loadPrescription reads a prescription of the organization with its items;
+// other tenants' prescriptions are reported as missing.
+func loadPrescription(ctx context.Context, orgID, id string) (models.Prescription, error) {
+	p, err := scanPrescription(database.Pool.QueryRow(ctx,
+		prescriptionSelect+` WHERE p.id::text = $1 AND p.org_id = $2`, id, orgID))
+	if err != nil {
+		return p, err
+	}
+	list := []models.Prescription{p}
+	err = loadPrescriptionItems(ctx, list)
+	return list[0], err
+}
+
+// ListPatientPrescriptions handles GET /api/org/patients/{id}/prescriptions?page=&limit=
+func ListPatientPrescriptions(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	patient, err := loadERPPatient(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+	page, limit, offset := parsePagination(r)
+
+	var total int
+	if err := database.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM prescriptions WHERE patient_id = $1 AND org_id = $2`,
+		patient.ID, orgID).Scan(&total); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الوصفات")
+		return
+	}
+	rows, err := database.Pool.Query(ctx,
+		prescriptionSelect+` WHERE p.patient_id = $1 AND p.org_id = $2
+		 ORDER BY p.prescription_date DESC, p.created_at DESC LIMIT $3 OFFSET $4`,
+		patient.ID, orgID, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الوصفات")
+		return
+	}
+	prescriptions := []models.Prescription{}
+	for rows.Next() {
+		if p, err := scanPrescription(rows); err == nil {
+			prescriptions = append(prescriptions, p)
+		}
+	}
+	rows.Close()
+	if err := loadPrescriptionItems(ctx, prescriptions); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الوصفات")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, models.Page{Items: prescriptions, Page: page, Limit: limit, Total: total})
+}
+
+// GetPrescription handles GET /api/org/prescriptions/{id}
+func GetPrescription(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	p, err := loadPrescription(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "الوصفة غير موجودة")
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// CreatePrescription handles POST /api/org/visits/{id}/prescriptions
+// The prescription is for the visit's patient, by the visit's doctor (or the
+// caller). renew_from renews an earlier prescription of the same patient,
+// with its medications unless items are given. Blocking alerts (allergy,
+// duplicate substance, major interaction) are answered with 409 unless
+// override_reason justifies them; the override is recorded in audit_log.
+func CreatePrescription(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	visit, err := loadVisit(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "الزيارة غير موجودة")
+		return
+	}
+	in, status, msg := readPrescriptionInput(ctx, r, orgID, visit)
+	if msg != "" {
+		writeError(w, status, msg)
+		return
+	}
+	notes := ""
+	if in.Notes != nil {
+		notes = strings.TrimSpace(*in.Notes)
+	}
+
+	alerts, err := checkPrescription(ctx, orgID, visit.PatientID, in.Items, in.RenewFrom, middleware.GetLang(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في التحقق من الوصفة")
+		return
+	}
+	var blocking []models.PrescriptionAlert
+	for _, a := range alerts {
+		if a.Level == models.AlertBlocking {
+			blocking = append(blocking, a)
+		}
+	}
+	reason := ""
+	if in.OverrideReason != nil {
+		reason = strings.TrimSpace(*in.OverrideReason)
+	}
+	if len(blocking) > 0 && reason == "" {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":  localize(w, "الوصفة تتعارض مع ملف المريض، يلزم تبرير لتجاوز التنبيهات"),
+			"alerts": alerts,
+		})
+		return
+	}
+	if utf8.RuneCountInString(reason) > 1000 {
+		writeError(w, http.StatusBadRequest, "التبرير لا يتجاوز 1000 حرف")
+		return
+	}
+
+	code, err := newVerificationCode()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الوصفة")
+		return
+	}
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الوصفة")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	err = tx.QueryRow(ctx,
+		`INSERT INTO prescriptions (org_id, visit_id, patient_id, doctor_id, notes, renewed_from, verification_code)
+		 VALUES ($1, $2, $3,
+			COALESCE($4::uuid, (SELECT id FROM staff WHERE org_id = $1 AND user_id = $5 LIMIT 1)),
+			NULLIF($6, ''), $7, $8)
+		 RETURNING id`,
+		orgID, visit.ID, visit.PatientID, visit.DoctorID, middleware.GetClaims(r).UserID,
+		notes, in.RenewFrom, code).Scan(&id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الوصفة")
+		return
+	}
+	for i, it := range in.Items {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO prescription_items (prescription_id, medication_id, medication_name, dosage, frequency,
+				duration, quantity, instructions, position)
+			 VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7, NULLIF($8, ''), $9)`,
+			id, it.MedicationID, it.MedicationName, it.Dosage, it.Frequency, it.Duration, it.Quantity,
+			it.Instructions, i)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "خطأ في حفظ الوصفة")
+			return
+		}
+	}
+	if len(blocking) > 0 {
+		override := map[string]interface{}{"alerts": blocking, "justification": reason}
+		if err := recordAudit(ctx, tx, r, orgID, "prescription.override", "prescription", id, nil, override); err != nil {
+			writeError(w, http.StatusInternalServerError, "خطأ في حفظ الوصفة")
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الوصفة")
+		return
+	}
+
+	p, err := loadPrescription(ctx, orgID, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الوصفة")
+		return
+	}
+	p.Alerts = alerts
+	writeJSON(w, http.StatusCreated, p)
+}
+
+// CheckPrescription handles POST /api/org/visits/{id}/prescriptions/check
+// Returns the alerts CreatePrescription would raise for the same body,
+// without saving.
+func CheckPrescription(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	visit, err := loadVisit(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "الزيارة غير موجودة")
+		return
+	}
+	in, status, msg := readPrescriptionInput(ctx, r, orgID, visit)
+	if msg != "" {
+		writeError(w, status, msg)
+		return
+	}
+	alerts, err := checkPrescription(ctx, orgID, visit.PatientID, in.Items, in.RenewFrom, middleware.GetLang(r))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في التحقق من الوصفة")
+		return
+	}
+	writeJSON(w, http.StatusOK, alerts)
+}
+
+// readPrescriptionInput decodes the prescription to write from visit. The
+// items of the renewed prescription are taken when none are given, and
+// renew_from is left holding its id, or nil. On error it returns the status
+// and message to answer with.
+func readPrescriptionInput(ctx context.Context, r *http.Request, orgID string, visit models.MedicalVisit) (models.PrescriptionInput, int, string) {
+	var in models.PrescriptionInput
+	if err := decodeJSON(r, &in); err != nil && !errors.Is(err, io.EOF) {
+		return in, http.StatusBadRequest, "Invalid request body"
+	}
+	if in.RenewFrom != nil && *in.RenewFrom == "" {
+		in.RenewFrom = nil
+	}
+	if in.RenewFrom != nil {
+		source, err := loadPrescription(ctx, orgID, *in.RenewFrom)
+		if err != nil || source.PatientID != visit.PatientID {
+			return in, http.StatusNotFound, "الوصفة المراد تجديدها غير موجودة"
+		}
+		if len(in.Items) == 0 {
+			in.Items = source.Items
+		}
+		in.RenewFrom = &source.ID
+	}
+	msg, err := resolveMedications(ctx, in.Items)
+	if err != nil {
+		return in, http.StatusInternalServerError, "خطأ في حفظ الوصفة"
+	}
+	if msg == "" {
+		msg = validatePrescriptionItems(in.Items)
+	}
+	return in, http.StatusBadRequest, msg
+}
+
+// validatePrescriptionItems trims the medications and checks them against
+// the column sizes. Returns an error message, or "".
+func validatePrescriptionItems(items []models.PrescriptionItem) string {
+	if len(items) == 0 || len(items) > maxPrescriptionItems {
+		return "الوصفة تحتوي على 1 إلى 20 دواء"
+	}
+	for i := range items {
+		it := &items[i]
+		for _, f := range []*string{&it.MedicationName, &it.Dosage, &it.Frequency, &it.Duration, &it.Instructions} {
+			*f = strings.TrimSpace(*f)
+		}
+		if it.MedicationName == "" || len([]rune(it.MedicationName)) > 255 {
+			return "اسم الدواء مطلوب ولا يتجاوز 255 حرفاً"
+		}
+		if len([]rune(it.Dosage)) > 100 || len([]rune(it.Frequency)) > 100 || len([]rune(it.Duration)) > 100 {
+			return "الجرعة والتكرار والمدة لا تتجاوز 100 حرف"
+		}
+		if it.Quantity != nil && (*it.Quantity < 1 || *it.Quantity > 100) {
+			return "الكمية يجب أن تكون بين 1 و 100"
+		}
+	}
+	return ""
+}
+
+// newVerificationCode returns 12 random characters (60 bits) from the link
+// code alphabet, which avoids look-alike letters and digits.
+func newVerificationCode() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = linkCodeAlphabet[int(b)%len(linkCodeAlphabet)]
+	}
+	return string(buf), nil
+}
+
+// PrescriptionPDF handles GET /api/org/prescriptions/{id}/pdf
+// Returns the printable ordonnance: organization header, prescriber,
+// patient, medications and a QR code pointing at the verification endpoint.
+func PrescriptionPDF(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	p, err := loadPrescription(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "الوصفة غير موجودة")
+		return
+	}
+
+	doc := document.Prescription{Reference: p.VerificationCode}
+	doc.Date, err = time.Parse("2006-01-02", p.PrescriptionDate)
+	if err != nil {
+		doc.Date = p.CreatedAt
+	}
+	var logoURL string
+	err = database.Pool.QueryRow(ctx,
+		`SELECT name, COALESCE(address, ''), COALESCE(phone, ''), COALESCE(logo_url, '')
+		 FROM organizations WHERE id = $1`, orgID).Scan(&doc.OrgName, &doc.OrgAddress, &doc.OrgPhone, &logoURL)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في إنشاء ملف PDF")
+		return
+	}
+	doc.Logo, doc.LogoType = loadLogo(ctx, logoURL)
+	if p.DoctorID != nil {
+		database.Pool.QueryRow(ctx,
+			`SELECT full_name_ar, COALESCE(full_name_fr, ''), COALESCE(specialty_ar, ''), COALESCE(specialty_fr, '')
+			 FROM staff WHERE id = $1`, *p.DoctorID).Scan(&doc.DoctorAr, &doc.DoctorFr, &doc.SpecialtyAr, &doc.SpecialtyFr)
+	}
+	err = database.Pool.QueryRow(ctx,
+		`SELECT e.full_name_ar, COALESCE(e.full_name_fr, ''),
+		        date_part('year', age(COALESCE(p.prescription_date, CURRENT_DATE), e.date_of_birth))::int
+		 FROM prescriptions p JOIN erp_patients e ON e.id = p.patient_id
+		 WHERE p.id = $1`, p.ID).Scan(&doc.PatientAr, &doc.PatientFr, &doc.AgeYears)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في إنشاء ملف PDF")
+		return
+	}
+	if p.RenewedFrom != nil {
+		var renewed time.Time
+		if database.Pool.QueryRow(ctx, `SELECT prescription_date FROM prescriptions WHERE id = $1`,
+			*p.RenewedFrom).Scan(&renewed) == nil {
+			doc.RenewalOf = &renewed
+		}
+	}
+	for _, it := range p.Items {
+		doc.Items = append(doc.Items, document.PrescriptionLine{
+			Medication: it.MedicationName, DCI: it.DCI, Dosage: it.Dosage, Frequency: it.Frequency,
+			Duration: it.Duration, Quantity: it.Quantity, Instructions: it.Instructions,
+		})
+	}
+	doc.Notes = p.Notes
+	if p.VerificationCode != "" {
+		doc.VerifyURL = publicAPIURL + "/api/prescriptions/verify/" + p.VerificationCode
+	}
+
+	pdf, err := documents.Prescription(doc)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في إنشاء ملف PDF")
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="ordonnance-`+p.VerificationCode+`.pdf"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(pdf)
+}
+
+// loadLogo reads an uploaded organization logo from media storage. Logos
+// elsewhere are not fetched; the ordonnance is printed without them.
+func loadLogo(ctx context.Context, logoURL string) ([]byte, string) {
+	key, ok := strings.CutPrefix(logoURL, mediaBaseURL+"/media/")
+	if !ok || logoURL == "" {
+		return nil, ""
+	}
+	imageType := map[string]string{".jpg": "JPG", ".jpeg": "JPG", ".png": "PNG", ".gif": "GIF"}[strings.ToLower(path.Ext(key))]
+	if imageType == "" {
+		return nil, ""
+	}
+	data, err := mediaStore.Get(ctx, key)
+	if err != nil {
+		return nil, ""
+	}
+	return data, imageType
+}
+
+// VerifyPrescription handles GET /api/prescriptions/verify/{code}
+// Public: confirms that an ordonnance was issued, for pharmacists scanning
+// its QR code. The patient appears by initials only.
+func VerifyPrescription(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	code := strings.ToUpper(strings.ReplaceAll(chi.URLParam(r, "code"), "-", ""))
+	if len(code) != 12 {
+		writeError(w, http.StatusNotFound, "الوصفة غير موجودة")
+		return
+	}
+
+	var v models.PrescriptionVerification
+	var id, patientName string
+	err := database.Pool.QueryRow(ctx,
+		`SELECT p.id, o.name, COALESCE(NULLIF(s.full_name_fr, ''), s.full_name_ar, ''),
+		        to_char(p.prescription_date, 'YYYY-MM-DD'), COALESCE(NULLIF(e.full_name_fr, ''), e.full_name_ar)
+		 FROM prescriptions p
+		 JOIN organizations o ON o.id = p.org_id
+		 JOIN erp_patients e ON e.id = p.patient_id
+		 LEFT JOIN staff s ON s.id = p.doctor_id
+		 WHERE p.verification_code = $1`, code).Scan(&id, &v.OrgName, &v.Doctor, &v.PrescriptionDate, &patientName)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "الوصفة غير موجودة")
+		return
+	}
+	v.VerificationCode = formatVerificationCode(code)
+	for _, word := range strings.Fields(patientName) {
+		v.PatientInitials += string([]rune(word)[:1]) + "."
+	}
+
+	v.Medications = []string{}
+	rows, err := database.Pool.Query(ctx,
+		`SELECT medication_name || COALESCE(' ' || dosage, '') FROM prescription_items
+		 WHERE prescription_id = $1 ORDER BY position, medication_name`, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الوصفات")
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var medication string
+		if rows.Scan(&medication) == nil {
+			v.Medications = append(v.Medications, medication)
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, v)
+}
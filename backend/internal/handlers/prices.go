@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anis7x/cliniclab/internal/cache"
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+)
+
+const (
+	defaultCheapest = 5
+	maxCheapest     = 50
+)
+
+// Stats are expensive aggregates over every published listing; a few
+// minutes of staleness is fine for "what does an FNS usually cost".
+var priceStatsCache = cache.New(10 * time.Minute)
+
+// priceFilter restricts provider_services to published listings, one service,
+// and the optional wilaya/type filters ($1 service, $2 wilaya, $3 type).
+const priceFilter = `
+	FROM provider_services ps
+	JOIN providers p ON p.id = ps.provider_id
+	WHERE p.is_published AND ps.price > 0 AND ps.service_id = $1
+	  AND ($2 = '' OR p.wilaya_id = $2)
+	  AND ($3 = '' OR p.type = $3)`
+
+// GetPriceStats handles GET /api/prices/stats?service=&wilaya=&type=&cheapest=
+// service is a taxonomy ID or any name/synonym that resolves to a single service.
+func GetPriceStats(w http.ResponseWriter, r *http.Request) {
+	term := strings.TrimSpace(r.URL.Query().Get("service"))
+	wilayaID := r.URL.Query().Get("wilaya")
+	provType := r.URL.Query().Get("type")
+	cheapest, err := strconv.Atoi(r.URL.Query().Get("cheapest"))
+	if err != nil || cheapest < 1 {
+		cheapest = defaultCheapest
+	}
+	if cheapest > maxCheapest {
+		cheapest = maxCheapest
+	}
+	if term == "" {
+		writeError(w, http.StatusBadRequest, "الخدمة مطلوبة")
+		return
+	}
+	if provType != "" && provType != "clinic" && provType != "lab" {
+		writeError(w, http.StatusBadRequest, "نوع المزود غير صالح")
+		return
+	}
+
+	ctx := context.Background()
+	serviceID := term
+	var svc models.MedicalService
+	err = database.Pool.QueryRow(ctx,
+		`SELECT id, name, category, COALESCE(name_fr, ''), COALESCE(name_en, ''), COALESCE(category_id, '')
+		 FROM medical_services WHERE id = $1`, serviceID).Scan(
+		&svc.ID, &svc.Name, &svc.Category, &svc.NameFr, &svc.NameEn, &svc.CategoryID)
+	if err != nil {
+		ids, _, err := resolveServiceIDs(ctx, models.SearchQuery{Service: term})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "خطأ في جلب الخدمة")
+			return
+		}
+		switch len(ids) {
+		case 0:
+			writeError(w, http.StatusNotFound, "الخدمة غير موجودة")
+			return
+		case 1:
+			serviceID = ids[0]
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error":      localize(w, "الخدمة غير محددة، اختر واحدة"),
+				"candidates": ids,
+			})
+			return
+		}
+		database.Pool.QueryRow(ctx,
+			`SELECT id, name, category, COALESCE(name_fr, ''), COALESCE(name_en, ''), COALESCE(category_id, '')
+			 FROM medical_services WHERE id = $1`, serviceID).Scan(
+			&svc.ID, &svc.Name, &svc.Category, &svc.NameFr, &svc.NameEn, &svc.CategoryID)
+	}
+
+	lang := middleware.GetLang(r)
+	svc.Label = i18n.Pick(lang, svc.Name, svc.NameFr, svc.NameEn)
+	cacheKey := strings.Join([]string{serviceID, wilayaID, provType, strconv.Itoa(cheapest), lang}, "|")
+	if cached, ok := priceStatsCache.Get(cacheKey); ok {
+		writeJSON(w, http.StatusOK, cached)
+		return
+	}
+
+	stats := models.PriceStats{
+		Service:     svc,
+		ByWilaya:    []models.PriceSummary{},
+		ByType:      []models.PriceSummary{},
+		Cheapest:    []models.CheapestOffer{},
+		GeneratedAt: time.Now().UTC(),
+	}
+	args := []interface{}{serviceID, wilayaID, provType}
+
+	err = database.Pool.QueryRow(ctx,
+		`SELECT COALESCE(MIN(ps.price), 0), COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY ps.price), 0),
+		        COALESCE(MAX(ps.price), 0), COUNT(*)`+priceFilter, args...).Scan(
+		&stats.Overall.Min, &stats.Overall.Median, &stats.Overall.Max, &stats.Overall.Count)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حساب الإحصائيات")
+		return
+	}
+
+	if err := loadPriceBreakdowns(ctx, &stats, lang, args, cheapest); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حساب الإحصائيات")
+		return
+	}
+
+	priceStatsCache.Set(cacheKey, stats)
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// loadPriceBreakdowns fills the by-wilaya, by-type and cheapest parts of
+// stats; args are the priceFilter arguments.
+func loadPriceBreakdowns(ctx context.Context, stats *models.PriceStats, lang string, args []interface{}, cheapest int) error {
+	rows, err := database.Pool.Query(ctx,
+		`SELECT p.wilaya_id, MAX(p.wilaya), COALESCE((SELECT name FROM wilayas wl WHERE wl.id = p.wilaya_id), ''),
+		        MIN(ps.price), percentile_cont(0.5) WITHIN GROUP (ORDER BY ps.price),
+		        MAX(ps.price), COUNT(*)`+priceFilter+`
+		 GROUP BY p.wilaya_id ORDER BY p.wilaya_id`, args...)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var s models.PriceSummary
+		var latinName string
+		if err := rows.Scan(&s.Key, &s.Label, &latinName, &s.Min, &s.Median, &s.Max, &s.Count); err != nil {
+			rows.Close()
+			return err
+		}
+		s.Label = i18n.Pick(lang, s.Label, latinName, latinName)
+		stats.ByWilaya = append(stats.ByWilaya, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = database.Pool.Query(ctx,
+		`SELECT p.type, MIN(ps.price), percentile_cont(0.5) WITHIN GROUP (ORDER BY ps.price),
+		        MAX(ps.price), COUNT(*)`+priceFilter+`
+		 GROUP BY p.type ORDER BY p.type`, args...)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var s models.PriceSummary
+		if err := rows.Scan(&s.Key, &s.Min, &s.Median, &s.Max, &s.Count); err != nil {
+			rows.Close()
+			return err
+		}
+		stats.ByType = append(stats.ByType, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Cheapest offer per provider
+	rows, err = database.Pool.Query(ctx,
+		`SELECT p.id, p.name, p.type, p.wilaya, p.wilaya_id, MIN(ps.price), p.rating`+priceFilter+`
+		 GROUP BY p.id ORDER BY MIN(ps.price), p.rating DESC LIMIT $4`, append(args, cheapest)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var o models.CheapestOffer
+		if err := rows.Scan(&o.ProviderID, &o.Name, &o.Type, &o.Wilaya, &o.WilayaID, &o.Price, &o.Rating); err != nil {
+			return err
+		}
+		stats.Cheapest = append(stats.Cheapest, o)
+	}
+	return rows.Err()
+}
@@ -2,48 +2,76 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/middleware"
 	"github.com/anis7x/cliniclab/internal/models"
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
 )
 
-// SearchProviders handles GET /api/providers/search?wilaya=&service=&sort=
+// SearchProviders handles GET /api/providers/search?wilaya=&commune=&type=&service=&service_id=&category=&specialty=&min_experience=&equipment_type=&origin=&price=&sort=&facets=
+// Every filter except service, service_id and min_experience accepts several
+// values. With facets=true the results are wrapped together with per-facet counts.
 func SearchProviders(w http.ResponseWriter, r *http.Request) {
-	wilaya := r.URL.Query().Get("wilaya")
-	service := r.URL.Query().Get("service")
-	sortBy := r.URL.Query().Get("sort")
-	if sortBy == "" {
-		sortBy = "rating"
+	start := time.Now()
+	ctx := context.Background()
+	q, err := parseSearchQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "سنوات الخبرة غير صالحة")
+		return
+	}
+	q.Lang = middleware.GetLang(r)
+
+	f, err := newSearchFilter(ctx, q)
+	if errors.Is(err, errInvalidPriceRange) {
+		writeError(w, http.StatusBadRequest, "نطاق السعر غير صالح")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في البحث")
+		return
 	}
 
-	// Build the query to fetch providers
+	// An unknown service leaves serviceIDs empty, which matches nothing
+	providers, err := searchProviders(ctx, f)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في البحث")
+		return
+	}
+	defer func() { logSearch(q, f, len(providers), time.Since(start)) }()
+
+	if !q.Facets {
+		writeJSON(w, http.StatusOK, providers)
+		return
+	}
+
+	facets, err := f.facets(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في البحث")
+		return
+	}
+	writeJSON(w, http.StatusOK, models.SearchResponse{Results: providers, Facets: facets})
+}
+
+// searchProviders runs the result query and attaches the matching services.
+func searchProviders(ctx context.Context, f *searchFilter) ([]models.Provider, error) {
+	var args sqlArgs
 	query := `
 		SELECT DISTINCT p.id, p.name, COALESCE(p.name_en, ''), p.type, p.wilaya, p.wilaya_id,
-			   COALESCE(p.city, ''), COALESCE(p.address, ''), COALESCE(p.phone, ''),
-			   p.rating, p.reviews_count, COALESCE(p.image, ''), COALESCE(p.open_hours, '')
-		FROM providers p
-		LEFT JOIN provider_services ps ON ps.provider_id = p.id
-		WHERE 1=1
-	`
-	args := []interface{}{}
-	argIdx := 1
-
-	if wilaya != "" {
-		query += ` AND (p.wilaya LIKE '%' || $` + itoa(argIdx) + ` || '%' OR p.wilaya_id = $` + itoa(argIdx) + `)`
-		args = append(args, wilaya)
-		argIdx++
-	}
-	if service != "" {
-		query += ` AND ps.name LIKE '%' || $` + itoa(argIdx) + ` || '%'`
-		args = append(args, service)
-		argIdx++
-	}
+			   COALESCE(p.city, ''), COALESCE(p.commune_code, ''), COALESCE(p.address, ''), COALESCE(p.phone, ''),
+			   p.rating, p.reviews_count, COALESCE(p.image, ''), COALESCE(p.open_hours, '')` +
+		searchFrom + f.where(&args, "")
 
 	// Order
-	switch sortBy {
+	switch f.q.SortBy {
 	case "price":
 		query += ` ORDER BY p.rating DESC` // price ordering needs service context, fallback to rating
 	case "name":
@@ -52,10 +80,9 @@ func SearchProviders(w http.ResponseWriter, r *http.Request) {
 		query += ` ORDER BY p.rating DESC`
 	}
 
-	rows, err := database.Pool.Query(context.Background(), query, args...)
+	rows, err := database.Pool.Query(ctx, query, args...)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "خطأ في البحث")
-		return
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -63,56 +90,49 @@ func SearchProviders(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var p models.Provider
 		err := rows.Scan(&p.ID, &p.Name, &p.NameEn, &p.Type, &p.Wilaya, &p.WilayaID,
-			&p.City, &p.Address, &p.Phone, &p.Rating, &p.ReviewsCount, &p.Image, &p.OpenHours)
+			&p.City, &p.CommuneCode, &p.Address, &p.Phone, &p.Rating, &p.ReviewsCount, &p.Image, &p.OpenHours)
 		if err != nil {
 			continue
 		}
+		p.Label = i18n.Pick(f.q.Lang, p.Name, p.NameEn, p.NameEn)
+		providers = append(providers, p)
+	}
+	rows.Close()
+
+	for i := range providers {
+		p := &providers[i]
 
-		// Fetch services for this provider (filtered if service search term provided)
-		svcQuery := `
-			SELECT COALESCE(service_id, ''), name, price, COALESCE(turnaround, ''),
-				   COALESCE(doctor_name, ''), COALESCE(doctor_specialty, ''), COALESCE(doctor_experience, ''),
-				   COALESCE(equipment_name, ''), COALESCE(equipment_type, ''), COALESCE(equipment_origin, '')
-			FROM provider_services WHERE provider_id = $1
-		`
-		svcArgs := []interface{}{p.ID}
-		if service != "" {
-			svcQuery += ` AND name LIKE '%' || $2 || '%'`
-			svcArgs = append(svcArgs, service)
+		// Fetch services for this provider, limited to the ones matching the service filters
+		svcArgs := sqlArgs{p.ID}
+		svcQuery := providerServiceSelect + `
+			LEFT JOIN medical_services ms ON ms.id = ps.service_id
+			WHERE ps.provider_id = $1`
+		for _, c := range f.serviceConds(&svcArgs, "") {
+			svcQuery += ` AND ` + c
 		}
+		svcQuery += ` ORDER BY ps.id`
 
-		svcRows, err := database.Pool.Query(context.Background(), svcQuery, svcArgs...)
+		svcRows, err := database.Pool.Query(ctx, svcQuery, svcArgs...)
 		if err != nil {
 			continue
 		}
-
 		for svcRows.Next() {
-			var s models.ProviderService
-			var dName, dSpec, dExp, eName, eType, eOrigin string
-			svcRows.Scan(&s.ServiceID, &s.Name, &s.Price, &s.Turnaround,
-				&dName, &dSpec, &dExp, &eName, &eType, &eOrigin)
-
-			if dName != "" {
-				s.Doctor = &models.Doctor{Name: dName, Specialty: dSpec, Experience: dExp}
-			}
-			if eName != "" {
-				s.Equipment = &models.Equipment{Name: eName, Type: eType, Origin: eOrigin}
+			if s, err := scanProviderService(svcRows, f.q.Lang); err == nil {
+				p.Services = append(p.Services, s)
 			}
-			p.Services = append(p.Services, s)
 		}
 		svcRows.Close()
 
 		if p.Services == nil {
 			p.Services = []models.ProviderService{}
 		}
-
-		providers = append(providers, p)
 	}
 
-	writeJSON(w, http.StatusOK, providers)
+	return providers, nil
 }
 
 // GetProvider handles GET /api/providers/{id}
+// Served from providerCache with ETag/Last-Modified validation.
 func GetProvider(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	if id == "" {
@@ -120,50 +140,166 @@ func GetProvider(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lang := middleware.GetLang(r)
+	key := id + ":" + lang
+	if v, ok := providerCache.Get(key); ok {
+		writeCached(w, r, v.(cachedResponse), providerCacheControl)
+		return
+	}
+
+	p, updatedAt, err := loadProvider(context.Background(), id, lang)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المزود غير موجود")
+		return
+	}
+
+	res, err := newCachedResponse(p, updatedAt)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في الخادم")
+		return
+	}
+	providerCache.Set(key, res)
+	writeCached(w, r, res, providerCacheControl)
+}
+
+// loadProvider reads a published provider with all its services, doctors and
+// equipment, and returns when it last changed.
+func loadProvider(ctx context.Context, id, lang string) (models.Provider, time.Time, error) {
 	var p models.Provider
-	err := database.Pool.QueryRow(context.Background(),
+	var updatedAt time.Time
+	err := database.Pool.QueryRow(ctx,
 		`SELECT id, name, COALESCE(name_en, ''), type, wilaya, wilaya_id,
-			    COALESCE(city, ''), COALESCE(address, ''), COALESCE(phone, ''),
-			    rating, reviews_count, COALESCE(image, ''), COALESCE(open_hours, '')
-		 FROM providers WHERE id = $1`, id).Scan(
+			    COALESCE(city, ''), COALESCE(commune_code, ''), COALESCE(address, ''), COALESCE(phone, ''),
+			    rating, reviews_count, COALESCE(image, ''), COALESCE(open_hours, ''), COALESCE(images, '{}'),
+			    latitude, longitude, COALESCE(updated_at, NOW())
+		 FROM providers WHERE id = $1 AND is_published`, id).Scan(
 		&p.ID, &p.Name, &p.NameEn, &p.Type, &p.Wilaya, &p.WilayaID,
-		&p.City, &p.Address, &p.Phone, &p.Rating, &p.ReviewsCount, &p.Image, &p.OpenHours)
+		&p.City, &p.CommuneCode, &p.Address, &p.Phone, &p.Rating, &p.ReviewsCount, &p.Image, &p.OpenHours, &p.Images,
+		&p.Latitude, &p.Longitude, &updatedAt)
 	if err != nil {
-		writeError(w, http.StatusNotFound, "المزود غير موجود")
-		return
+		return p, updatedAt, err
 	}
 
 	// Fetch all services
-	svcRows, err := database.Pool.Query(context.Background(),
-		`SELECT COALESCE(service_id, ''), name, price, COALESCE(turnaround, ''),
-			    COALESCE(doctor_name, ''), COALESCE(doctor_specialty, ''), COALESCE(doctor_experience, ''),
-			    COALESCE(equipment_name, ''), COALESCE(equipment_type, ''), COALESCE(equipment_origin, '')
-		 FROM provider_services WHERE provider_id = $1`, id)
+	svcRows, err := database.Pool.Query(ctx,
+		providerServiceSelect+` WHERE ps.provider_id = $1 ORDER BY ps.id`, id)
 	if err == nil {
-		defer svcRows.Close()
 		for svcRows.Next() {
-			var s models.ProviderService
-			var dName, dSpec, dExp, eName, eType, eOrigin string
-			svcRows.Scan(&s.ServiceID, &s.Name, &s.Price, &s.Turnaround,
-				&dName, &dSpec, &dExp, &eName, &eType, &eOrigin)
-			if dName != "" {
-				s.Doctor = &models.Doctor{Name: dName, Specialty: dSpec, Experience: dExp}
-			}
-			if eName != "" {
-				s.Equipment = &models.Equipment{Name: eName, Type: eType, Origin: eOrigin}
+			if s, err := scanProviderService(svcRows, lang); err == nil {
+				p.Services = append(p.Services, s)
 			}
-			p.Services = append(p.Services, s)
 		}
+		svcRows.Close()
 	}
+	p.Doctors, p.Equipment = providerStaff(ctx, id, lang)
 	if p.Services == nil {
 		p.Services = []models.ProviderService{}
 	}
+	p.Label = i18n.Pick(lang, p.Name, p.NameEn, p.NameEn)
+	return p, updatedAt, nil
+}
+
+// providerServiceSelect selects provider services (alias ps) with their
+// doctor (d) and equipment (e); scan rows with scanProviderService.
+const providerServiceSelect = `
+	SELECT ps.id, COALESCE(ps.service_id, ''), ps.name, ps.price, COALESCE(ps.turnaround, ''),
+	       d.id, COALESCE(d.name, ''), COALESCE(d.specialty, ''), COALESCE(d.experience_years, 0),
+	       e.id, COALESCE(e.name, ''), COALESCE(e.type, ''), COALESCE(e.origin, '')
+	FROM provider_services ps
+	LEFT JOIN provider_doctors d ON d.id = ps.doctor_id
+	LEFT JOIN provider_equipment e ON e.id = ps.equipment_id`
 
-	writeJSON(w, http.StatusOK, p)
+func scanProviderService(rows pgx.Rows, lang string) (models.ProviderService, error) {
+	var s models.ProviderService
+	var d models.Doctor
+	var e models.Equipment
+	var doctorID, equipmentID *int
+	err := rows.Scan(&s.ID, &s.ServiceID, &s.Name, &s.Price, &s.Turnaround,
+		&doctorID, &d.Name, &d.Specialty, &d.ExperienceYears,
+		&equipmentID, &e.Name, &e.Type, &e.Origin)
+	if err != nil {
+		return s, err
+	}
+	if doctorID != nil {
+		d.ID = *doctorID
+		d.Experience = formatExperience(d.ExperienceYears, lang)
+		s.Doctor = &d
+	}
+	if equipmentID != nil {
+		e.ID = *equipmentID
+		s.Equipment = &e
+	}
+	return s, nil
+}
+
+// providerStaff lists every doctor and machine of a provider.
+func providerStaff(ctx context.Context, providerID, lang string) ([]models.Doctor, []models.Equipment) {
+	doctors := []models.Doctor{}
+	rows, err := database.Pool.Query(ctx,
+		`SELECT id, name, COALESCE(specialty, ''), COALESCE(experience_years, 0)
+		 FROM provider_doctors WHERE provider_id = $1 ORDER BY name`, providerID)
+	if err == nil {
+		for rows.Next() {
+			var d models.Doctor
+			if rows.Scan(&d.ID, &d.Name, &d.Specialty, &d.ExperienceYears) == nil {
+				d.Experience = formatExperience(d.ExperienceYears, lang)
+				doctors = append(doctors, d)
+			}
+		}
+		rows.Close()
+	}
+
+	equipment := []models.Equipment{}
+	rows, err = database.Pool.Query(ctx,
+		`SELECT id, name, COALESCE(type, ''), COALESCE(origin, '')
+		 FROM provider_equipment WHERE provider_id = $1 ORDER BY name`, providerID)
+	if err == nil {
+		for rows.Next() {
+			var e models.Equipment
+			if rows.Scan(&e.ID, &e.Name, &e.Type, &e.Origin) == nil {
+				equipment = append(equipment, e)
+			}
+		}
+		rows.Close()
+	}
+	return doctors, equipment
+}
+
+// formatExperience renders years of experience in lang, e.g. "15 سنة",
+// "15 ans" or "15 years".
+func formatExperience(years int, lang string) string {
+	switch {
+	case years <= 0:
+		return ""
+	case years == 1:
+		return i18n.T(lang, "سنة واحدة")
+	}
+	return fmt.Sprintf(i18n.T(lang, "%d سنة"), years)
+}
+
+// parseExperience extracts the number of years from text like "15 سنة".
+func parseExperience(s string) int {
+	start := strings.IndexAny(s, "0123456789")
+	if start < 0 {
+		return 0
+	}
+	end := start
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	years, _ := strconv.Atoi(s[start:end])
+	return years
 }
 
 // GetWilayas handles GET /api/wilayas
 func GetWilayas(w http.ResponseWriter, r *http.Request) {
+	lang := middleware.GetLang(r)
+	key := "wilayas:" + lang
+	if v, ok := referenceCache.Get(key); ok {
+		writeCached(w, r, v.(cachedResponse), referenceCacheControl)
+		return
+	}
+
 	rows, err := database.Pool.Query(context.Background(),
 		`SELECT id, name, ar_name FROM wilayas ORDER BY id`)
 	if err != nil {
@@ -176,15 +312,41 @@ func GetWilayas(w http.ResponseWriter, r *http.Request) {
 	for rows.Next() {
 		var wil models.Wilaya
 		rows.Scan(&wil.ID, &wil.Name, &wil.ArName)
+		wil.Label = i18n.Pick(lang, wil.ArName, wil.Name, wil.Name)
 		result = append(result, wil)
 	}
-	writeJSON(w, http.StatusOK, result)
+
+	res, err := newCachedResponse(result, time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في الخادم")
+		return
+	}
+	referenceCache.Set(key, res)
+	writeCached(w, r, res, referenceCacheControl)
 }
 
-// GetServices handles GET /api/services
+// GetServices handles GET /api/services?category=
+// The category filter includes every sub-category.
 func GetServices(w http.ResponseWriter, r *http.Request) {
-	rows, err := database.Pool.Query(context.Background(),
-		`SELECT id, name, category FROM medical_services ORDER BY id`)
+	category := r.URL.Query().Get("category")
+	lang := middleware.GetLang(r)
+	key := "services:" + category + ":" + lang
+	if v, ok := referenceCache.Get(key); ok {
+		writeCached(w, r, v.(cachedResponse), referenceCacheControl)
+		return
+	}
+
+	rows, err := database.Pool.Query(context.Background(), categoryTreeCTE(1)+`
+		SELECT ms.id, ms.name, ms.category, COALESCE(ms.name_fr, ''), COALESCE(ms.name_en, ''),
+		       COALESCE(ms.category_id, ''),
+		       ARRAY(SELECT term FROM service_synonyms s WHERE s.service_id = ms.id ORDER BY s.id),
+		       ARRAY(SELECT ngap_code FROM service_code_mappings m
+		             WHERE m.service_id = ms.id AND m.ngap_code IS NOT NULL ORDER BY m.ngap_code),
+		       ARRAY(SELECT lab_test_code FROM service_code_mappings m
+		             WHERE m.service_id = ms.id AND m.lab_test_code IS NOT NULL ORDER BY m.lab_test_code)
+		FROM medical_services ms
+		WHERE $1 = '' OR ms.category_id IN (SELECT id FROM cats)
+		ORDER BY ms.id`, category)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "خطأ في جلب الخدمات")
 		return
@@ -194,10 +356,19 @@ func GetServices(w http.ResponseWriter, r *http.Request) {
 	services := []models.MedicalService{}
 	for rows.Next() {
 		var s models.MedicalService
-		rows.Scan(&s.ID, &s.Name, &s.Category)
+		rows.Scan(&s.ID, &s.Name, &s.Category, &s.NameFr, &s.NameEn, &s.CategoryID,
+			&s.Synonyms, &s.NgapCodes, &s.LabTestCodes)
+		s.Label = i18n.Pick(lang, s.Name, s.NameFr, s.NameEn)
 		services = append(services, s)
 	}
-	writeJSON(w, http.StatusOK, services)
+
+	res, err := newCachedResponse(services, time.Now())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في الخادم")
+		return
+	}
+	referenceCache.Set(key, res)
+	writeCached(w, r, res, referenceCacheControl)
 }
 
 // itoa converts an int to string for query building.
@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// reviewColumns is shared by every query that scans into scanReview.
+const reviewColumns = `
+	rv.id, rv.provider_id, COALESCE(split_part(pp.full_name, ' ', 1), ''),
+	rv.rating, COALESCE(rv.comment, ''),
+	CASE WHEN rv.appointment_id IS NOT NULL THEN 'appointment' ELSE 'lab_result' END,
+	rv.status, rv.reply, rv.replied_at, rv.created_at`
+
+func scanReview(row pgx.Row) (models.Review, error) {
+	var rv models.Review
+	var reply *string
+	var repliedAt *time.Time
+	err := row.Scan(&rv.ID, &rv.ProviderID, &rv.AuthorName, &rv.Rating, &rv.Comment,
+		&rv.VisitType, &rv.Status, &reply, &repliedAt, &rv.CreatedAt)
+	if err == nil && reply != nil && repliedAt != nil {
+		rv.Reply = &models.ReviewReply{Text: *reply, RepliedAt: *repliedAt}
+	}
+	return rv, err
+}
+
+// ListProviderReviews handles GET /api/providers/{id}/reviews?page=&limit=
+func ListProviderReviews(w http.ResponseWriter, r *http.Request) {
+	providerID := chi.URLParam(r, "id")
+	page, limit, offset := parsePagination(r)
+
+	var total int
+	err := database.Pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM provider_reviews WHERE provider_id = $1 AND status = 'PUBLISHED'`,
+		providerID).Scan(&total)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب التقييمات")
+		return
+	}
+
+	rows, err := database.Pool.Query(context.Background(),
+		`SELECT `+reviewColumns+`
+		 FROM provider_reviews rv
+		 LEFT JOIN profiles_patient pp ON pp.user_id = rv.patient_user_id
+		 WHERE rv.provider_id = $1 AND rv.status = 'PUBLISHED'
+		 ORDER BY rv.created_at DESC
+		 LIMIT $2 OFFSET $3`, providerID, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب التقييمات")
+		return
+	}
+	defer rows.Close()
+
+	reviews := []models.Review{}
+	for rows.Next() {
+		rv, err := scanReview(rows)
+		if err != nil {
+			continue
+		}
+		rv.Status = ""
+		reviews = append(reviews, rv)
+	}
+
+	writeJSON(w, http.StatusOK, models.Page{Items: reviews, Page: page, Limit: limit, Total: total})
+}
+
+// CreateReview handles POST /api/providers/{id}/reviews (patients only)
+// The patient must reference a completed appointment or a delivered lab order
+// at the organization behind this provider.
+func CreateReview(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	providerID := chi.URLParam(r, "id")
+
+	var req models.CreateReviewRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		writeError(w, http.StatusBadRequest, "التقييم يجب أن يكون بين 1 و 5")
+		return
+	}
+	if (req.AppointmentID == "") == (req.LabOrderID == "") {
+		writeError(w, http.StatusBadRequest, "يجب تحديد موعد أو طلب تحليل واحد")
+		return
+	}
+
+	ctx := context.Background()
+	var eligible bool
+	var err error
+	if req.AppointmentID != "" {
+		err = database.Pool.QueryRow(ctx,
+			`SELECT EXISTS(
+				SELECT 1 FROM appointments a
+				JOIN erp_patients ep ON ep.id = a.patient_id
+				JOIN providers p ON p.org_id = a.org_id
+				WHERE a.id = $1 AND p.id = $2 AND ep.platform_user_id = $3
+				  AND a.status = 'COMPLETED')`,
+			req.AppointmentID, providerID, claims.UserID).Scan(&eligible)
+	} else {
+		err = database.Pool.QueryRow(ctx,
+			`SELECT EXISTS(
+				SELECT 1 FROM lab_orders lo
+				JOIN erp_patients ep ON ep.id = lo.patient_id
+				JOIN providers p ON p.org_id = lo.org_id
+				WHERE lo.id = $1 AND p.id = $2 AND ep.platform_user_id = $3
+				  AND EXISTS(SELECT 1 FROM lab_order_items li
+				             WHERE li.lab_order_id = lo.id AND li.status = 'DELIVERED'))`,
+			req.LabOrderID, providerID, claims.UserID).Scan(&eligible)
+	}
+	if err != nil || !eligible {
+		writeError(w, http.StatusForbidden, "يمكنك التقييم فقط بعد زيارة مكتملة لدى هذا المزود")
+		return
+	}
+
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في الخادم")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var reviewID string
+	err = tx.QueryRow(ctx,
+		`INSERT INTO provider_reviews (provider_id, patient_user_id, appointment_id, lab_order_id, rating, comment)
+		 VALUES ($1, $2, NULLIF($3, '')::UUID, NULLIF($4, '')::UUID, $5, NULLIF($6, ''))
+		 RETURNING id`,
+		providerID, claims.UserID, req.AppointmentID, req.LabOrderID, req.Rating, strings.TrimSpace(req.Comment)).Scan(&reviewID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			writeError(w, http.StatusConflict, "لقد قمت بتقييم هذه الزيارة مسبقاً")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ التقييم")
+		return
+	}
+
+	if err := recomputeProviderRating(ctx, tx, providerID); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في تحديث التقييم")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	invalidateProvider(providerID)
+
+	writeJSON(w, http.StatusCreated, map[string]string{"id": reviewID})
+}
+
+// ReplyToReview handles POST /api/reviews/{id}/reply (provider owners only)
+func ReplyToReview(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	reviewID := chi.URLParam(r, "id")
+
+	var req models.ReplyReviewRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Reply = strings.TrimSpace(req.Reply)
+	if req.Reply == "" {
+		writeError(w, http.StatusBadRequest, "نص الرد مطلوب")
+		return
+	}
+
+	var providerID string
+	err := database.Pool.QueryRow(context.Background(),
+		`SELECT provider_id FROM provider_reviews WHERE id = $1`, reviewID).Scan(&providerID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "التقييم غير موجود")
+		return
+	}
+	if !canManageProvider(context.Background(), claims.UserID, providerID) {
+		writeError(w, http.StatusForbidden, "غير مصرح")
+		return
+	}
+
+	_, err = database.Pool.Exec(context.Background(),
+		`UPDATE provider_reviews SET reply = $1, replied_by = $2, replied_at = NOW(), updated_at = NOW()
+		 WHERE id = $3`, req.Reply, claims.UserID, reviewID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الرد")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تم نشر الرد")})
+}
+
+// ReportReview handles POST /api/reviews/{id}/report (any authenticated user)
+func ReportReview(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	reviewID := chi.URLParam(r, "id")
+
+	var req models.ReportReviewRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		writeError(w, http.StatusBadRequest, "سبب الإبلاغ مطلوب")
+		return
+	}
+
+	tag, err := database.Pool.Exec(context.Background(),
+		`INSERT INTO review_reports (review_id, reporter_id, reason)
+		 SELECT id, $2, $3 FROM provider_reviews WHERE id = $1
+		 ON CONFLICT (review_id, reporter_id) DO NOTHING`,
+		reviewID, claims.UserID, req.Reason)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البلاغ")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		var exists bool
+		database.Pool.QueryRow(context.Background(),
+			`SELECT EXISTS(SELECT 1 FROM provider_reviews WHERE id = $1)`, reviewID).Scan(&exists)
+		if !exists {
+			writeError(w, http.StatusNotFound, "التقييم غير موجود")
+			return
+		}
+		writeError(w, http.StatusConflict, "لقد أبلغت عن هذا التقييم مسبقاً")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]string{"message": localize(w, "تم إرسال البلاغ")})
+}
+
+// ListReviewReports handles GET /api/admin/review-reports?status=OPEN (platform admins)
+func ListReviewReports(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = models.ReportOpen
+	}
+	page, limit, offset := parsePagination(r)
+
+	var total int
+	database.Pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM review_reports WHERE status = $1`, status).Scan(&total)
+
+	rows, err := database.Pool.Query(context.Background(),
+		`SELECT rr.id, rr.review_id, rr.reporter_id, rr.reason, rr.status, rr.created_at, `+reviewColumns+`
+		 FROM review_reports rr
+		 JOIN provider_reviews rv ON rv.id = rr.review_id
+		 LEFT JOIN profiles_patient pp ON pp.user_id = rv.patient_user_id
+		 WHERE rr.status = $1
+		 ORDER BY rr.created_at
+		 LIMIT $2 OFFSET $3`, status, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب البلاغات")
+		return
+	}
+	defer rows.Close()
+
+	reports := []models.ReviewReport{}
+	for rows.Next() {
+		var rep models.ReviewReport
+		var reply *string
+		var repliedAt *time.Time
+		rv := &rep.Review
+		err := rows.Scan(&rep.ID, &rep.ReviewID, &rep.ReporterID, &rep.Reason, &rep.Status, &rep.CreatedAt,
+			&rv.ID, &rv.ProviderID, &rv.AuthorName, &rv.Rating, &rv.Comment,
+			&rv.VisitType, &rv.Status, &reply, &repliedAt, &rv.CreatedAt)
+		if err != nil {
+			continue
+		}
+		if reply != nil && repliedAt != nil {
+			rv.Reply = &models.ReviewReply{Text: *reply, RepliedAt: *repliedAt}
+		}
+		rep.ProviderID = rv.ProviderID
+		reports = append(reports, rep)
+	}
+
+	writeJSON(w, http.StatusOK, models.Page{Items: reports, Page: page, Limit: limit, Total: total})
+}
+
+// ModerateReview handles PATCH /api/admin/reviews/{id} (platform admins)
+// Hiding or restoring a review recomputes the provider's rating.
+func ModerateReview(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	reviewID := chi.URLParam(r, "id")
+
+	var req models.ModerateReviewRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Status != models.ReviewPublished && req.Status != models.ReviewHidden {
+		writeError(w, http.StatusBadRequest, "حالة غير صالحة")
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في الخادم")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var providerID string
+	err = tx.QueryRow(ctx,
+		`UPDATE provider_reviews
+		 SET status = $1, moderation_note = NULLIF($2, ''), moderated_by = $3, moderated_at = NOW(), updated_at = NOW()
+		 WHERE id = $4 RETURNING provider_id`,
+		req.Status, req.Note, claims.UserID, reviewID).Scan(&providerID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "التقييم غير موجود")
+		return
+	}
+
+	// Hiding a review closes its open reports
+	if req.Status == models.ReviewHidden {
+		_, err := tx.Exec(ctx,
+			`UPDATE review_reports SET status = 'RESOLVED', resolved_by = $1, resolved_at = NOW()
+			 WHERE review_id = $2 AND status = 'OPEN'`, claims.UserID, reviewID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+			return
+		}
+	}
+
+	if err := recomputeProviderRating(ctx, tx, providerID); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في تحديث التقييم")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	invalidateProvider(providerID)
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تم تحديث التقييم")})
+}
+
+// ResolveReviewReport handles PATCH /api/admin/review-reports/{id} (platform admins)
+func ResolveReviewReport(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	reportID := chi.URLParam(r, "id")
+
+	var req models.ResolveReportRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Status != models.ReportResolved && req.Status != models.ReportDismissed {
+		writeError(w, http.StatusBadRequest, "حالة غير صالحة")
+		return
+	}
+
+	tag, err := database.Pool.Exec(context.Background(),
+		`UPDATE review_reports SET status = $1, resolved_by = $2, resolved_at = NOW() WHERE id = $3`,
+		req.Status, claims.UserID, reportID)
+	if err != nil || tag.RowsAffected() == 0 {
+		writeError(w, http.StatusNotFound, "البلاغ غير موجود")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تم تحديث البلاغ")})
+}
+
+// recomputeProviderRating rebuilds providers.rating and reviews_count from
+// published reviews. Call it inside the transaction that changed a review.
+func recomputeProviderRating(ctx context.Context, tx pgx.Tx, providerID string) error {
+	_, err := tx.Exec(ctx,
+		`UPDATE providers SET
+			rating = COALESCE((SELECT ROUND(AVG(rating)::NUMERIC, 1) FROM provider_reviews
+			                   WHERE provider_id = $1 AND status = 'PUBLISHED'), 0),
+			reviews_count = (SELECT COUNT(*) FROM provider_reviews
+			                 WHERE provider_id = $1 AND status = 'PUBLISHED'),
+			updated_at = NOW()
+		 WHERE id = $1`, providerID)
+	return err
+}
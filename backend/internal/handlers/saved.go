@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const maxSavedSearches = 20
+
+// savedSearchIgnored are SearchProviders parameters that change how results
+// are shown, not which providers match, so they are not saved.
+var savedSearchIgnored = []string{"sort", "facets", "lang", "page", "limit"}
+
+// ListFavorites handles GET /api/favorites
+func ListFavorites(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	lang := middleware.GetLang(r)
+
+	rows, err := database.Pool.Query(context.Background(),
+		`SELECT p.id, p.name, COALESCE(p.name_en, ''), p.type, p.wilaya, p.wilaya_id,
+			    COALESCE(p.city, ''), COALESCE(p.commune_code, ''), COALESCE(p.address, ''), COALESCE(p.phone, ''),
+			    p.rating, p.reviews_count, COALESCE(p.image, ''), COALESCE(p.open_hours, ''),
+			    f.created_at
+		 FROM patient_favorites f
+		 JOIN providers p ON p.id = f.provider_id
+		 WHERE f.user_id = $1 AND p.is_published
+		 ORDER BY f.created_at DESC`, claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب المفضلة")
+		return
+	}
+	defer rows.Close()
+
+	favorites := []models.Favorite{}
+	for rows.Next() {
+		var f models.Favorite
+		p := &f.Provider
+		if err := rows.Scan(&p.ID, &p.Name, &p.NameEn, &p.Type, &p.Wilaya, &p.WilayaID,
+			&p.City, &p.CommuneCode, &p.Address, &p.Phone, &p.Rating, &p.ReviewsCount, &p.Image, &p.OpenHours,
+			&f.CreatedAt); err != nil {
+			continue
+		}
+		p.Label = i18n.Pick(lang, p.Name, p.NameEn, p.NameEn)
+		p.Services = []models.ProviderService{}
+		favorites = append(favorites, f)
+	}
+	writeJSON(w, http.StatusOK, favorites)
+}
+
+// AddFavorite handles PUT /api/favorites/{providerId}
+func AddFavorite(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	providerID := chi.URLParam(r, "providerId")
+
+	tag, err := database.Pool.Exec(context.Background(),
+		`INSERT INTO patient_favorites (user_id, provider_id)
+		 SELECT $1, id FROM providers WHERE id = $2 AND is_published
+		 ON CONFLICT DO NOTHING`, claims.UserID, providerID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		var exists bool
+		database.Pool.QueryRow(context.Background(),
+			`SELECT EXISTS(SELECT 1 FROM patient_favorites WHERE user_id = $1 AND provider_id = $2)`,
+			claims.UserID, providerID).Scan(&exists)
+		if !exists {
+			writeError(w, http.StatusNotFound, "المزود غير موجود")
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تمت الإضافة إلى المفضلة")})
+}
+
+// RemoveFavorite handles DELETE /api/favorites/{providerId}
+func RemoveFavorite(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	tag, err := database.Pool.Exec(context.Background(),
+		`DELETE FROM patient_favorites WHERE user_id = $1 AND provider_id = $2`,
+		claims.UserID, chi.URLParam(r, "providerId"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		writeError(w, http.StatusNotFound, "المزود ليس في المفضلة")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تمت الإزالة من المفضلة")})
+}
+
+const savedSearchColumns = `id, name, query, notify, last_checked_at, created_at`
+
+func scanSavedSearch(row pgx.Row) (models.SavedSearch, error) {
+	var s models.SavedSearch
+	err := row.Scan(&s.ID, &s.Name, &s.Query, &s.Notify, &s.LastCheckedAt, &s.CreatedAt)
+	return s, err
+}
+
+// ListSavedSearches handles GET /api/saved-searches
+func ListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	rows, err := database.Pool.Query(context.Background(),
+		`SELECT `+savedSearchColumns+` FROM saved_searches WHERE user_id = $1 ORDER BY created_at DESC`,
+		claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب عمليات البحث المحفوظة")
+		return
+	}
+	defer rows.Close()
+
+	searches := []models.SavedSearch{}
+	for rows.Next() {
+		if s, err := scanSavedSearch(rows); err == nil {
+			searches = append(searches, s)
+		}
+	}
+	writeJSON(w, http.StatusOK, searches)
+}
+
+// CreateSavedSearch handles POST /api/saved-searches
+// The providers matching at creation are the baseline: only providers and
+// prices that appear later trigger alerts.
+func CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	var req models.SavedSearchInput
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Name == nil || req.Query == nil {
+		writeError(w, http.StatusBadRequest, "الاسم ومعايير البحث مطلوبة")
+		return
+	}
+	if msg := validateSavedSearch(&req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	notify := req.Notify == nil || *req.Notify
+
+	ctx := context.Background()
+	var count int
+	database.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM saved_searches WHERE user_id = $1`, claims.UserID).Scan(&count)
+	if count >= maxSavedSearches {
+		writeError(w, http.StatusConflict, "بلغت الحد الأقصى لعمليات البحث المحفوظة")
+		return
+	}
+
+	matches, err := savedSearchMatches(ctx, *req.Query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في البحث")
+		return
+	}
+
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	s, err := scanSavedSearch(tx.QueryRow(ctx,
+		`INSERT INTO saved_searches (user_id, name, query, lang, notify, last_checked_at)
+		 VALUES ($1, $2, $3, $4, $5, NOW())
+		 RETURNING `+savedSearchColumns,
+		claims.UserID, *req.Name, *req.Query, middleware.GetLang(r), notify))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	if err := replaceSavedSearchMatches(ctx, tx, s.ID, matches); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	writeJSON(w, http.StatusCreated, s)
+}
+
+// UpdateSavedSearch handles PATCH /api/saved-searches/{id}
+// Changing the query resets the alert baseline.
+func UpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	var req models.SavedSearchInput
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := validateSavedSearch(&req); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	ctx := context.Background()
+	var matches []searchMatch
+	if req.Query != nil {
+		var err error
+		if matches, err = savedSearchMatches(ctx, *req.Query); err != nil {
+			writeError(w, http.StatusInternalServerError, "خطأ في البحث")
+			return
+		}
+	}
+
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	s, err := scanSavedSearch(tx.QueryRow(ctx,
+		`UPDATE saved_searches SET
+			name = COALESCE($1, name),
+			query = COALESCE($2, query),
+			notify = COALESCE($3, notify),
+			last_checked_at = CASE WHEN $2::TEXT IS NULL THEN last_checked_at ELSE NOW() END
+		 WHERE id = $4 AND user_id = $5
+		 RETURNING `+savedSearchColumns,
+		req.Name, req.Query, req.Notify, chi.URLParam(r, "id"), claims.UserID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "البحث المحفوظ غير موجود")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	if req.Query != nil {
+		if err := replaceSavedSearchMatches(ctx, tx, s.ID, matches); err != nil {
+			writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// DeleteSavedSearch handles DELETE /api/saved-searches/{id}
+func DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	tag, err := database.Pool.Exec(context.Background(),
+		`DELETE FROM saved_searches WHERE id = $1 AND user_id = $2`,
+		chi.URLParam(r, "id"), claims.UserID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ البيانات")
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		writeError(w, http.StatusNotFound, "البحث المحفوظ غير موجود")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": localize(w, "تم حذف البحث المحفوظ")})
+}
+
+// validateSavedSearch trims the input and normalizes the query in place,
+// and returns an error message, or "".
+func validateSavedSearch(in *models.SavedSearchInput) string {
+	if in.Name != nil {
+		*in.Name = strings.TrimSpace(*in.Name)
+		if *in.Name == "" || utf8.RuneCountInString(*in.Name) > 100 {
+			return "اسم البحث مطلوب ولا يتجاوز 100 حرف"
+		}
+	}
+	if in.Query == nil {
+		return ""
+	}
+	values, err := url.ParseQuery(strings.TrimPrefix(strings.TrimSpace(*in.Query), "?"))
+	if err != nil {
+		return "معايير البحث غير صالحة"
+	}
+	for _, k := range savedSearchIgnored {
+		values.Del(k)
+	}
+	for k, vs := range values {
+		if len(strings.Join(vs, "")) == 0 {
+			values.Del(k)
+		}
+	}
+	if len(values) == 0 {
+		return "معايير البحث غير صالحة"
+	}
+	q, err := parseSearchQuery(values)
+	if err != nil {
+		return "سنوات الخبرة غير صالحة"
+	}
+	for _, p := range q.Prices {
+		if _, err := parsePriceRange(p); err != nil {
+			return "نطاق السعر غير صالح"
+		}
+	}
+	*in.Query = values.Encode()
+	return ""
+}
+
+// searchMatch is a provider matching a saved search with its lowest price
+// among the matching services (0 when none is priced).
+type searchMatch struct {
+	ProviderID string
+	Name       string
+	Price      int
+}
+
+// savedSearchMatches runs a saved query through the public search.
+func savedSearchMatches(ctx context.Context, query string) ([]searchMatch, error) {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	q, err := parseSearchQuery(values)
+	if err != nil {
+		return nil, err
+	}
+	f, err := newSearchFilter(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	providers, err := searchProviders(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]searchMatch, 0, len(providers))
+	for _, p := range providers {
+		m := searchMatch{ProviderID: p.ID, Name: p.Name}
+		for _, s := range p.Services {
+			if s.Price > 0 && (m.Price == 0 || s.Price < m.Price) {
+				m.Price = s.Price
+			}
+		}
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
+
+// replaceSavedSearchMatches makes matches the new alert baseline.
+func replaceSavedSearchMatches(ctx context.Context, tx pgx.Tx, savedSearchID string, matches []searchMatch) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM saved_search_matches WHERE saved_search_id = $1`, savedSearchID); err != nil {
+		return err
+	}
+	for _, m := range matches {
+		var price *int
+		if m.Price > 0 {
+			price = &m.Price
+		}
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO saved_search_matches (saved_search_id, provider_id, min_price) VALUES ($1, $2, $3)`,
+			savedSearchID, m.ProviderID, price); err != nil {
+			return err
+		}
+	}
+	return nil
+}
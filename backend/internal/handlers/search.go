@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/models"
+)
+
+// Facet names, also used to skip a facet's own filter when counting it.
+const (
+	facetWilaya    = "wilaya"
+	facetType      = "type"
+	facetCategory  = "category"
+	facetSpecialty = "specialty"
+	facetEquipment = "equipment_type"
+	facetOrigin    = "equipment_origin"
+	facetPrice     = "price"
+)
+
+// priceBuckets are the ranges reported by the price facet, in DZD.
+var priceBuckets = []priceRange{
+	{0, 1000}, {1000, 3000}, {3000, 5000}, {5000, 10000}, {10000, -1},
+}
+
+// priceRange is a half-open [Min, Max) range; Max < 0 means no upper bound.
+type priceRange struct {
+	Min, Max int
+}
+
+func (p priceRange) String() string {
+	if p.Max < 0 {
+		return strconv.Itoa(p.Min) + "-"
+	}
+	return strconv.Itoa(p.Min) + "-" + strconv.Itoa(p.Max)
+}
+
+var (
+	errInvalidPriceRange = errors.New("invalid price range")
+	errInvalidExperience = errors.New("invalid min_experience")
+)
+
+// parsePriceRange parses "min-max" or "min-".
+func parsePriceRange(s string) (priceRange, error) {
+	lo, hi, ok := strings.Cut(s, "-")
+	if !ok {
+		return priceRange{}, errInvalidPriceRange
+	}
+	from, err := strconv.Atoi(lo)
+	if err != nil || from < 0 {
+		return priceRange{}, errInvalidPriceRange
+	}
+	if hi == "" {
+		return priceRange{from, -1}, nil
+	}
+	to, err := strconv.Atoi(hi)
+	if err != nil || to <= from {
+		return priceRange{}, errInvalidPriceRange
+	}
+	return priceRange{from, to}, nil
+}
+
+// parseSearchQuery reads the SearchProviders filters from a query string.
+// Every filter except service, service_id and min_experience accepts several values.
+func parseSearchQuery(v url.Values) (models.SearchQuery, error) {
+	q := models.SearchQuery{
+		Wilayas:        multiValue(v, "wilaya"),
+		Communes:       multiValue(v, "commune"),
+		Types:          multiValue(v, "type"),
+		Service:        v.Get("service"),
+		ServiceID:      v.Get("service_id"),
+		Categories:     multiValue(v, "category"),
+		Specialties:    multiValue(v, "specialty"),
+		EquipmentTypes: multiValue(v, "equipment_type"),
+		Origins:        multiValue(v, "origin"),
+		Prices:         multiValue(v, "price"),
+		SortBy:         v.Get("sort"),
+		Facets:         v.Get("facets") == "true",
+	}
+	if s := v.Get("min_experience"); s != "" {
+		years, err := strconv.Atoi(s)
+		if err != nil || years < 0 {
+			return q, errInvalidExperience
+		}
+		q.MinExperience = years
+	}
+	if q.SortBy == "" {
+		q.SortBy = "rating"
+	}
+	return q, nil
+}
+
+// newSearchFilter parses the price buckets and resolves service text and
+// synonyms to taxonomy IDs. Parse errors are errInvalidPriceRange.
+func newSearchFilter(ctx context.Context, q models.SearchQuery) (*searchFilter, error) {
+	f := &searchFilter{q: q}
+	for _, s := range q.Prices {
+		pr, err := parsePriceRange(s)
+		if err != nil {
+			return nil, err
+		}
+		f.prices = append(f.prices, pr)
+	}
+	var err error
+	f.serviceIDs, f.filterServices, err = resolveServiceIDs(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// multiParam reads a filter that may be repeated (?type=lab&type=clinic)
+// or comma-separated (?type=lab,clinic).
+func multiParam(r *http.Request, name string) []string {
+	return multiValue(r.URL.Query(), name)
+}
+
+func multiValue(query url.Values, name string) []string {
+	var values []string
+	for _, raw := range query[name] {
+		for _, v := range strings.Split(raw, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// sqlArgs accumulates positional query arguments.
+type sqlArgs []interface{}
+
+// add appends v and returns its placeholder.
+func (a *sqlArgs) add(v interface{}) string {
+	*a = append(*a, v)
+	return "$" + itoa(len(*a))
+}
+
+// searchFilter is a parsed search with its service criteria resolved.
+type searchFilter struct {
+	q              models.SearchQuery
+	serviceIDs     []string
+	filterServices bool
+	prices         []priceRange
+}
+
+// providerConds returns the provider-level conditions (alias p), leaving out
+// the filter of the facet named skip.
+func (f *searchFilter) providerConds(a *sqlArgs, skip string) []string {
+	conds := []string{"p.is_published"}
+	if len(f.q.Wilayas) > 0 && skip != facetWilaya {
+		conds = append(conds, `EXISTS(SELECT 1 FROM unnest(`+a.add(f.q.Wilayas)+`::text[]) v
+			WHERE p.wilaya_id = v OR p.wilaya LIKE '%' || v || '%')`)
+	}
+	if len(f.q.Communes) > 0 {
+		conds = append(conds, `p.commune_code = ANY(`+a.add(f.q.Communes)+`)`)
+	}
+	if len(f.q.Types) > 0 && skip != facetType {
+		conds = append(conds, `p.type = ANY(`+a.add(f.q.Types)+`)`)
+	}
+	return conds
+}
+
+// serviceConds returns the service-level conditions (aliases ps, ms, d and e).
+// A provider matches when one of its services meets all of them.
+func (f *searchFilter) serviceConds(a *sqlArgs, skip string) []string {
+	var conds []string
+	if f.filterServices {
+		conds = append(conds, `ps.service_id = ANY(`+a.add(f.serviceIDs)+`)`)
+	}
+	if len(f.q.Categories) > 0 && skip != facetCategory {
+		conds = append(conds, `ms.category_id IN (WITH RECURSIVE cats AS (
+				SELECT id FROM service_categories WHERE id = ANY(`+a.add(f.q.Categories)+`)
+				UNION ALL
+				SELECT c.id FROM service_categories c JOIN cats ON c.parent_id = cats.id
+			) SELECT id FROM cats)`)
+	}
+	if len(f.q.Specialties) > 0 && skip != facetSpecialty {
+		conds = append(conds, `d.specialty = ANY(`+a.add(f.q.Specialties)+`)`)
+	}
+	if f.q.MinExperience > 0 {
+		conds = append(conds, `d.experience_years >= `+a.add(f.q.MinExperience))
+	}
+	if len(f.q.EquipmentTypes) > 0 && skip != facetEquipment {
+		conds = append(conds, `e.type = ANY(`+a.add(f.q.EquipmentTypes)+`)`)
+	}
+	if len(f.q.Origins) > 0 && skip != facetOrigin {
+		conds = append(conds, `e.origin = ANY(`+a.add(f.q.Origins)+`)`)
+	}
+	if len(f.prices) > 0 && skip != facetPrice {
+		var ranges []string
+		for _, pr := range f.prices {
+			ranges = append(ranges, priceRangeCond(a, pr))
+		}
+		conds = append(conds, `(`+strings.Join(ranges, " OR ")+`)`)
+	}
+	return conds
+}
+
+func priceRangeCond(a *sqlArgs, pr priceRange) string {
+	cond := `ps.price >= ` + a.add(pr.Min)
+	if pr.Max >= 0 {
+		cond += ` AND ps.price < ` + a.add(pr.Max)
+	}
+	return `(` + cond + `)`
+}
+
+// searchFrom is the join shared by the result and facet queries.
+const searchFrom = `
+	FROM providers p
+	LEFT JOIN provider_services ps ON ps.provider_id = p.id
+	LEFT JOIN medical_services ms ON ms.id = ps.service_id
+	LEFT JOIN provider_doctors d ON d.id = ps.doctor_id
+	LEFT JOIN provider_equipment e ON e.id = ps.equipment_id`
+
+// where joins every condition except the filter of the facet named skip.
+func (f *searchFilter) where(a *sqlArgs, skip string) string {
+	conds := append(f.providerConds(a, skip), f.serviceConds(a, skip)...)
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// facetCounts counts matching providers per value of expr. label may be empty.
+func (f *searchFilter) facetCounts(ctx context.Context, skip, expr, label, join, order string) ([]models.FacetCount, error) {
+	var a sqlArgs
+	if label == "" {
+		label = "''"
+	}
+	query := `SELECT ` + expr + `, MIN(` + label + `), COUNT(DISTINCT p.id)` + searchFrom + join +
+		f.where(&a, skip) + ` AND ` + expr + ` IS NOT NULL AND ` + expr + ` <> ''
+		GROUP BY 1 ORDER BY ` + order
+
+	rows, err := database.Pool.Query(ctx, query, a...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []models.FacetCount{}
+	for rows.Next() {
+		var c models.FacetCount
+		if err := rows.Scan(&c.Value, &c.Label, &c.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// priceBucketExpr labels ps.price with its bucket from priceBuckets.
+func priceBucketExpr() string {
+	expr := "CASE"
+	for _, b := range priceBuckets {
+		expr += ` WHEN ps.price >= ` + itoa(b.Min)
+		if b.Max >= 0 {
+			expr += ` AND ps.price < ` + itoa(b.Max)
+		}
+		expr += ` THEN '` + b.String() + `'`
+	}
+	return expr + " END"
+}
+
+// facets computes every facet for the current filters, with wilaya and
+// category labels in the query language.
+func (f *searchFilter) facets(ctx context.Context) (*models.SearchFacets, error) {
+	const byCount = "3 DESC, 1"
+	var (
+		fc  models.SearchFacets
+		err error
+	)
+	wilayaLabel, categoryLabel := "p.wilaya", "sc.name_ar"
+	switch f.q.Lang {
+	case i18n.French:
+		wilayaLabel, categoryLabel = "COALESCE(wl.name, p.wilaya)", "COALESCE(NULLIF(sc.name_fr, ''), sc.name_ar)"
+	case i18n.English:
+		wilayaLabel, categoryLabel = "COALESCE(wl.name, p.wilaya)", "COALESCE(NULLIF(sc.name_en, ''), sc.name_ar)"
+	}
+
+	if fc.Wilaya, err = f.facetCounts(ctx, facetWilaya, "p.wilaya_id", wilayaLabel,
+		" LEFT JOIN wilayas wl ON wl.id = p.wilaya_id", byCount); err != nil {
+		return nil, err
+	}
+	if fc.Type, err = f.facetCounts(ctx, facetType, "p.type", "", "", byCount); err != nil {
+		return nil, err
+	}
+	if fc.Category, err = f.facetCounts(ctx, facetCategory, "ms.category_id", categoryLabel,
+		" LEFT JOIN service_categories sc ON sc.id = ms.category_id", byCount); err != nil {
+		return nil, err
+	}
+	if fc.Specialty, err = f.facetCounts(ctx, facetSpecialty, "d.specialty", "", "", byCount); err != nil {
+		return nil, err
+	}
+	if fc.EquipmentType, err = f.facetCounts(ctx, facetEquipment, "e.type", "", "", byCount); err != nil {
+		return nil, err
+	}
+	if fc.EquipmentOrigin, err = f.facetCounts(ctx, facetOrigin, "e.origin", "", "", byCount); err != nil {
+		return nil, err
+	}
+	if fc.Price, err = f.facetCounts(ctx, facetPrice, priceBucketExpr(), "", "", "MIN(ps.price)"); err != nil {
+		return nil, err
+	}
+	return &fc, nil
+}
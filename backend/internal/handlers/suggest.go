@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/anis7x/cliniclab/internal/cache"
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+)
+
+const (
+	defaultSuggestLimit = 5
+	maxSuggestLimit     = 10
+	maxSuggestQuery     = 50
+
+	// suggestDemandDays is how far back searches count towards popularity.
+	suggestDemandDays = 30
+)
+
+// The index holds every name and synonym in memory so a keystroke costs no
+// database round trip. It is rebuilt when it expires or listings change.
+var (
+	suggestCache   = cache.New(10 * time.Minute)
+	suggestBuildMu sync.Mutex
+)
+
+// suggestEntry is one suggestible item with all the terms it answers to.
+type suggestEntry struct {
+	typ        string
+	id         string
+	wilayaID   string
+	ar, fr, en string // label in each language
+	terms      []suggestTerm
+	popularity int
+}
+
+// suggestTerm is a name or synonym, with its folded form for matching.
+type suggestTerm struct {
+	text   string
+	folded string
+}
+
+type suggestIndex []suggestEntry
+
+// Suggest handles GET /api/suggest?q=&type=&limit=
+// Prefix-matches the start of any word of provider names, services and their
+// synonyms, cities and wilayas in ar/fr/en. limit (default 5) applies per type.
+func Suggest(w http.ResponseWriter, r *http.Request) {
+	result := models.Suggestions{
+		Providers: []models.Suggestion{},
+		Services:  []models.Suggestion{},
+		Cities:    []models.Suggestion{},
+		Wilayas:   []models.Suggestion{},
+	}
+	q := foldText(r.URL.Query().Get("q"))
+	if q == "" {
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+	if len([]rune(q)) > maxSuggestQuery {
+		q = string([]rune(q)[:maxSuggestQuery])
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		limit = defaultSuggestLimit
+	}
+	if limit > maxSuggestLimit {
+		limit = maxSuggestLimit
+	}
+	types := map[string]bool{}
+	for _, t := range multiParam(r, "type") {
+		types[t] = true
+	}
+
+	index, err := loadSuggestIndex(context.Background())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في البحث")
+		return
+	}
+
+	type match struct {
+		entry *suggestEntry
+		term  string
+		whole bool // the whole term starts with q, not just one of its words
+	}
+	byType := map[string][]match{}
+	for i := range index {
+		e := &index[i]
+		if len(types) > 0 && !types[e.typ] {
+			continue
+		}
+		best := -1
+		whole := false
+		for j, t := range e.terms {
+			if strings.HasPrefix(t.folded, q) {
+				best, whole = j, true
+				break
+			}
+			if best < 0 && hasWordPrefix(t.folded, q) {
+				best = j
+			}
+		}
+		if best >= 0 {
+			byType[e.typ] = append(byType[e.typ], match{e, e.terms[best].text, whole})
+		}
+	}
+
+	lang := middleware.GetLang(r)
+	for typ, matches := range byType {
+		sort.Slice(matches, func(i, j int) bool {
+			a, b := matches[i], matches[j]
+			if a.entry.popularity != b.entry.popularity {
+				return a.entry.popularity > b.entry.popularity
+			}
+			if a.whole != b.whole {
+				return a.whole
+			}
+			return len(a.term) < len(b.term)
+		})
+		if len(matches) > limit {
+			matches = matches[:limit]
+		}
+		list := make([]models.Suggestion, 0, len(matches))
+		for _, m := range matches {
+			list = append(list, models.Suggestion{
+				Type:       typ,
+				ID:         m.entry.id,
+				Text:       m.term,
+				Label:      i18n.Pick(lang, m.entry.ar, m.entry.fr, m.entry.en),
+				WilayaID:   m.entry.wilayaID,
+				Popularity: m.entry.popularity,
+			})
+		}
+		switch typ {
+		case models.SuggestProvider:
+			result.Providers = list
+		case models.SuggestService:
+			result.Services = list
+		case models.SuggestCity:
+			result.Cities = list
+		case models.SuggestWilaya:
+			result.Wilayas = list
+		}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// hasWordPrefix reports whether q starts at the beginning of any word of s,
+// also ignoring the Arabic article "ال" ("تحليل" finds "التحليل").
+func hasWordPrefix(s, q string) bool {
+	for i := 0; i < len(s); i++ {
+		if i > 0 && s[i-1] != ' ' {
+			continue
+		}
+		if strings.HasPrefix(s[i:], q) || strings.HasPrefix(s[i:], "ال"+q) {
+			return true
+		}
+	}
+	return false
+}
+
+// arabicFolds unifies letter variants that users type interchangeably.
+var arabicFolds = strings.NewReplacer(
+	"أ", "ا", "إ", "ا", "آ", "ا", "ٱ", "ا",
+	"ة", "ه", "ى", "ي", "ؤ", "و", "ئ", "ي",
+)
+
+// latinFolds drops the accents of French letters.
+var latinFolds = strings.NewReplacer(
+	"à", "a", "â", "a", "ä", "a", "é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "ô", "o", "ö", "o", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "œ", "oe", "æ", "ae",
+)
+
+// foldText lowercases s, strips Arabic diacritics and tatweel, folds letter
+// variants and accents, and collapses punctuation and spaces to one space.
+func foldText(s string) string {
+	s = latinFolds.Replace(arabicFolds.Replace(strings.ToLower(s)))
+	var b strings.Builder
+	space := true
+	for _, r := range s {
+		switch {
+		case r >= 0x064B && r <= 0x0652, r == 0x0670, r == 0x0640:
+			// harakat, superscript alef, tatweel
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			space = false
+		case !space:
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// loadSuggestIndex returns the cached index, building it on a miss.
+func loadSuggestIndex(ctx context.Context) (suggestIndex, error) {
+	if v, ok := suggestCache.Get("index"); ok {
+		return v.(suggestIndex), nil
+	}
+	suggestBuildMu.Lock()
+	defer suggestBuildMu.Unlock()
+	if v, ok := suggestCache.Get("index"); ok {
+		return v.(suggestIndex), nil
+	}
+	index, err := buildSuggestIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	suggestCache.Set("index", index)
+	return index, nil
+}
+
+// buildSuggestIndex reads every suggestible item. Popularity is reviews and
+// favorites for providers, providers offering it plus recent searches for
+// services and wilayas, and providers located there for cities (communes).
+func buildSuggestIndex(ctx context.Context) (suggestIndex, error) {
+	since := time.Now().AddDate(0, 0, -suggestDemandDays)
+	var index suggestIndex
+	add := func(e suggestEntry, terms ...string) {
+		seen := map[string]bool{}
+		for _, t := range terms {
+			if f := foldText(t); f != "" && !seen[f] {
+				seen[f] = true
+				e.terms = append(e.terms, suggestTerm{text: t, folded: f})
+			}
+		}
+		if len(e.terms) > 0 {
+			index = append(index, e)
+		}
+	}
+
+	rows, err := database.Pool.Query(ctx,
+		`SELECT p.id, p.name, COALESCE(p.name_en, ''), p.wilaya_id,
+		        p.reviews_count + (SELECT COUNT(*) FROM patient_favorites f WHERE f.provider_id = p.id)
+		 FROM providers p WHERE p.is_published`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		e := suggestEntry{typ: models.SuggestProvider}
+		if err := rows.Scan(&e.id, &e.ar, &e.en, &e.wilayaID, &e.popularity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		add(e, e.ar, e.en)
+	}
+	rows.Close()
+
+	rows, err = database.Pool.Query(ctx,
+		`SELECT ms.id, ms.name, COALESCE(ms.name_fr, ''), COALESCE(ms.name_en, ''),
+		        COALESCE((SELECT array_agg(s.term) FROM service_synonyms s WHERE s.service_id = ms.id), '{}'),
+		        (SELECT COUNT(DISTINCT ps.provider_id) FROM provider_services ps
+		           JOIN providers p ON p.id = ps.provider_id
+		          WHERE ps.service_id = ms.id AND p.is_published)
+		        + (SELECT COUNT(*) FROM search_events e WHERE ms.id = ANY(e.service_ids) AND e.searched_at >= $1)
+		 FROM medical_services ms`, since)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		e := suggestEntry{typ: models.SuggestService}
+		var synonyms []string
+		if err := rows.Scan(&e.id, &e.ar, &e.fr, &e.en, &synonyms, &e.popularity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		add(e, append([]string{e.ar, e.fr, e.en}, synonyms...)...)
+	}
+	rows.Close()
+
+	// Communes, plus free-text cities of providers not linked to one
+	rows, err = database.Pool.Query(ctx,
+		`SELECT c.code, c.ar_name, c.name, c.wilaya_id,
+		        (SELECT COUNT(*) FROM providers p WHERE p.commune_code = c.code AND p.is_published)
+		 FROM communes c
+		 UNION ALL
+		 SELECT '', MIN(city), '', wilaya_id, COUNT(*)
+		 FROM providers WHERE is_published AND commune_code IS NULL AND COALESCE(city, '') <> ''
+		 GROUP BY LOWER(city), wilaya_id`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		e := suggestEntry{typ: models.SuggestCity}
+		if err := rows.Scan(&e.id, &e.ar, &e.fr, &e.wilayaID, &e.popularity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		e.en = e.fr
+		add(e, e.ar, e.fr)
+	}
+	rows.Close()
+
+	rows, err = database.Pool.Query(ctx,
+		`SELECT w.id, w.ar_name, w.name,
+		        (SELECT COUNT(*) FROM providers p WHERE p.wilaya_id = w.id AND p.is_published)
+		        + (SELECT COUNT(*) FROM search_events e WHERE w.id = ANY(e.wilaya_ids) AND e.searched_at >= $1)
+		 FROM wilayas w`, since)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		e := suggestEntry{typ: models.SuggestWilaya}
+		if err := rows.Scan(&e.id, &e.ar, &e.fr, &e.popularity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		e.en = e.fr
+		add(e, e.ar, e.fr, e.id)
+	}
+	rows.Close()
+
+	return index, rows.Err()
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+)
+
+// GetServiceCategories handles GET /api/services/categories
+// Returns the taxonomy as a tree of root categories.
+func GetServiceCategories(w http.ResponseWriter, r *http.Request) {
+	lang := middleware.GetLang(r)
+	rows, err := database.Pool.Query(context.Background(),
+		`SELECT id, parent_id, name_ar, name_fr, name_en FROM service_categories
+		 ORDER BY sort_order, id`)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب التصنيفات")
+		return
+	}
+	defer rows.Close()
+
+	var all []models.ServiceCategory
+	for rows.Next() {
+		var c models.ServiceCategory
+		if err := rows.Scan(&c.ID, &c.ParentID, &c.NameAr, &c.NameFr, &c.NameEn); err != nil {
+			continue
+		}
+		c.Label = i18n.Pick(lang, c.NameAr, c.NameFr, c.NameEn)
+		all = append(all, c)
+	}
+
+	writeJSON(w, http.StatusOK, buildCategoryTree(all, nil))
+}
+
+func buildCategoryTree(all []models.ServiceCategory, parentID *string) []models.ServiceCategory {
+	nodes := []models.ServiceCategory{}
+	for _, c := range all {
+		if (parentID == nil && c.ParentID == nil) || (parentID != nil && c.ParentID != nil && *c.ParentID == *parentID) {
+			id := c.ID
+			c.Children = buildCategoryTree(all, &id)
+			if len(c.Children) == 0 {
+				c.Children = nil
+			}
+			nodes = append(nodes, c)
+		}
+	}
+	return nodes
+}
+
+// categoryTreeCTE expands $N into the category and all its descendants as "cats".
+func categoryTreeCTE(argIdx int) string {
+	return `WITH RECURSIVE cats AS (
+		SELECT id FROM service_categories WHERE id = $` + itoa(argIdx) + `
+		UNION ALL
+		SELECT c.id FROM service_categories c JOIN cats ON c.parent_id = cats.id
+	)`
+}
+
+// resolveServiceIDs maps the service part of a search (free text or exact
+// ID) to taxonomy IDs. Free text matches names in every language and
+// synonyms. ok is false when the query has no service criteria at all.
+func resolveServiceIDs(ctx context.Context, q models.SearchQuery) (ids []string, ok bool, err error) {
+	if q.Service == "" && q.ServiceID == "" {
+		return nil, false, nil
+	}
+
+	rows, err := database.Pool.Query(ctx,
+		`SELECT ms.id FROM medical_services ms
+		 WHERE ($1 = '' OR ms.id = $1
+		        OR ms.name ILIKE '%' || $1 || '%'
+		        OR COALESCE(ms.name_fr, '') ILIKE '%' || $1 || '%'
+		        OR COALESCE(ms.name_en, '') ILIKE '%' || $1 || '%'
+		        OR EXISTS(SELECT 1 FROM service_synonyms s
+		                  WHERE s.service_id = ms.id AND s.term ILIKE '%' || $1 || '%'))
+		   AND ($2 = '' OR ms.id = $2)`,
+		q.Service, q.ServiceID)
+	if err != nil {
+		return nil, true, err
+	}
+	defer rows.Close()
+
+	ids = []string{}
+	for rows.Next() {
+		var id string
+		if rows.Scan(&id) == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, true, rows.Err()
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/i18n"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+// timelineSource reads the events of one type for patient $1 of org $2, as
+// (type, id, at, status, summary_ar, summary_fr, actor_ar, actor_fr).
+type timelineSource struct {
+	eventType string
+	title     [3]string // ar, fr, en
+	sql       string
+}
+
+// timelineSources lists the event types of the timeline. Types are inlined in
+// the timeline query, so only these may reach it.
+var timelineSources = []timelineSource{
+	{models.TimelineVisit, [3]string{"استشارة طبية", "Consultation", "Consultation"},
+		`SELECT '` + models.TimelineVisit + `', v.id, COALESCE(v.visit_date, v.created_at), NULL,
+		        concat_ws(' - ', NULLIF(v.diagnosis_code, ''), COALESCE(NULLIF(v.diagnosis, ''), c.label_ar, v.chief_complaint)),
+		        concat_ws(' - ', NULLIF(v.diagnosis_code, ''), COALESCE(NULLIF(v.diagnosis, ''), c.label_fr, v.chief_complaint)),
+		        s.full_name_ar, s.full_name_fr
+		 FROM medical_visits v
+		 LEFT JOIN icd10_codes c ON c.code = v.diagnosis_code
+		 LEFT JOIN staff s ON s.id = v.doctor_id
+		 WHERE v.patient_id = $1 AND v.org_id = $2`},
+	// A lab order's status is that of its least advanced test
+	{models.TimelineLabOrder, [3]string{"طلب تحاليل", "Bilan biologique", "Lab order"},
+		`SELECT '` + models.TimelineLabOrder + `', lo.id, COALESCE(lo.ordered_at, lo.created_at),
+		        (SELECT MIN(i.status)::text FROM lab_order_items i WHERE i.lab_order_id = lo.id),
+		        concat_ws(': ', lo.order_number, (SELECT string_agg(t.name_ar, ', ' ORDER BY t.code)
+		            FROM lab_order_items i JOIN lab_tests_catalog t ON t.id = i.test_id WHERE i.lab_order_id = lo.id)),
+		        concat_ws(': ', lo.order_number, (SELECT string_agg(t.name_fr, ', ' ORDER BY t.code)
+		            FROM lab_order_items i JOIN lab_tests_catalog t ON t.id = i.test_id WHERE i.lab_order_id = lo.id)),
+		        s.full_name_ar, s.full_name_fr
+		 FROM lab_orders lo
+		 LEFT JOIN staff s ON s.id = lo.ordering_doctor_id
+		 WHERE lo.patient_id = $1 AND lo.org_id = $2`},
+	{models.TimelineAdmission, [3]string{"استشفاء", "Hospitalisation", "Admission"},
+		`SELECT '` + models.TimelineAdmission + `', a.id, COALESCE(a.admitted_at, a.created_at), a.status::text,
+		        COALESCE(NULLIF(a.diagnosis, ''), a.admission_reason), COALESCE(NULLIF(a.diagnosis, ''), a.admission_reason),
+		        s.full_name_ar, s.full_name_fr
+		 FROM admissions a
+		 LEFT JOIN staff s ON s.id = a.admitting_doctor_id
+		 WHERE a.patient_id = $1 AND a.org_id = $2`},
+	{models.TimelineSurgery, [3]string{"عملية جراحية", "Intervention chirurgicale", "Surgery"},
+		`SELECT '` + models.TimelineSurgery + `', su.id, COALESCE(su.started_at, su.scheduled_at, su.created_at), su.status::text,
+		        su.procedure_name_ar, su.procedure_name_fr,
+		        s.full_name_ar, s.full_name_fr
+		 FROM surgeries su
+		 LEFT JOIN staff s ON s.id = su.surgeon_id
+		 WHERE su.patient_id = $1 AND su.org_id = $2`},
+	// Prescriptions only have a date; one written the day it was saved takes
+	// its creation time, so that it follows the visit it was written at.
+	{models.TimelinePrescription, [3]string{"وصفة طبية", "Ordonnance", "Prescription"},
+		`SELECT '` + models.TimelinePrescription + `', p.id,
+		        CASE WHEN p.prescription_date IS NULL OR p.prescription_date = p.created_at::date
+		             THEN p.created_at ELSE p.prescription_date::timestamptz END, NULL,
+		        (SELECT string_agg(i.medication_name, ', ' ORDER BY i.position, i.medication_name)
+		         FROM prescription_items i WHERE i.prescription_id = p.id), NULL,
+		        s.full_name_ar, s.full_name_fr
+		 FROM prescriptions p
+		 LEFT JOIN staff s ON s.id = p.doctor_id
+		 WHERE p.patient_id = $1 AND p.org_id = $2`},
+	{models.TimelineAppointment, [3]string{"موعد", "Rendez-vous", "Appointment"},
+		`SELECT '` + models.TimelineAppointment + `', ap.id, ap.scheduled_at, ap.status::text, ap.reason, NULL,
+		        s.full_name_ar, s.full_name_fr
+		 FROM appointments ap
+		 LEFT JOIN staff s ON s.id = ap.doctor_id
+		 WHERE ap.patient_id = $1 AND ap.org_id = $2`},
+	{models.TimelineInvoice, [3]string{"فاتورة", "Facture", "Invoice"},
+		`SELECT '` + models.TimelineInvoice + `', iv.id, COALESCE(iv.issued_at, iv.created_at), iv.status::text,
+		        iv.invoice_number || ' - ' || to_char(iv.total, 'FM9999999990.00') || ' دج'
+		            || CASE WHEN iv.remaining > 0 THEN ' (المتبقي ' || to_char(iv.remaining, 'FM9999999990.00') || ')' ELSE '' END,
+		        iv.invoice_number || ' - ' || to_char(iv.total, 'FM9999999990.00') || ' DA'
+		            || CASE WHEN iv.remaining > 0 THEN ' (reste ' || to_char(iv.remaining, 'FM9999999990.00') || ')' ELSE '' END,
+		        NULL, NULL
+		 FROM invoices iv
+		 WHERE iv.patient_id = $1 AND iv.org_id = $2`},
+}
+
+// PatientTimeline handles GET /api/org/patients/{id}/timeline?types=&from=&to=&page=&limit=
+// Merges the patient's visits, lab orders, admissions, surgeries,
+// prescriptions, appointments and invoices, newest first. types is a comma
+// separated list of event types (all when empty); from and to are dates
+// (YYYY-MM-DD) or RFC 3339 times, to is exclusive.
+func PatientTimeline(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	patient, err := loadERPPatient(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+	query := r.URL.Query()
+	lang := middleware.GetLang(r)
+	page, limit, offset := parsePagination(r)
+
+	selected := map[string]bool{}
+	for _, t := range strings.Split(query.Get("types"), ",") {
+		if t = strings.ToUpper(strings.TrimSpace(t)); t != "" {
+			selected[t] = true
+		}
+	}
+	titles := map[string]string{}
+	var branches []string
+	for _, src := range timelineSources {
+		titles[src.eventType] = i18n.Pick(lang, src.title[0], src.title[1], src.title[2])
+		if len(selected) == 0 || selected[src.eventType] {
+			branches = append(branches, src.sql)
+			delete(selected, src.eventType)
+		}
+	}
+	if len(selected) > 0 {
+		writeError(w, http.StatusBadRequest, "نوع الحدث غير معروف")
+		return
+	}
+
+	args := sqlArgs{patient.ID, orgID}
+	where := ` WHERE at IS NOT NULL`
+	for _, bound := range []struct{ param, op string }{{"from", ">="}, {"to", "<"}} {
+		raw := query.Get(bound.param)
+		if raw == "" {
+			continue
+		}
+		t, err := parseTrendTime(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "صيغة التاريخ غير صالحة")
+			return
+		}
+		where += ` AND at ` + bound.op + ` ` + args.add(t)
+	}
+	events := `WITH events (type, id, at, status, summary_ar, summary_fr, actor_ar, actor_fr) AS (
+		` + strings.Join(branches, "\n\t\tUNION ALL\n\t\t") + `
+	)`
+
+	var total int
+	if err := database.Pool.QueryRow(ctx, events+` SELECT COUNT(*) FROM events`+where, args...).Scan(&total); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+	rows, err := database.Pool.Query(ctx,
+		events+` SELECT type, id::text, at, COALESCE(status, ''), COALESCE(summary_ar, ''), COALESCE(summary_fr, ''),
+		        COALESCE(actor_ar, ''), COALESCE(actor_fr, '')
+		 FROM events`+where+`
+		 ORDER BY at DESC, type, id LIMIT `+args.add(limit)+` OFFSET `+args.add(offset), args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الملف الطبي")
+		return
+	}
+	defer rows.Close()
+
+	timeline := []models.TimelineEvent{}
+	for rows.Next() {
+		var e models.TimelineEvent
+		var summaryAr, summaryFr, actorAr, actorFr string
+		if err := rows.Scan(&e.Type, &e.ID, &e.At, &e.Status, &summaryAr, &summaryFr, &actorAr, &actorFr); err == nil {
+			e.Title = titles[e.Type]
+			e.Summary = i18n.Pick(lang, summaryAr, summaryFr, summaryFr)
+			e.Actor = i18n.Pick(lang, actorAr, actorFr, actorFr)
+			timeline = append(timeline, e)
+		}
+	}
+	writeJSON(w, http.StatusOK, models.Page{Items: timeline, Page: page, Limit: limit, Total: total})
+}
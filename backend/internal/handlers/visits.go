@@ -0,0 +1,512 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anis7x/cliniclab/internal/database"
+	"github.com/anis7x/cliniclab/internal/middleware"
+	"github.com/anis7x/cliniclab/internal/models"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// trendVitals are the vitals a trend can be asked for. Keys are inlined in the
+// trend query, so only these may reach it.
+var trendVitals = map[string]bool{
+	models.VitalSystolicBP: true, models.VitalDiastolicBP: true, models.VitalHeartRate: true,
+	models.VitalTemperature: true, models.VitalSpO2: true, models.VitalWeight: true,
+	models.VitalHeight: true, models.VitalGlycemia: true, models.VitalBMI: true,
+}
+
+// visitSelect reads a visit in the column order of scanVisit.
+const visitSelect = `SELECT v.id, v.patient_id, v.doctor_id::text, COALESCE(s.full_name_ar, ''), v.department_id::text,
+	COALESCE(v.visit_date, v.created_at), COALESCE(v.chief_complaint, ''), COALESCE(v.diagnosis, ''),
+	COALESCE(v.diagnosis_code, ''),
+	COALESCE((SELECT json_agg(json_build_object('code', d.code, 'label_fr', COALESCE(c.label_fr, ''),
+		'label_ar', COALESCE(c.label_ar, ''), 'is_primary', d.is_primary) ORDER BY d.position)
+		FROM visit_diagnoses d JOIN icd10_codes c ON c.code = d.code WHERE d.visit_id = v.id), '[]'),
+	COALESCE(v.treatment_plan, ''), COALESCE(v.notes, ''),
+	v.vitals, v.created_at, v.updated_at
+	FROM medical_visits v
+	LEFT JOIN staff s ON s.id = v.doctor_id`
+
+func scanVisit(row pgx.Row) (models.MedicalVisit, error) {
+	var v models.MedicalVisit
+	var diagnoses, vitals []byte
+	err := row.Scan(&v.ID, &v.PatientID, &v.DoctorID, &v.DoctorName, &v.DepartmentID,
+		&v.VisitDate, &v.ChiefComplaint, &v.Diagnosis,
+		&v.DiagnosisCode, &diagnoses, &v.TreatmentPlan, &v.Notes,
+		&vitals, &v.CreatedAt, &v.UpdatedAt)
+	v.Diagnoses = []models.VisitDiagnosis{}
+	json.Unmarshal(diagnoses, &v.Diagnoses)
+	v.Vitals = decodeVitals(vitals)
+	return v, err
+}
+
+// decodeVitals reads a vitals column. Visits recorded before the typed schema
+// may hold other keys or strings; those are skipped, the rest is kept.
+func decodeVitals(raw []byte) *models.Vitals {
+	if len(raw) == 0 {
+		return nil
+	}
+	var v models.Vitals
+	json.Unmarshal(raw, &v)
+	if v == (models.Vitals{}) {
+		return nil
+	}
+	return &v
+}
+
+// loadVisit reads a visit of the organization; other tenants' visits are
+// reported as missing.
+func loadVisit(ctx context.Context, orgID, id string) (models.MedicalVisit, error) {
+	return scanVisit(database.Pool.QueryRow(ctx,
+		visitSelect+` WHERE v.id::text = $1 AND v.org_id = $2`, id, orgID))
+}
+
+// ListVisits handles GET /api/org/patients/{id}/visits?page=&limit=
+func ListVisits(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	patient, err := loadERPPatient(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+	page, limit, offset := parsePagination(r)
+
+	var total int
+	if err := database.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM medical_visits WHERE patient_id = $1 AND org_id = $2`,
+		patient.ID, orgID).Scan(&total); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الزيارات")
+		return
+	}
+	rows, err := database.Pool.Query(ctx,
+		visitSelect+` WHERE v.patient_id = $1 AND v.org_id = $2
+		 ORDER BY COALESCE(v.visit_date, v.created_at) DESC, v.id LIMIT $3 OFFSET $4`,
+		patient.ID, orgID, limit, offset)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب الزيارات")
+		return
+	}
+	defer rows.Close()
+
+	visits := []models.MedicalVisit{}
+	for rows.Next() {
+		if v, err := scanVisit(rows); err == nil {
+			visits = append(visits, v)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, models.Page{Items: visits, Page: page, Limit: limit, Total: total})
+}
+
+// GetVisit handles GET /api/org/visits/{id}
+func GetVisit(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	v, err := loadVisit(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "الزيارة غير موجودة")
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// CreateVisit handles POST /api/org/patients/{id}/visits
+// The doctor defaults to the caller's staff record and the date to now.
+func CreateVisit(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	patient, err := loadERPPatient(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+
+	var in models.MedicalVisitInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := validateVisit(ctx, orgID, &in); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الزيارة")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var id string
+	err = tx.QueryRow(ctx,
+		`INSERT INTO medical_visits (org_id, patient_id, doctor_id, department_id, visit_date,
+			chief_complaint, diagnosis, diagnosis_code, treatment_plan, notes, vitals)
+		 VALUES ($1, $2,
+			COALESCE(NULLIF($3, '')::uuid, (SELECT id FROM staff WHERE org_id = $1 AND user_id = $4 LIMIT 1)),
+			NULLIF($5, '')::uuid, COALESCE($6, NOW()),
+			NULLIF($7, ''), NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), NULLIF($11, ''), $12)
+		 RETURNING id`,
+		orgID, patient.ID, in.DoctorID, middleware.GetClaims(r).UserID, in.DepartmentID, in.VisitDate,
+		in.ChiefComplaint, in.Diagnosis, in.DiagnosisCode, in.TreatmentPlan, in.Notes, in.Vitals).Scan(&id)
+	if err != nil || writeVisitDiagnoses(ctx, tx, id, in.DiagnosisCodes) != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الزيارة")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الزيارة")
+		return
+	}
+
+	v, err := loadVisit(ctx, orgID, id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الزيارة")
+		return
+	}
+	writeJSON(w, http.StatusCreated, v)
+}
+
+// AmendVisit handles PATCH /api/org/visits/{id}
+// Only the fields present in the body change; "" clears an optional field and
+// vitals replace the recorded set. The previous version is kept in audit_log.
+func AmendVisit(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	current, err := loadVisit(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "الزيارة غير موجودة")
+		return
+	}
+
+	var in models.MedicalVisitInput
+	if err := decodeJSON(r, &in); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if msg := validateVisit(ctx, orgID, &in); msg != "" {
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+
+	args := sqlArgs{current.ID, orgID}
+	var set []string
+	for _, c := range []struct {
+		name  string
+		cast  string
+		value *string
+	}{
+		{"doctor_id", "::uuid", in.DoctorID},
+		{"department_id", "::uuid", in.DepartmentID},
+		{"chief_complaint", "", in.ChiefComplaint},
+		{"diagnosis", "", in.Diagnosis},
+		{"diagnosis_code", "", in.DiagnosisCode},
+		{"treatment_plan", "", in.TreatmentPlan},
+		{"notes", "", in.Notes},
+	} {
+		if c.value != nil {
+			set = append(set, c.name+" = NULLIF("+args.add(*c.value)+", '')"+c.cast)
+		}
+	}
+	if in.VisitDate != nil {
+		set = append(set, "visit_date = "+args.add(*in.VisitDate))
+	}
+	if in.Vitals != nil {
+		set = append(set, "vitals = "+args.add(in.Vitals))
+	}
+	if len(set) == 0 {
+		writeJSON(w, http.StatusOK, current)
+		return
+	}
+
+	tx, err := database.Pool.Begin(ctx)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الزيارة")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx,
+		`UPDATE medical_visits SET `+strings.Join(set, ", ")+`, updated_at = NOW()
+		 WHERE id = $1 AND org_id = $2`, args...)
+	if err != nil || writeVisitDiagnoses(ctx, tx, current.ID, in.DiagnosisCodes) != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الزيارة")
+		return
+	}
+	amended, err := scanVisit(tx.QueryRow(ctx, visitSelect+` WHERE v.id = $1`, current.ID))
+	if err != nil || recordAudit(ctx, tx, r, orgID, "visit.amend", "medical_visit", current.ID, current, amended) != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الزيارة")
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في حفظ الزيارة")
+		return
+	}
+	writeJSON(w, http.StatusOK, amended)
+}
+
+// validateVisit trims the input and checks every field it sets, deriving BMI
+// from the vitals. Returns an error message, or "".
+func validateVisit(ctx context.Context, orgID string, in *models.MedicalVisitInput) string {
+	for _, f := range []*string{in.DoctorID, in.DepartmentID, in.ChiefComplaint, in.Diagnosis,
+		in.DiagnosisCode, in.TreatmentPlan, in.Notes} {
+		if f != nil {
+			*f = strings.TrimSpace(*f)
+		}
+	}
+
+	if in.DoctorID != nil && *in.DoctorID != "" {
+		var exists bool
+		database.Pool.QueryRow(ctx,
+			`SELECT EXISTS(SELECT 1 FROM staff WHERE id::text = $1 AND org_id = $2)`,
+			*in.DoctorID, orgID).Scan(&exists)
+		if !exists {
+			return "الطبيب غير موجود"
+		}
+	}
+	if in.DepartmentID != nil && *in.DepartmentID != "" {
+		var exists bool
+		database.Pool.QueryRow(ctx,
+			`SELECT EXISTS(SELECT 1 FROM departments WHERE id::text = $1 AND org_id = $2)`,
+			*in.DepartmentID, orgID).Scan(&exists)
+		if !exists {
+			return "القسم غير موجود"
+		}
+	}
+	if in.VisitDate != nil && in.VisitDate.After(time.Now().Add(time.Hour)) {
+		return "تاريخ الزيارة لا يمكن أن يكون في المستقبل"
+	}
+	if in.DiagnosisCodes == nil && in.DiagnosisCode != nil {
+		codes := []string{}
+		if *in.DiagnosisCode != "" {
+			codes = append(codes, *in.DiagnosisCode)
+		}
+		in.DiagnosisCodes = &codes
+	}
+	if in.DiagnosisCodes != nil {
+		codes, msg := checkDiagnosisCodes(ctx, *in.DiagnosisCodes)
+		if msg != "" {
+			return msg
+		}
+		primary := ""
+		if len(codes) > 0 {
+			primary = codes[0]
+		}
+		in.DiagnosisCodes, in.DiagnosisCode = &codes, &primary
+	}
+	if in.Vitals != nil {
+		return validateVitals(in.Vitals)
+	}
+	return ""
+}
+
+// checkDiagnosisCodes normalizes codes and checks that each is an active,
+// billable catalog entry given once. Returns the normalized codes or an error
+// message.
+func checkDiagnosisCodes(ctx context.Context, codes []string) ([]string, string) {
+	if len(codes) > maxVisitDiagnoses {
+		return nil, "عدد التشخيصات يتجاوز الحد المسموح"
+	}
+	normalized := make([]string, len(codes))
+	for i, raw := range codes {
+		code, ok := normalizeICD10(raw)
+		if !ok {
+			return nil, "رمز التشخيص غير موجود في التصنيف"
+		}
+		if containsString(normalized[:i], code) {
+			return nil, "رمز التشخيص مكرر"
+		}
+		normalized[i] = code
+	}
+	if len(normalized) == 0 {
+		return normalized, ""
+	}
+
+	var found, billable int
+	err := database.Pool.QueryRow(ctx,
+		`SELECT COUNT(*), COUNT(*) FILTER (WHERE is_billable) FROM icd10_codes
+		 WHERE code = ANY($1) AND is_active`, normalized).Scan(&found, &billable)
+	if err != nil {
+		return nil, "خطأ في الخادم"
+	}
+	if found < len(normalized) {
+		return nil, "رمز التشخيص غير موجود في التصنيف"
+	}
+	if billable < len(normalized) {
+		return nil, "رمز التشخيص عام، اختر رمزاً أدق"
+	}
+	return normalized, ""
+}
+
+// writeVisitDiagnoses replaces the coded diagnoses of a visit; nil codes
+// leave them unchanged.
+func writeVisitDiagnoses(ctx context.Context, tx pgx.Tx, visitID string, codes *[]string) error {
+	if codes == nil {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM visit_diagnoses WHERE visit_id = $1`, visitID); err != nil {
+		return err
+	}
+	for i, code := range *codes {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO visit_diagnoses (visit_id, code, is_primary, position) VALUES ($1, $2, $3, $4)`,
+			visitID, code, i == 0, i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateVitals checks each measurement against physiologically plausible
+// bounds and sets BMI when weight and height are both given.
+func validateVitals(v *models.Vitals) string {
+	intIn := func(p *int, min, max int) bool { return p == nil || (*p >= min && *p <= max) }
+	floatIn := func(p *float64, min, max float64) bool { return p == nil || (*p >= min && *p <= max) }
+
+	if (v.SystolicBP == nil) != (v.DiastolicBP == nil) {
+		return "ضغط الدم يتطلب القيمتين الانقباضية والانبساطية"
+	}
+	if !intIn(v.SystolicBP, 50, 300) || !intIn(v.DiastolicBP, 20, 200) ||
+		(v.SystolicBP != nil && *v.DiastolicBP >= *v.SystolicBP) {
+		return "قيمة ضغط الدم غير صالحة"
+	}
+	if !intIn(v.HeartRate, 20, 300) {
+		return "قيمة نبض القلب غير صالحة"
+	}
+	if !floatIn(v.Temperature, 30, 45) {
+		return "قيمة درجة الحرارة غير صالحة"
+	}
+	if !intIn(v.SpO2, 50, 100) {
+		return "قيمة تشبع الأكسجين غير صالحة"
+	}
+	if !floatIn(v.WeightKg, 0.3, 400) {
+		return "قيمة الوزن غير صالحة"
+	}
+	if !floatIn(v.HeightCm, 20, 260) {
+		return "قيمة الطول غير صالحة"
+	}
+	if !floatIn(v.Glycemia, 0.2, 10) {
+		return "قيمة نسبة السكر في الدم غير صالحة"
+	}
+
+	v.BMI = nil
+	if v.WeightKg != nil && v.HeightCm != nil {
+		m := *v.HeightCm / 100
+		bmi := math.Round(*v.WeightKg/(m*m)*10) / 10
+		v.BMI = &bmi
+	}
+	return ""
+}
+
+// vitalValue is the SQL reading one vital from a vitals column as a number,
+// NULL when absent or not numeric. BMI falls back to weight and height for
+// entries that do not store it.
+func vitalValue(key string) string {
+	num := func(k string) string {
+		return `CASE WHEN jsonb_typeof(vitals->'` + k + `') = 'number' THEN (vitals->>'` + k + `')::float8 END`
+	}
+	if key != models.VitalBMI {
+		return num(key)
+	}
+	height := num(models.VitalHeight)
+	return `COALESCE(` + num(key) + `, CASE WHEN ` + height + ` > 0
+		THEN round((` + num(models.VitalWeight) + ` / power((` + height + `) / 100, 2))::numeric, 1)::float8 END)`
+}
+
+// VitalTrends handles GET /api/org/patients/{id}/vitals/trends?vital=&from=&to=
+// Returns the series of one vital across the patient's visits and the nursing
+// notes of their admissions, oldest first. from and to are dates (YYYY-MM-DD)
+// or RFC 3339 times; to is exclusive.
+func VitalTrends(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	orgID, err := activeOrgID(ctx, middleware.GetClaims(r).UserID)
+	if err != nil {
+		writeError(w, http.StatusForbidden, "لا توجد مؤسسة نشطة")
+		return
+	}
+	patient, err := loadERPPatient(ctx, orgID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "المريض غير موجود")
+		return
+	}
+	query := r.URL.Query()
+	vital := query.Get("vital")
+	if !trendVitals[vital] {
+		writeError(w, http.StatusBadRequest, "المؤشر الحيوي غير معروف")
+		return
+	}
+
+	args := sqlArgs{patient.ID, orgID}
+	where := ` WHERE value IS NOT NULL`
+	for _, bound := range []struct{ param, op string }{{"from", ">="}, {"to", "<"}} {
+		raw := query.Get(bound.param)
+		if raw == "" {
+			continue
+		}
+		t, err := parseTrendTime(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "صيغة التاريخ غير صالحة")
+			return
+		}
+		where += ` AND at ` + bound.op + ` ` + args.add(t)
+	}
+
+	sql := `WITH entries AS (
+		SELECT COALESCE(visit_date, created_at) AS at, vitals, 'visit' AS source, id AS source_id
+		FROM medical_visits WHERE patient_id = $1 AND org_id = $2 AND vitals IS NOT NULL
+		UNION ALL
+		SELECT n.note_time, n.vitals, 'nursing_note', n.id
+		FROM nursing_notes n JOIN admissions a ON a.id = n.admission_id
+		WHERE a.patient_id = $1 AND a.org_id = $2 AND n.vitals IS NOT NULL
+	)
+	SELECT at, value, source, source_id::text FROM (
+		SELECT at, source, source_id, ` + vitalValue(vital) + ` AS value FROM entries
+	) points` + where + ` ORDER BY at, source_id`
+	rows, err := database.Pool.Query(ctx, sql, args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "خطأ في جلب المؤشرات الحيوية")
+		return
+	}
+	defer rows.Close()
+
+	trend := models.VitalTrend{PatientID: patient.ID, Vital: vital, Points: []models.VitalPoint{}}
+	for rows.Next() {
+		var p models.VitalPoint
+		if err := rows.Scan(&p.At, &p.Value, &p.Source, &p.SourceID); err == nil {
+			trend.Points = append(trend.Points, p)
+		}
+	}
+	writeJSON(w, http.StatusOK, trend)
+}
+
+func parseTrendTime(raw string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
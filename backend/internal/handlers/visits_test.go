@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/anis7x/cliniclab/internal/models"
+)
+
+func TestValidateVitals(t *testing.T) {
+	i := func(v int) *int { return &v }
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name    string
+		in      models.Vitals
+		wantErr string
+		wantBMI *float64
+	}{
+		{"empty", models.Vitals{}, "", nil},
+		{"normal set", models.Vitals{SystolicBP: i(120), DiastolicBP: i(80), HeartRate: i(72), Temperature: f(37.2),
+			SpO2: i(98), WeightKg: f(70), HeightCm: f(175), Glycemia: f(0.95)}, "", f(22.9)},
+		{"bounds are inclusive", models.Vitals{SystolicBP: i(300), DiastolicBP: i(20), HeartRate: i(20), Temperature: f(45),
+			SpO2: i(100), WeightKg: f(0.3), Glycemia: f(10)}, "", nil},
+		{"systolic without diastolic", models.Vitals{SystolicBP: i(120)}, "ضغط الدم يتطلب القيمتين الانقباضية والانبساطية", nil},
+		{"diastolic without systolic", models.Vitals{DiastolicBP: i(80)}, "ضغط الدم يتطلب القيمتين الانقباضية والانبساطية", nil},
+		{"systolic too high", models.Vitals{SystolicBP: i(301), DiastolicBP: i(80)}, "قيمة ضغط الدم غير صالحة", nil},
+		{"diastolic too low", models.Vitals{SystolicBP: i(120), DiastolicBP: i(19)}, "قيمة ضغط الدم غير صالحة", nil},
+		{"diastolic equal to systolic", models.Vitals{SystolicBP: i(90), DiastolicBP: i(90)}, "قيمة ضغط الدم غير صالحة", nil},
+		{"inverted pressure", models.Vitals{SystolicBP: i(80), DiastolicBP: i(120)}, "قيمة ضغط الدم غير صالحة", nil},
+		{"heart rate", models.Vitals{HeartRate: i(0)}, "قيمة نبض القلب غير صالحة", nil},
+		{"temperature in Fahrenheit", models.Vitals{Temperature: f(98.6)}, "قيمة درجة الحرارة غير صالحة", nil},
+		{"spo2 above 100", models.Vitals{SpO2: i(101)}, "قيمة تشبع الأكسجين غير صالحة", nil},
+		{"weight in grams", models.Vitals{WeightKg: f(3500)}, "قيمة الوزن غير صالحة", nil},
+		{"negative weight", models.Vitals{WeightKg: f(-1)}, "قيمة الوزن غير صالحة", nil},
+		{"height in meters", models.Vitals{HeightCm: f(1.75)}, "قيمة الطول غير صالحة", nil},
+		{"glycemia in mg/dL", models.Vitals{Glycemia: f(95)}, "قيمة نسبة السكر في الدم غير صالحة", nil},
+		{"weight alone has no BMI", models.Vitals{WeightKg: f(70)}, "", nil},
+		{"given BMI is recomputed", models.Vitals{WeightKg: f(80), HeightCm: f(200), BMI: f(99)}, "", f(20)},
+		{"given BMI without height is dropped", models.Vitals{BMI: f(25)}, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := tt.in
+			if got := validateVitals(&v); got != tt.wantErr {
+				t.Fatalf("validateVitals() = %q, want %q", got, tt.wantErr)
+			}
+			if tt.wantErr != "" {
+				return
+			}
+			switch {
+			case tt.wantBMI == nil && v.BMI != nil:
+				t.Errorf("BMI = %v, want none", *v.BMI)
+			case tt.wantBMI != nil && (v.BMI == nil || *v.BMI != *tt.wantBMI):
+				t.Errorf("BMI = %v, want %v", v.BMI, *tt.wantBMI)
+			}
+		})
+	}
+}
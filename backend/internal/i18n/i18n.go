@@ -0,0 +1,97 @@
+package i18n
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported languages. Arabic is the source language of the message catalog.
+const (
+	Arabic  = "ar"
+	French  = "fr"
+	English = "en"
+	Default = Arabic
+)
+
+// Languages lists every supported language.
+var Languages = []string{Arabic, French, English}
+
+// Supported reports whether lang is one of Languages.
+func Supported(lang string) bool {
+	return lang == Arabic || lang == French || lang == English
+}
+
+// Negotiate picks the response language from ?lang= and then Accept-Language.
+// explicit is false when the request named no supported language, in which
+// case the caller may substitute a better default than Arabic.
+func Negotiate(r *http.Request) (lang string, explicit bool) {
+	if q := strings.ToLower(r.URL.Query().Get("lang")); Supported(q) {
+		return q, true
+	}
+	if lang := ParseAcceptLanguage(r.Header.Get("Accept-Language")); lang != "" {
+		return lang, true
+	}
+	return Default, false
+}
+
+// ParseAcceptLanguage returns the supported language with the highest
+// q-value in an Accept-Language header ("fr-DZ,fr;q=0.9,ar;q=0.8"), or "".
+func ParseAcceptLanguage(header string) string {
+	type choice struct {
+		lang string
+		q    float64
+	}
+	var choices []choice
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !Supported(base) {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			choices = append(choices, choice{base, q})
+		}
+	}
+	if len(choices) == 0 {
+		return ""
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	return choices[0].lang
+}
+
+// T translates a catalog message. Messages are keyed by their source text;
+// unknown messages and missing translations come back unchanged.
+func T(lang, msg string) string {
+	if t, ok := catalog[msg][lang]; ok {
+		return t
+	}
+	return msg
+}
+
+// Pick returns the value of a multilingual field for lang. Empty values fall
+// back to the other Latin-script language first, then to Arabic.
+func Pick(lang, ar, fr, en string) string {
+	var order []string
+	switch lang {
+	case French:
+		order = []string{fr, en, ar}
+	case English:
+		order = []string{en, fr, ar}
+	default:
+		order = []string{ar, fr, en}
+	}
+	for _, v := range order {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,1038 @@
+package i18n
+
+// catalog maps every API message, keyed by its source text, to its
+// translations. Arabic sources need only fr and en; the few English
+// sources (request and auth errors) need ar and fr.
+var catalog = map[string]map[string]string{
+	// Request & auth errors
+	"Invalid request body": {
+		"ar": "صيغة الطلب غير صالحة",
+		"fr": "Corps de requête invalide",
+	},
+	"authorization header required": {
+		"ar": "رأس التفويض مطلوب",
+		"fr": "En-tête d'autorisation requis",
+	},
+	"invalid authorization format": {
+		"ar": "صيغة التفويض غير صالحة",
+		"fr": "Format d'autorisation invalide",
+	},
+	"invalid or expired token": {
+		"ar": "التوكن غير صالح أو منتهي الصلاحية",
+		"fr": "Jeton invalide ou expiré",
+	},
+	"insufficient permissions": {
+		"ar": "صلاحيات غير كافية",
+		"fr": "Permissions insuffisantes",
+	},
+	"غير مصرح": {
+		"fr": "Non autorisé",
+		"en": "Unauthorized",
+	},
+	"خطأ في الخادم": {
+		"fr": "Erreur du serveur",
+		"en": "Server error",
+	},
+	"حدث خطأ في الخادم": {
+		"fr": "Une erreur est survenue sur le serveur",
+		"en": "A server error occurred",
+	},
+	"خطأ في حفظ البيانات": {
+		"fr": "Erreur lors de l'enregistrement des données",
+		"en": "Error saving data",
+	},
+
+	// Accounts & 2FA
+	"الاسم، البريد الإلكتروني وكلمة المرور مطلوبة": {
+		"fr": "Le nom, l'e-mail et le mot de passe sont requis",
+		"en": "Name, email and password are required",
+	},
+	"اسم المؤسسة، البريد الإلكتروني وكلمة المرور مطلوبة": {
+		"fr": "Le nom de l'établissement, l'e-mail et le mot de passe sont requis",
+		"en": "Organization name, email and password are required",
+	},
+	"البريد الإلكتروني وكلمة المرور مطلوبة": {
+		"fr": "L'e-mail et le mot de passe sont requis",
+		"en": "Email and password are required",
+	},
+	"البريد الإلكتروني أو كلمة المرور غير صحيحة": {
+		"fr": "E-mail ou mot de passe incorrect",
+		"en": "Incorrect email or password",
+	},
+	"هذا البريد الإلكتروني مسجل بالفعل": {
+		"fr": "Cet e-mail est déjà enregistré",
+		"en": "This email is already registered",
+	},
+	"كلمة المرور يجب أن تكون 8 أحرف على الأقل": {
+		"fr": "Le mot de passe doit contenir au moins 8 caractères",
+		"en": "Password must be at least 8 characters",
+	},
+	"كلمة المرور غير متطابقة": {
+		"fr": "Le mot de passe ne correspond pas",
+		"en": "Password does not match",
+	},
+	"نوع الحساب غير صالح": {
+		"fr": "Type de compte invalide",
+		"en": "Invalid account type",
+	},
+	"المستخدم غير موجود": {
+		"fr": "Utilisateur introuvable",
+		"en": "User not found",
+	},
+	"خطأ في معالجة كلمة المرور": {
+		"fr": "Erreur lors du traitement du mot de passe",
+		"en": "Error processing password",
+	},
+	"خطأ في إنشاء الحساب": {
+		"fr": "Erreur lors de la création du compte",
+		"en": "Error creating account",
+	},
+	"خطأ في إنشاء الملف الشخصي": {
+		"fr": "Erreur lors de la création du profil",
+		"en": "Error creating profile",
+	},
+	"خطأ في إنشاء الملف المهني": {
+		"fr": "Erreur lors de la création du profil professionnel",
+		"en": "Error creating professional profile",
+	},
+	"خطأ في إنشاء المؤسسة": {
+		"fr": "Erreur lors de la création de l'établissement",
+		"en": "Error creating organization",
+	},
+	"خطأ في ربط المستخدم بالمؤسسة": {
+		"fr": "Erreur lors de l'association de l'utilisateur à l'établissement",
+		"en": "Error linking user to organization",
+	},
+	"خطأ في إنشاء التوكن": {
+		"fr": "Erreur lors de la création du jeton",
+		"en": "Error creating token",
+	},
+	"خطأ في تحديث المستخدم": {
+		"fr": "Erreur lors de la mise à jour de l'utilisateur",
+		"en": "Error updating user",
+	},
+	"الحساب مقفل مؤقتاً بسبب محاولات فاشلة متعددة": {
+		"fr": "Compte temporairement verrouillé après plusieurs tentatives échouées",
+		"en": "Account temporarily locked after several failed attempts",
+	},
+	"تم قفل الحساب لمدة 15 دقيقة بسبب محاولات فاشلة متعددة": {
+		"fr": "Compte verrouillé pendant 15 minutes après plusieurs tentatives échouées",
+		"en": "Account locked for 15 minutes after several failed attempts",
+	},
+	"التوكن المؤقت والرمز مطلوبة": {
+		"fr": "Le jeton temporaire et le code sont requis",
+		"en": "Temporary token and code are required",
+	},
+	"التوكن المؤقت منتهي الصلاحية. أعد تسجيل الدخول": {
+		"fr": "Le jeton temporaire a expiré. Reconnectez-vous",
+		"en": "Temporary token expired. Please log in again",
+	},
+	"الرمز يجب أن يكون 6 أرقام": {
+		"fr": "Le code doit comporter 6 chiffres",
+		"en": "Code must be 6 digits",
+	},
+	"الرمز غير صحيح": {
+		"fr": "Code incorrect",
+		"en": "Incorrect code",
+	},
+	"الرمز غير صحيح. تأكد من إعدادات التطبيق": {
+		"fr": "Code incorrect. Vérifiez les réglages de l'application",
+		"en": "Incorrect code. Check your authenticator app settings",
+	},
+	"لم يتم إنشاء رمز المصادقة بعد": {
+		"fr": "Le code d'authentification n'a pas encore été généré",
+		"en": "Authentication secret has not been generated yet",
+	},
+	"خطأ في إنشاء رمز المصادقة": {
+		"fr": "Erreur lors de la génération du code d'authentification",
+		"en": "Error generating authentication secret",
+	},
+	"خطأ في إنشاء رموز الاسترداد": {
+		"fr": "Erreur lors de la génération des codes de récupération",
+		"en": "Error generating recovery codes",
+	},
+	"خطأ في تفعيل المصادقة الثنائية": {
+		"fr": "Erreur lors de l'activation de l'authentification à deux facteurs",
+		"en": "Error enabling two-factor authentication",
+	},
+	"تم تفعيل المصادقة الثنائية بنجاح": {
+		"fr": "Authentification à deux facteurs activée",
+		"en": "Two-factor authentication enabled",
+	},
+
+	// Providers, search & reference data
+	"معرف المزود مطلوب": {
+		"fr": "L'identifiant du prestataire est requis",
+		"en": "Provider ID is required",
+	},
+	"المزود غير موجود": {
+		"fr": "Prestataire introuvable",
+		"en": "Provider not found",
+	},
+	"خطأ في البحث": {
+		"fr": "Erreur de recherche",
+		"en": "Search error",
+	},
+	"نطاق السعر غير صالح": {
+		"fr": "Fourchette de prix invalide",
+		"en": "Invalid price range",
+	},
+	"سنوات الخبرة غير صالحة": {
+		"fr": "Années d'expérience invalides",
+		"en": "Invalid years of experience",
+	},
+	"نوع المزود غير صالح": {
+		"fr": "Type de prestataire invalide",
+		"en": "Invalid provider type",
+	},
+	"خطأ في جلب الولايات": {
+		"fr": "Erreur lors du chargement des wilayas",
+		"en": "Error loading wilayas",
+	},
+	"خطأ في جلب الخدمات": {
+		"fr": "Erreur lors du chargement des services",
+		"en": "Error loading services",
+	},
+	"خطأ في جلب الخدمة": {
+		"fr": "Erreur lors du chargement du service",
+		"en": "Error loading service",
+	},
+	"خطأ في جلب التصنيفات": {
+		"fr": "Erreur lors du chargement des catégories",
+		"en": "Error loading categories",
+	},
+	"الخدمة مطلوبة": {
+		"fr": "Le service est requis",
+		"en": "Service is required",
+	},
+	"الخدمة غير موجودة": {
+		"fr": "Service introuvable",
+		"en": "Service not found",
+	},
+	"الخدمة غير محددة، اختر واحدة": {
+		"fr": "Service ambigu, choisissez-en un",
+		"en": "Ambiguous service, pick one",
+	},
+	"خطأ في حساب الإحصائيات": {
+		"fr": "Erreur lors du calcul des statistiques",
+		"en": "Error computing statistics",
+	},
+	"الولاية غير موجودة": {
+		"fr": "Wilaya introuvable",
+		"en": "Wilaya not found",
+	},
+	"تم مسح الذاكرة المؤقتة": {
+		"fr": "Cache vidé",
+		"en": "Cache cleared",
+	},
+
+	// Reviews
+	"يجب تحديد موعد أو طلب تحليل واحد": {
+		"fr": "Indiquez un seul rendez-vous ou une seule demande d'analyse",
+		"en": "Specify exactly one appointment or lab order",
+	},
+	"التقييم يجب أن يكون بين 1 و 5": {
+		"fr": "La note doit être comprise entre 1 et 5",
+		"en": "Rating must be between 1 and 5",
+	},
+	"يمكنك التقييم فقط بعد زيارة مكتملة لدى هذا المزود": {
+		"fr": "Vous ne pouvez évaluer qu'après une visite terminée chez ce prestataire",
+		"en": "You can only review after a completed visit with this provider",
+	},
+	"لقد قمت بتقييم هذه الزيارة مسبقاً": {
+		"fr": "Vous avez déjà évalué cette visite",
+		"en": "You have already reviewed this visit",
+	},
+	"خطأ في حفظ التقييم": {
+		"fr": "Erreur lors de l'enregistrement de l'avis",
+		"en": "Error saving review",
+	},
+	"خطأ في تحديث التقييم": {
+		"fr": "Erreur lors de la mise à jour de la note",
+		"en": "Error updating rating",
+	},
+	"خطأ في جلب التقييمات": {
+		"fr": "Erreur lors du chargement des avis",
+		"en": "Error loading reviews",
+	},
+	"التقييم غير موجود": {
+		"fr": "Avis introuvable",
+		"en": "Review not found",
+	},
+	"تم تحديث التقييم": {
+		"fr": "Avis mis à jour",
+		"en": "Review updated",
+	},
+	"نص الرد مطلوب": {
+		"fr": "Le texte de la réponse est requis",
+		"en": "Reply text is required",
+	},
+	"خطأ في حفظ الرد": {
+		"fr": "Erreur lors de l'enregistrement de la réponse",
+		"en": "Error saving reply",
+	},
+	"تم نشر الرد": {
+		"fr": "Réponse publiée",
+		"en": "Reply published",
+	},
+	"سبب الإبلاغ مطلوب": {
+		"fr": "Le motif du signalement est requis",
+		"en": "Report reason is required",
+	},
+	"لقد أبلغت عن هذا التقييم مسبقاً": {
+		"fr": "Vous avez déjà signalé cet avis",
+		"en": "You have already reported this review",
+	},
+	"خطأ في حفظ البلاغ": {
+		"fr": "Erreur lors de l'enregistrement du signalement",
+		"en": "Error saving report",
+	},
+	"تم إرسال البلاغ": {
+		"fr": "Signalement envoyé",
+		"en": "Report submitted",
+	},
+	"خطأ في جلب البلاغات": {
+		"fr": "Erreur lors du chargement des signalements",
+		"en": "Error loading reports",
+	},
+	"البلاغ غير موجود": {
+		"fr": "Signalement introuvable",
+		"en": "Report not found",
+	},
+	"تم تحديث البلاغ": {
+		"fr": "Signalement mis à jour",
+		"en": "Report updated",
+	},
+	"حالة غير صالحة": {
+		"fr": "Statut invalide",
+		"en": "Invalid status",
+	},
+
+	// Listings
+	"خطأ في جلب الصفحات": {
+		"fr": "Erreur lors du chargement des fiches",
+		"en": "Error loading listings",
+	},
+	"هذه الصفحة مطالب بها مسبقاً": {
+		"fr": "Cette fiche est déjà revendiquée",
+		"en": "This listing has already been claimed",
+	},
+	"طلب المطالبة قيد المراجعة": {
+		"fr": "La demande de revendication est en cours d'examen",
+		"en": "The claim is pending review",
+	},
+	"اسم المزود مطلوب ولا يتجاوز 255 حرفاً": {
+		"fr": "Le nom du prestataire est requis (255 caractères max.)",
+		"en": "Provider name is required (max 255 characters)",
+	},
+	"الاسم بالإنجليزية طويل جداً": {
+		"fr": "Le nom en anglais est trop long",
+		"en": "English name is too long",
+	},
+	"العنوان طويل جداً": {
+		"fr": "L'adresse est trop longue",
+		"en": "Address is too long",
+	},
+	"رقم الهاتف غير صالح": {
+		"fr": "Numéro de téléphone invalide",
+		"en": "Invalid phone number",
+	},
+	"أوقات العمل يجب أن تكون بالشكل 08:00 - 18:00 أو 24/7": {
+		"fr": "Les horaires doivent être au format 08:00 - 18:00 ou 24/7",
+		"en": "Opening hours must look like 08:00 - 18:00 or 24/7",
+	},
+	"رابط الصورة غير صالح": {
+		"fr": "Lien d'image invalide",
+		"en": "Invalid image URL",
+	},
+	"عدد الصور يتجاوز الحد المسموح": {
+		"fr": "Trop d'images",
+		"en": "Too many images",
+	},
+	"يجب اختيار الخدمة من الدليل": {
+		"fr": "Le service doit être choisi dans le catalogue",
+		"en": "The service must be chosen from the catalog",
+	},
+	"الخدمة غير موجودة في الدليل": {
+		"fr": "Service absent du catalogue",
+		"en": "Service not found in the catalog",
+	},
+	"اسم الخدمة مطلوب ولا يتجاوز 255 حرفاً": {
+		"fr": "Le nom du service est requis (255 caractères max.)",
+		"en": "Service name is required (max 255 characters)",
+	},
+	"السعر غير صالح": {
+		"fr": "Prix invalide",
+		"en": "Invalid price",
+	},
+	"مدة الإنجاز طويلة جداً": {
+		"fr": "Le délai de réalisation est trop long",
+		"en": "Turnaround is too long",
+	},
+	"بيانات الطبيب طويلة جداً": {
+		"fr": "Les informations du médecin sont trop longues",
+		"en": "Doctor details are too long",
+	},
+	"بيانات الجهاز طويلة جداً": {
+		"fr": "Les informations de l'équipement sont trop longues",
+		"en": "Equipment details are too long",
+	},
+	"معرف الخدمة غير صالح": {
+		"fr": "Identifiant de service invalide",
+		"en": "Invalid service ID",
+	},
+	"خطأ في حفظ الطلب": {
+		"fr": "Erreur lors de l'enregistrement de la demande",
+		"en": "Error saving request",
+	},
+	"خطأ في تطبيق التعديل": {
+		"fr": "Erreur lors de l'application de la modification",
+		"en": "Error applying change",
+	},
+	"خطأ في جلب الطلبات": {
+		"fr": "Erreur lors du chargement des demandes",
+		"en": "Error loading requests",
+	},
+	"الطلب غير موجود أو تمت مراجعته": {
+		"fr": "Demande introuvable ou déjà examinée",
+		"en": "Request not found or already reviewed",
+	},
+	"سبب الرفض مطلوب": {
+		"fr": "Le motif du refus est requis",
+		"en": "Rejection reason is required",
+	},
+	"تم إرسال التعديل للمراجعة": {
+		"fr": "Modification envoyée pour examen",
+		"en": "Change submitted for review",
+	},
+	"تم حفظ التعديل": {
+		"fr": "Modification enregistrée",
+		"en": "Change saved",
+	},
+	"تم اعتماد التعديل": {
+		"fr": "Modification approuvée",
+		"en": "Change approved",
+	},
+	"تم رفض التعديل": {
+		"fr": "Modification refusée",
+		"en": "Change rejected",
+	},
+
+	// Organization listing & prices
+	"لا توجد مؤسسة نشطة": {
+		"fr": "Aucun établissement actif",
+		"en": "No active organization",
+	},
+	"المؤسسة غير موجودة": {
+		"fr": "Établissement introuvable",
+		"en": "Organization not found",
+	},
+	"الولاية مطلوبة لنشر الصفحة": {
+		"fr": "La wilaya est requise pour publier la fiche",
+		"en": "A wilaya is required to publish the listing",
+	},
+	"خطأ في تحديث المؤسسة": {
+		"fr": "Erreur lors de la mise à jour de l'établissement",
+		"en": "Error updating organization",
+	},
+	"خطأ في نشر الصفحة": {
+		"fr": "Erreur lors de la publication de la fiche",
+		"en": "Error publishing listing",
+	},
+	"خطأ في مزامنة الخدمات": {
+		"fr": "Erreur lors de la synchronisation des services",
+		"en": "Error syncing services",
+	},
+	"خطأ في إخفاء الصفحة": {
+		"fr": "Erreur lors du masquage de la fiche",
+		"en": "Error hiding listing",
+	},
+	"تم إخفاء الصفحة من البحث": {
+		"fr": "Fiche masquée de la recherche",
+		"en": "Listing hidden from search",
+	},
+	"خطأ في جلب الأسعار": {
+		"fr": "Erreur lors du chargement des prix",
+		"en": "Error loading prices",
+	},
+	"الرمز غير موجود في الدليل": {
+		"fr": "Code absent du catalogue",
+		"en": "Code not found in the catalog",
+	},
+	"خطأ في حفظ السعر": {
+		"fr": "Erreur lors de l'enregistrement du prix",
+		"en": "Error saving price",
+	},
+	"تم حفظ السعر": {
+		"fr": "Prix enregistré",
+		"en": "Price saved",
+	},
+	"السعر غير موجود": {
+		"fr": "Prix introuvable",
+		"en": "Price not found",
+	},
+	"خطأ في حذف السعر": {
+		"fr": "Erreur lors de la suppression du prix",
+		"en": "Error deleting price",
+	},
+	"تم حذف السعر": {
+		"fr": "Prix supprimé",
+		"en": "Price deleted",
+	},
+
+	// Media uploads
+	"الملف مطلوب": {
+		"fr": "Le fichier est requis",
+		"en": "A file is required",
+	},
+	"حجم الملف يتجاوز الحد المسموح": {
+		"fr": "Le fichier dépasse la taille autorisée",
+		"en": "File exceeds the size limit",
+	},
+	"نوع الملف غير مدعوم (JPEG أو PNG أو GIF)": {
+		"fr": "Type de fichier non pris en charge (JPEG, PNG ou GIF)",
+		"en": "Unsupported file type (JPEG, PNG or GIF)",
+	},
+	"أبعاد الصورة كبيرة جداً": {
+		"fr": "Les dimensions de l'image sont trop grandes",
+		"en": "Image dimensions are too large",
+	},
+	"خطأ في حفظ الملف": {
+		"fr": "Erreur lors de l'enregistrement du fichier",
+		"en": "Error saving file",
+	},
+
+	// Provider comparison
+	"اختر من 2 إلى 5 مزودين للمقارنة": {
+		"fr": "Choisissez de 2 à 5 prestataires à comparer",
+		"en": "Choose 2 to 5 providers to compare",
+	},
+	"الإحداثيات غير صالحة": {
+		"fr": "Coordonnées invalides",
+		"en": "Invalid coordinates",
+	},
+	"السعر": {
+		"fr": "Prix",
+		"en": "Price",
+	},
+	"مدة الإنجاز": {
+		"fr": "Délai",
+		"en": "Turnaround",
+	},
+	"الجهاز": {
+		"fr": "Équipement",
+		"en": "Equipment",
+	},
+	"الطبيب": {
+		"fr": "Médecin",
+		"en": "Doctor",
+	},
+	"التقييم": {
+		"fr": "Note",
+		"en": "Rating",
+	},
+	"أوقات العمل": {
+		"fr": "Horaires",
+		"en": "Opening hours",
+	},
+	"المسافة (كم)": {
+		"fr": "Distance (km)",
+		"en": "Distance (km)",
+	},
+
+	// Favorites & saved searches
+	"خطأ في جلب المفضلة": {
+		"fr": "Erreur lors de la récupération des favoris",
+		"en": "Error fetching favorites",
+	},
+	"تمت الإضافة إلى المفضلة": {
+		"fr": "Ajouté aux favoris",
+		"en": "Added to favorites",
+	},
+	"المزود ليس في المفضلة": {
+		"fr": "Ce prestataire n'est pas dans vos favoris",
+		"en": "Provider is not in your favorites",
+	},
+	"تمت الإزالة من المفضلة": {
+		"fr": "Retiré des favoris",
+		"en": "Removed from favorites",
+	},
+	"خطأ في جلب عمليات البحث المحفوظة": {
+		"fr": "Erreur lors de la récupération des recherches enregistrées",
+		"en": "Error fetching saved searches",
+	},
+	"الاسم ومعايير البحث مطلوبة": {
+		"fr": "Le nom et les critères de recherche sont requis",
+		"en": "Name and search criteria are required",
+	},
+	"اسم البحث مطلوب ولا يتجاوز 100 حرف": {
+		"fr": "Le nom de la recherche est requis (100 caractères max.)",
+		"en": "Search name is required (max 100 characters)",
+	},
+	"معايير البحث غير صالحة": {
+		"fr": "Critères de recherche invalides",
+		"en": "Invalid search criteria",
+	},
+	"بلغت الحد الأقصى لعمليات البحث المحفوظة": {
+		"fr": "Nombre maximal de recherches enregistrées atteint",
+		"en": "Saved search limit reached",
+	},
+	"البحث المحفوظ غير موجود": {
+		"fr": "Recherche enregistrée introuvable",
+		"en": "Saved search not found",
+	},
+	"تم حذف البحث المحفوظ": {
+		"fr": "Recherche enregistrée supprimée",
+		"en": "Saved search deleted",
+	},
+	"نتائج جديدة لبحثك \"%s\"": {
+		"fr": "Nouveaux résultats pour votre recherche « %s »",
+		"en": "New results for your search \"%s\"",
+	},
+	"مزود جديد: %s": {
+		"fr": "Nouveau prestataire : %s",
+		"en": "New provider: %s",
+	},
+	"سعر أقل لدى %s: %d ← %d دج": {
+		"fr": "Prix en baisse chez %s : %d → %d DA",
+		"en": "Lower price at %s: %d → %d DZD",
+	},
+
+	// Communes
+	"خطأ في جلب البلديات": {
+		"fr": "Erreur lors de la récupération des communes",
+		"en": "Error fetching communes",
+	},
+	"البلدية لا تنتمي إلى ولاية المزود": {
+		"fr": "La commune n'appartient pas à la wilaya du prestataire",
+		"en": "The commune is not in the provider's wilaya",
+	},
+	"خطأ في جلب المرضى": {
+		"fr": "Erreur lors de la récupération des patients",
+		"en": "Error fetching patients",
+	},
+	"المريض غير موجود": {
+		"fr": "Patient introuvable",
+		"en": "Patient not found",
+	},
+	"خطأ في حفظ المريض": {
+		"fr": "Erreur lors de l'enregistrement du patient",
+		"en": "Error saving patient",
+	},
+	"اسم المريض بالعربية مطلوب": {
+		"fr": "Le nom du patient en arabe est requis",
+		"en": "The patient's Arabic name is required",
+	},
+	"رقم التعريف الوطني يجب أن يتكون من 18 رقما": {
+		"fr": "Le NIN doit comporter 18 chiffres",
+		"en": "The NIN must have 18 digits",
+	},
+	"يوجد مريض بنفس رقم التعريف الوطني": {
+		"fr": "Un patient avec ce NIN existe déjà",
+		"en": "A patient with this NIN already exists",
+	},
+	"رقم الشفاء يجب أن يتكون من 12 رقما": {
+		"fr": "Le numéro Chifa doit comporter 12 chiffres",
+		"en": "The Chifa number must have 12 digits",
+	},
+	"يوجد مريض بنفس رقم الشفاء": {
+		"fr": "Un patient avec ce numéro Chifa existe déjà",
+		"en": "A patient with this Chifa number already exists",
+	},
+	"تاريخ الميلاد غير صالح": {
+		"fr": "Date de naissance invalide",
+		"en": "Invalid date of birth",
+	},
+	"الجنس غير صالح": {
+		"fr": "Sexe invalide",
+		"en": "Invalid gender",
+	},
+	"فصيلة الدم غير صالحة": {
+		"fr": "Groupe sanguin invalide",
+		"en": "Invalid blood type",
+	},
+	"الحالة العائلية غير صالحة": {
+		"fr": "Situation familiale invalide",
+		"en": "Invalid marital status",
+	},
+	"نوع التأمين غير صالح": {
+		"fr": "Type d'assurance invalide",
+		"en": "Invalid insurance type",
+	},
+	"نسبة التغطية يجب أن تكون بين 0 و 100": {
+		"fr": "Le taux de couverture doit être entre 0 et 100",
+		"en": "Coverage must be between 0 and 100",
+	},
+	"البريد الإلكتروني غير صالح": {
+		"fr": "Adresse e-mail invalide",
+		"en": "Invalid email address",
+	},
+	"البلدية لا تنتمي إلى الولاية": {
+		"fr": "La commune n'appartient pas à la wilaya",
+		"en": "The commune is not in the wilaya",
+	},
+	"لا يمكن دمج المريض مع نفسه": {
+		"fr": "Impossible de fusionner un patient avec lui-même",
+		"en": "A patient cannot be merged with itself",
+	},
+	"لا يمكن دمج مريضين برقمي تعريف وطني مختلفين": {
+		"fr": "Impossible de fusionner deux patients aux NIN différents",
+		"en": "Patients with different NINs cannot be merged",
+	},
+	"لا يمكن دمج ملفين مرتبطين بحسابين مختلفين": {
+		"fr": "Impossible de fusionner deux dossiers liés à des comptes différents",
+		"en": "Records linked to different accounts cannot be merged",
+	},
+	"خطأ في دمج الملفين": {
+		"fr": "Erreur lors de la fusion des dossiers",
+		"en": "Error merging records",
+	},
+	"خطأ في ربط الحساب": {
+		"fr": "Erreur lors de la liaison du compte",
+		"en": "Error linking the account",
+	},
+	"المريض مرتبط بحساب أو لديه طلب ربط قائم": {
+		"fr": "Le patient est déjà lié à un compte ou a une demande en attente",
+		"en": "The patient is already linked or has a pending request",
+	},
+	"رمز الربط غير صالح أو منتهي الصلاحية": {
+		"fr": "Code de liaison invalide ou expiré",
+		"en": "Invalid or expired link code",
+	},
+	"لا يوجد حساب مطابق لهذا المريض": {
+		"fr": "Aucun compte ne correspond à ce patient",
+		"en": "No account matches this patient",
+	},
+	"أكثر من حساب يطابق هذا المريض، استخدم رمز الربط": {
+		"fr": "Plusieurs comptes correspondent à ce patient, utilisez un code de liaison",
+		"en": "Several accounts match this patient, use a link code",
+	},
+	"هذا الحساب مرتبط بمريض آخر في المؤسسة": {
+		"fr": "Ce compte est déjà lié à un autre patient de l'établissement",
+		"en": "This account is linked to another patient of the organization",
+	},
+	"لا يوجد ربط لهذا المريض": {
+		"fr": "Aucune liaison pour ce patient",
+		"en": "No link for this patient",
+	},
+	"خطأ في جلب الروابط": {
+		"fr": "Erreur lors de la récupération des liaisons",
+		"en": "Error fetching links",
+	},
+	"طلب الربط لم يعد قائما": {
+		"fr": "Cette demande de liaison n'est plus ouverte",
+		"en": "This link is no longer open",
+	},
+	"خطأ في جلب سجل الوصول": {
+		"fr": "Erreur lors de la récupération du journal d'accès",
+		"en": "Error fetching the access log",
+	},
+	"الربط غير موجود": {
+		"fr": "Liaison introuvable",
+		"en": "Link not found",
+	},
+	"خطأ في جلب الملف الطبي": {
+		"fr": "Erreur lors de la récupération du dossier médical",
+		"en": "Error fetching the medical record",
+	},
+	"خطأ في جلب الإعدادات": {
+		"fr": "Erreur lors de la récupération des paramètres",
+		"en": "Error fetching settings",
+	},
+	"خطأ في حفظ الإعدادات": {
+		"fr": "Erreur lors de l'enregistrement des paramètres",
+		"en": "Error saving settings",
+	},
+	"قاعدة نشر النتائج غير صالحة": {
+		"fr": "Règle de publication des résultats invalide",
+		"en": "Invalid result release rule",
+	},
+	"مدة تأخير النتائج يجب أن تكون بين 0 و 168 ساعة": {
+		"fr": "Le délai de publication doit être entre 0 et 168 heures",
+		"en": "The result delay must be between 0 and 168 hours",
+	},
+	"الزيارة غير موجودة": {
+		"fr": "Consultation introuvable",
+		"en": "Visit not found",
+	},
+	"خطأ في جلب الزيارات": {
+		"fr": "Erreur lors du chargement des consultations",
+		"en": "Error loading visits",
+	},
+	"خطأ في حفظ الزيارة": {
+		"fr": "Erreur lors de l'enregistrement de la consultation",
+		"en": "Error saving visit",
+	},
+	"الطبيب غير موجود": {
+		"fr": "Médecin introuvable",
+		"en": "Doctor not found",
+	},
+	"القسم غير موجود": {
+		"fr": "Service introuvable",
+		"en": "Department not found",
+	},
+	"تاريخ الزيارة لا يمكن أن يكون في المستقبل": {
+		"fr": "La date de consultation ne peut pas être dans le futur",
+		"en": "The visit date cannot be in the future",
+	},
+	"ضغط الدم يتطلب القيمتين الانقباضية والانبساطية": {
+		"fr": "La tension artérielle requiert les valeurs systolique et diastolique",
+		"en": "Blood pressure requires both systolic and diastolic values",
+	},
+	"قيمة ضغط الدم غير صالحة": {
+		"fr": "Tension artérielle invalide",
+		"en": "Invalid blood pressure",
+	},
+	"قيمة نبض القلب غير صالحة": {
+		"fr": "Fréquence cardiaque invalide",
+		"en": "Invalid heart rate",
+	},
+	"قيمة درجة الحرارة غير صالحة": {
+		"fr": "Température invalide",
+		"en": "Invalid temperature",
+	},
+	"قيمة تشبع الأكسجين غير صالحة": {
+		"fr": "Saturation en oxygène invalide",
+		"en": "Invalid oxygen saturation",
+	},
+	"قيمة الوزن غير صالحة": {
+		"fr": "Poids invalide",
+		"en": "Invalid weight",
+	},
+	"قيمة الطول غير صالحة": {
+		"fr": "Taille invalide",
+		"en": "Invalid height",
+	},
+	"قيمة نسبة السكر في الدم غير صالحة": {
+		"fr": "Glycémie invalide",
+		"en": "Invalid blood glucose",
+	},
+	"المؤشر الحيوي غير معروف": {
+		"fr": "Signe vital inconnu",
+		"en": "Unknown vital sign",
+	},
+	"صيغة التاريخ غير صالحة": {
+		"fr": "Format de date invalide",
+		"en": "Invalid date format",
+	},
+	"خطأ في جلب المؤشرات الحيوية": {
+		"fr": "Erreur lors du chargement des signes vitaux",
+		"en": "Error loading vital signs",
+	},
+	"رمز التشخيص غير موجود في التصنيف": {
+		"fr": "Code de diagnostic absent de la CIM-10",
+		"en": "Diagnosis code not found in ICD-10",
+	},
+	"رمز التشخيص عام، اختر رمزاً أدق": {
+		"fr": "Code de diagnostic trop général, choisissez un code plus précis",
+		"en": "Diagnosis code is too general, choose a more specific one",
+	},
+	"رمز التشخيص مكرر": {
+		"fr": "Code de diagnostic en double",
+		"en": "Duplicate diagnosis code",
+	},
+	"عدد التشخيصات يتجاوز الحد المسموح": {
+		"fr": "Trop de diagnostics pour une consultation",
+		"en": "Too many diagnoses for one visit",
+	},
+	"خطأ في جلب رموز التشخيص": {
+		"fr": "Erreur lors du chargement des codes de diagnostic",
+		"en": "Error loading diagnosis codes",
+	},
+	"صيغة الملف غير مدعومة (who أو atih أو csv)": {
+		"fr": "Format de fichier non pris en charge (who, atih ou csv)",
+		"en": "Unsupported file format (who, atih or csv)",
+	},
+	"اللغة غير مدعومة": {
+		"fr": "Langue non prise en charge",
+		"en": "Unsupported language",
+	},
+	"ملف CSV غير صالح، يجب أن يحتوي على عمود code": {
+		"fr": "Fichier CSV invalide, une colonne code est requise",
+		"en": "Invalid CSV file, a code column is required",
+	},
+	"لم يتم العثور على أي رمز في الملف": {
+		"fr": "Aucun code trouvé dans le fichier",
+		"en": "No code found in the file",
+	},
+	"خطأ في استيراد التصنيف": {
+		"fr": "Erreur lors de l'import de la classification",
+		"en": "Error importing the classification",
+	},
+	"الوصفة غير موجودة": {
+		"fr": "Ordonnance introuvable",
+		"en": "Prescription not found",
+	},
+	"الوصفة المراد تجديدها غير موجودة": {
+		"fr": "Ordonnance à renouveler introuvable",
+		"en": "Prescription to renew not found",
+	},
+	"الوصفة تحتوي على 1 إلى 20 دواء": {
+		"fr": "Une ordonnance contient de 1 à 20 médicaments",
+		"en": "A prescription has 1 to 20 medications",
+	},
+	"اسم الدواء مطلوب ولا يتجاوز 255 حرفاً": {
+		"fr": "Le nom du médicament est requis (255 caractères max.)",
+		"en": "Medication name is required (max 255 characters)",
+	},
+	"الجرعة والتكرار والمدة لا تتجاوز 100 حرف": {
+		"fr": "Posologie, fréquence et durée : 100 caractères max.",
+		"en": "Dosage, frequency and duration: max 100 characters",
+	},
+	"الكمية يجب أن تكون بين 1 و 100": {
+		"fr": "La quantité doit être comprise entre 1 et 100",
+		"en": "Quantity must be between 1 and 100",
+	},
+	"خطأ في حفظ الوصفة": {
+		"fr": "Erreur lors de l'enregistrement de l'ordonnance",
+		"en": "Error saving the prescription",
+	},
+	"خطأ في جلب الوصفات": {
+		"fr": "Erreur lors de la récupération des ordonnances",
+		"en": "Error fetching prescriptions",
+	},
+	"خطأ في إنشاء ملف PDF": {
+		"fr": "Erreur lors de la génération du PDF",
+		"en": "Error generating the PDF",
+	},
+	"خطأ في جلب الأدوية": {
+		"fr": "Erreur lors de la récupération des médicaments",
+		"en": "Error fetching medications",
+	},
+	"الدواء غير موجود في قائمة الأدوية": {
+		"fr": "Médicament introuvable dans la nomenclature",
+		"en": "Medication not found in the formulary",
+	},
+	"ملف CSV غير صالح، يجب أن يحتوي على أعمدة رقم التسجيل والتسمية المشتركة والعلامة التجارية": {
+		"fr": "Fichier CSV invalide, les colonnes numéro d'enregistrement, DCI et nom de marque sont requises",
+		"en": "Invalid CSV file, registration number, DCI and brand columns are required",
+	},
+	"لم يتم العثور على أي دواء في الملف": {
+		"fr": "Aucun médicament trouvé dans le fichier",
+		"en": "No medication found in the file",
+	},
+	"خطأ في استيراد قائمة الأدوية": {
+		"fr": "Erreur lors de l'import de la nomenclature",
+		"en": "Error importing the formulary",
+	},
+	"الوصفة تتعارض مع ملف المريض، يلزم تبرير لتجاوز التنبيهات": {
+		"fr": "L'ordonnance présente des alertes bloquantes, une justification est requise pour passer outre",
+		"en": "The prescription raises blocking alerts, a justification is required to override them",
+	},
+	"التبرير لا يتجاوز 1000 حرف": {
+		"fr": "La justification ne doit pas dépasser 1000 caractères",
+		"en": "The justification must not exceed 1000 characters",
+	},
+	"خطأ في التحقق من الوصفة": {
+		"fr": "Erreur lors de la vérification de l'ordonnance",
+		"en": "Error checking the prescription",
+	},
+	"خطأ في جلب الحساسيات": {
+		"fr": "Erreur lors de la récupération des allergies",
+		"en": "Error fetching allergies",
+	},
+	"المادة المسببة للحساسية مطلوبة ولا تتجاوز 255 حرفاً": {
+		"fr": "La substance est requise (255 caractères max.)",
+		"en": "Substance is required (max 255 characters)",
+	},
+	"وصف رد الفعل لا يتجاوز 255 حرفاً": {
+		"fr": "La réaction ne doit pas dépasser 255 caractères",
+		"en": "Reaction must not exceed 255 characters",
+	},
+	"درجة الحساسية غير صالحة": {
+		"fr": "Sévérité d'allergie invalide",
+		"en": "Invalid allergy severity",
+	},
+	"هذه الحساسية مسجلة مسبقاً": {
+		"fr": "Cette allergie est déjà enregistrée",
+		"en": "This allergy is already recorded",
+	},
+	"الحساسية غير موجودة": {
+		"fr": "Allergie introuvable",
+		"en": "Allergy not found",
+	},
+	"تم حذف الحساسية": {
+		"fr": "Allergie supprimée",
+		"en": "Allergy deleted",
+	},
+	"خطأ في جلب الأمراض المزمنة": {
+		"fr": "Erreur lors de la récupération des pathologies",
+		"en": "Error fetching conditions",
+	},
+	"اسم المرض مطلوب ولا يتجاوز 255 حرفاً": {
+		"fr": "Le libellé de la pathologie est requis (255 caractères max.)",
+		"en": "Condition label is required (max 255 characters)",
+	},
+	"تاريخ بداية المرض غير صالح": {
+		"fr": "Date de début invalide",
+		"en": "Invalid onset date",
+	},
+	"المرض غير موجود": {
+		"fr": "Pathologie introuvable",
+		"en": "Condition not found",
+	},
+	"تم حذف المرض": {
+		"fr": "Pathologie supprimée",
+		"en": "Condition deleted",
+	},
+	"خطأ في جلب قواعد التداخل الدوائي": {
+		"fr": "Erreur lors de la récupération des interactions médicamenteuses",
+		"en": "Error fetching drug interaction rules",
+	},
+	"رمز التشخيص غير صالح": {
+		"fr": "Code diagnostic invalide",
+		"en": "Invalid diagnosis code",
+	},
+	"القاعدة تربط مادة بمادة أخرى أو بمرض": {
+		"fr": "Une règle associe une substance à une autre substance ou à une pathologie",
+		"en": "A rule pairs a substance with another substance or with a condition",
+	},
+	"درجة الخطورة غير صالحة": {
+		"fr": "Sévérité invalide",
+		"en": "Invalid severity",
+	},
+	"وصف التداخل مطلوب": {
+		"fr": "La description de l'interaction est requise",
+		"en": "Interaction description is required",
+	},
+	"هذه القاعدة موجودة مسبقاً": {
+		"fr": "Cette règle existe déjà",
+		"en": "This rule already exists",
+	},
+	"قاعدة التداخل غير موجودة": {
+		"fr": "Règle d'interaction introuvable",
+		"en": "Interaction rule not found",
+	},
+	"تم حذف قاعدة التداخل": {
+		"fr": "Règle d'interaction supprimée",
+		"en": "Interaction rule deleted",
+	},
+	"نوع الحدث غير معروف": {
+		"fr": "Type d'événement inconnu",
+		"en": "Unknown event type",
+	},
+	"سنة واحدة": {
+		"fr": "1 an",
+		"en": "1 year",
+	},
+	"%d سنة": {
+		"fr": "%d ans",
+		"en": "%d years",
+	},
+	"حساسية مسجلة لدى المريض تجاه %s": {
+		"fr": "Allergie connue du patient : %s",
+		"en": "Known patient allergy: %s",
+	},
+	"المادة %s موصوفة مرتين": {
+		"fr": "Substance prescrite deux fois : %s",
+		"en": "Substance prescribed twice: %s",
+	},
+	"المادة %s موصوفة في العلاج الحالي": {
+		"fr": "Substance déjà présente dans le traitement en cours : %s",
+		"en": "Substance already in the current treatment: %s",
+	},
+	"دواءان من نفس الفئة العلاجية: %s": {
+		"fr": "Deux médicaments de la même classe : %s",
+		"en": "Two medications of the same class: %s",
+	},
+}
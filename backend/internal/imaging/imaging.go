@@ -0,0 +1,110 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+
+	// Register decoders for image.Decode
+	_ "image/gif"
+	_ "image/png"
+)
+
+// MaxPixels bounds decoded images so a small, highly compressed file cannot
+// expand into gigabytes of memory.
+const MaxPixels = 25_000_000
+
+var (
+	ErrUnsupported = errors.New("unsupported image type")
+	ErrTooLarge    = errors.New("image dimensions too large")
+)
+
+// Extensions maps the accepted content types to file extensions.
+var Extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Info describes a validated upload.
+type Info struct {
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// Sniff detects the real content type from the bytes (ignoring what the
+// client claimed) and checks the dimensions without decoding the pixels.
+func Sniff(data []byte) (Info, error) {
+	ct := http.DetectContentType(data)
+	if _, ok := Extensions[ct]; !ok {
+		return Info{}, ErrUnsupported
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Info{}, ErrUnsupported
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return Info{}, ErrTooLarge
+	}
+	return Info{ContentType: ct, Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+// Thumbnail decodes an image and returns a JPEG that fits in size×size,
+// keeping the aspect ratio. Transparent areas are flattened onto white.
+func Thumbnail(data []byte, size int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/w)
+			dst.SetRGBA(x, y, averageOverWhite(src, x0, y0, x1, y1))
+		}
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// averageOverWhite box-filters the source rectangle [x0,x1)×[y0,y1).
+func averageOverWhite(src image.Image, x0, y0, x1, y1 int) color.RGBA {
+	var r, g, bl, n uint64
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			cr, cg, cb, ca := src.At(x, y).RGBA() // alpha-premultiplied, 16-bit
+			r += uint64(cr + 0xffff - ca)
+			g += uint64(cg + 0xffff - ca)
+			bl += uint64(cb + 0xffff - ca)
+			n++
+		}
+	}
+	return color.RGBA{
+		R: uint8(r / n >> 8),
+		G: uint8(g / n >> 8),
+		B: uint8(bl / n >> 8),
+		A: 0xff,
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/anis7x/cliniclab/internal/auth"
+	"github.com/anis7x/cliniclab/internal/models"
 )
 
 type contextKey string
@@ -17,24 +18,24 @@ func AuthRequired(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		header := r.Header.Get("Authorization")
 		if header == "" {
-			http.Error(w, `{"error":"authorization header required"}`, http.StatusUnauthorized)
+			writeError(w, http.StatusUnauthorized, "authorization header required")
 			return
 		}
 
 		parts := strings.SplitN(header, " ", 2)
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, `{"error":"invalid authorization format"}`, http.StatusUnauthorized)
+			writeError(w, http.StatusUnauthorized, "invalid authorization format")
 			return
 		}
 
 		claims, err := auth.ValidateToken(parts[1])
 		if err != nil {
-			http.Error(w, `{"error":"invalid or expired token"}`, http.StatusUnauthorized)
+			writeError(w, http.StatusUnauthorized, "invalid or expired token")
 			return
 		}
 
 		ctx := context.WithValue(r.Context(), UserClaimsKey, claims)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, withUserLanguage(w, r.WithContext(ctx), claims.UserID))
 	})
 }
 
@@ -43,3 +44,24 @@ func GetClaims(r *http.Request) *auth.Claims {
 	claims, _ := r.Context().Value(UserClaimsKey).(*auth.Claims)
 	return claims
 }
+
+// RequireRole rejects requests whose JWT role is not in the allowed list.
+// Must be mounted after AuthRequired.
+func RequireRole(roles ...models.UserRole) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := GetClaims(r)
+			if claims == nil {
+				writeError(w, http.StatusUnauthorized, "authorization header required")
+				return
+			}
+			for _, role := range roles {
+				if claims.Role == string(role) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			writeError(w, http.StatusForbidden, "insufficient permissions")
+		})
+	}
+}
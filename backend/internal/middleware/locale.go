@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/anis7x/cliniclab/internal/i18n"
+)
+
+const localeKey contextKey = "locale"
+
+type locale struct {
+	lang     string
+	explicit bool
+}
+
+var userDefaultLanguage func(ctx context.Context, userID string) string
+
+// InitLocale sets how to find the fallback language of an authenticated
+// user (their organization's default_language). Must be called at startup.
+func InitLocale(fn func(ctx context.Context, userID string) string) {
+	userDefaultLanguage = fn
+}
+
+// Locale negotiates the response language from ?lang= and Accept-Language
+// and announces it in Content-Language, which writeError reads back.
+func Locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lang, explicit := i18n.Negotiate(r)
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, withLang(w, r, locale{lang: lang, explicit: explicit}))
+	})
+}
+
+// withUserLanguage switches to the user's default language when the request
+// did not ask for one.
+func withUserLanguage(w http.ResponseWriter, r *http.Request, userID string) *http.Request {
+	loc, ok := r.Context().Value(localeKey).(locale)
+	if (ok && loc.explicit) || userDefaultLanguage == nil {
+		return r
+	}
+	lang := userDefaultLanguage(r.Context(), userID)
+	if !i18n.Supported(lang) {
+		return r
+	}
+	return withLang(w, r, locale{lang: lang})
+}
+
+func withLang(w http.ResponseWriter, r *http.Request, loc locale) *http.Request {
+	w.Header().Set("Content-Language", loc.lang)
+	return r.WithContext(context.WithValue(r.Context(), localeKey, loc))
+}
+
+// GetLang returns the negotiated response language.
+func GetLang(r *http.Request) string {
+	if loc, ok := r.Context().Value(localeKey).(locale); ok {
+		return loc.lang
+	}
+	return i18n.Default
+}
+
+// writeError writes a localized {"error": msg} body.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	body, _ := json.Marshal(map[string]string{"error": i18n.T(w.Header().Get("Content-Language"), msg)})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Allergy severities
+const (
+	AllergyMild     = "MILD"
+	AllergyModerate = "MODERATE"
+	AllergySevere   = "SEVERE"
+)
+
+// PatientAllergy is a structured allergy entry, checked when prescribing.
+// Substance is a DCI (AMOXICILLINE), a drug class (PENICILLINES) or a
+// brand, resolved to its DCI at check time.
+type PatientAllergy struct {
+	ID         string    `json:"id"`
+	PatientID  string    `json:"patient_id"`
+	Substance  string    `json:"substance"`
+	Reaction   string    `json:"reaction,omitempty"`
+	Severity   string    `json:"severity"`
+	RecordedBy string    `json:"recorded_by,omitempty"` // staff name
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type PatientAllergyInput struct {
+	Substance string `json:"substance"`
+	Reaction  string `json:"reaction"`
+	Severity  string `json:"severity"` // defaults to MODERATE
+}
+
+// PatientCondition is a structured chronic condition. Interaction rules match
+// its ICD-10 code; a condition without one is informative only.
+type PatientCondition struct {
+	ID         string    `json:"id"`
+	PatientID  string    `json:"patient_id"`
+	Label      string    `json:"label"`
+	ICD10Code  *string   `json:"icd10_code,omitempty"`
+	Since      *string   `json:"since,omitempty"` // YYYY-MM-DD
+	RecordedBy string    `json:"recorded_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PatientConditionInput adds a condition; label defaults to the catalog
+// label of icd10_code.
+type PatientConditionInput struct {
+	Label     string  `json:"label"`
+	ICD10Code *string `json:"icd10_code"`
+	Since     *string `json:"since"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// QueryStat counts searches for one query (free text, or service IDs when
+// the search used service_id).
+type QueryStat struct {
+	Query       string  `json:"query"`
+	Searches    int     `json:"searches"`
+	ZeroResults int     `json:"zero_results"`
+	AvgResults  float64 `json:"avg_results"`
+}
+
+// ZeroResultStat is a query that found nothing in a wilaya. WilayaID is
+// empty for searches without a wilaya filter.
+type ZeroResultStat struct {
+	WilayaID string `json:"wilaya_id"`
+	Wilaya   string `json:"wilaya,omitempty"`
+	Query    string `json:"query"`
+	Searches int    `json:"searches"`
+}
+
+// DemandSupplyStat compares how often a service is searched with how many
+// published providers offer it. SearchesPerProvider is nil when none does.
+type DemandSupplyStat struct {
+	ServiceID           string   `json:"service_id"`
+	Label               string   `json:"label"`
+	Searches            int      `json:"searches"`
+	ZeroResults         int      `json:"zero_results"`
+	Providers           int      `json:"providers"`
+	SearchesPerProvider *float64 `json:"searches_per_provider"`
+}
+
+// SearchAnalytics is the platform-admin search report.
+type SearchAnalytics struct {
+	From           time.Time          `json:"from"`
+	To             time.Time          `json:"to"`
+	WilayaID       string             `json:"wilaya_id,omitempty"`
+	Searches       int                `json:"searches"`
+	ZeroResultRate float64            `json:"zero_result_rate"`
+	AvgLatencyMs   float64            `json:"avg_latency_ms"`
+	TopQueries     []QueryStat        `json:"top_queries"`
+	ZeroResults    []ZeroResultStat   `json:"zero_results"`
+	DemandSupply   []DemandSupplyStat `json:"demand_supply"`
+}
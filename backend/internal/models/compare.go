@@ -0,0 +1,37 @@
+package models
+
+// Comparison rows
+const (
+	CompareRowPrice      = "price"
+	CompareRowTurnaround = "turnaround"
+	CompareRowEquipment  = "equipment"
+	CompareRowDoctor     = "doctor"
+	CompareRowRating     = "rating"
+	CompareRowOpenHours  = "open_hours"
+	CompareRowDistance   = "distance"
+)
+
+// ComparisonCell is one provider's value in a row. Value is nil when the
+// provider does not offer the service or the value is unknown.
+type ComparisonCell struct {
+	ProviderID string      `json:"provider_id"`
+	Value      interface{} `json:"value"`
+	Best       bool        `json:"best,omitempty"`
+}
+
+// ComparisonRow compares one attribute across providers; Cells follow the
+// order of Comparison.Providers. Service rows carry their ServiceID.
+type ComparisonRow struct {
+	Key       string           `json:"key"`
+	Label     string           `json:"label"`
+	ServiceID string           `json:"service_id,omitempty"`
+	Cells     []ComparisonCell `json:"cells"`
+}
+
+// Comparison is the response of the provider comparison endpoint.
+// Providers are the same documents GET /api/providers/{id} returns.
+type Comparison struct {
+	Providers []Provider       `json:"providers"`
+	Services  []MedicalService `json:"services"`
+	Rows      []ComparisonRow  `json:"rows"`
+}
@@ -0,0 +1,41 @@
+package models
+
+// ICD-10 catalog sources
+const (
+	ICD10SourceSeed = "SEED"
+	ICD10SourceWHO  = "WHO"
+	ICD10SourceATIH = "ATIH" // CIM-10 FR à usage PMSI
+	ICD10SourceCSV  = "CSV"
+)
+
+// ICD10Code is an entry of the diagnosis catalog. Codes carry their dot
+// (J45.0); only billable codes, with nothing more specific below them, may
+// be recorded on patients.
+type ICD10Code struct {
+	Code       string  `json:"code"`
+	ParentCode *string `json:"parent_code,omitempty"`
+	LabelFr    string  `json:"label_fr"`
+	LabelAr    string  `json:"label_ar"`
+	LabelEn    string  `json:"label_en"`
+	Label      string  `json:"label,omitempty"` // label in the response language
+	IsBillable bool    `json:"is_billable"`
+	IsActive   bool    `json:"is_active"`
+}
+
+// ICD10Import is the outcome of a catalog import.
+type ICD10Import struct {
+	Source      string   `json:"source"`
+	Lang        string   `json:"lang,omitempty"`
+	Imported    int      `json:"imported"`
+	Deactivated int      `json:"deactivated"`
+	Skipped     int      `json:"skipped"`
+	SkippedAt   []string `json:"skipped_at"` // first unreadable lines, as "line N: content"
+}
+
+// VisitDiagnosis is a coded diagnosis of a visit.
+type VisitDiagnosis struct {
+	Code      string `json:"code"`
+	LabelFr   string `json:"label_fr"`
+	LabelAr   string `json:"label_ar"`
+	IsPrimary bool   `json:"is_primary"`
+}
@@ -0,0 +1,36 @@
+package models
+
+// Interaction severities. Major and contraindicated interactions block a
+// prescription unless overridden.
+const (
+	InteractionMinor           = "MINOR"
+	InteractionModerate        = "MODERATE"
+	InteractionMajor           = "MAJOR"
+	InteractionContraindicated = "CONTRAINDICATED"
+)
+
+// DrugInteraction is a local rule between two DCIs or drug classes, or
+// between one and a condition given by an ICD-10 code prefix.
+type DrugInteraction struct {
+	ID            string  `json:"id"`
+	SubstanceA    string  `json:"substance_a"`
+	SubstanceB    *string `json:"substance_b,omitempty"`
+	ConditionCode *string `json:"condition_code,omitempty"`
+	Severity      string  `json:"severity"`
+	DescriptionFr string  `json:"description_fr"`
+	DescriptionAr string  `json:"description_ar"`
+	DescriptionEn string  `json:"description_en"`
+}
+
+// DrugClass groups DCIs for allergies, rules and duplicate-therapy checks.
+type DrugClass struct {
+	Name           string   `json:"name"`
+	DuplicateCheck bool     `json:"duplicate_check"`
+	Members        []string `json:"members"`
+}
+
+// DrugRules is the content of the seed file of classes and interactions.
+type DrugRules struct {
+	Classes      []DrugClass       `json:"classes"`
+	Interactions []DrugInteraction `json:"interactions"`
+}
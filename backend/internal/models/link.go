@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Link statuses
+const (
+	LinkPending  = "PENDING"
+	LinkActive   = "ACTIVE"
+	LinkDeclined = "DECLINED"
+	LinkRevoked  = "REVOKED"
+)
+
+// How a link was made
+const (
+	LinkByProposal = "PROPOSAL"
+	LinkByCode     = "CODE"
+)
+
+// PatientLink ties a platform patient account to an organization's ERP
+// patient record.
+type PatientLink struct {
+	ID          string     `json:"id"`
+	OrgID       string     `json:"org_id"`
+	OrgName     string     `json:"org_name"`
+	PatientID   string     `json:"patient_id"`
+	PatientName string     `json:"patient_name"`
+	UserID      string     `json:"user_id"`
+	Method      string     `json:"method"`
+	MatchedBy   string     `json:"matched_by,omitempty"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+// LinkCode is a one-time code a patient shares at reception.
+type LinkCode struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// LinkPatientRequest links an ERP patient: with the patient's code, or, when
+// empty, by proposing a link to the account matching the record.
+type LinkPatientRequest struct {
+	Code string `json:"code"`
+}
+
+// LinkAccess is one audited event of a link.
+type LinkAccess struct {
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"` // "patient" or "organization"
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	ChangeClaim         = "CLAIM"
+	ChangeUpdate        = "UPDATE"
+	ChangeServiceAdd    = "SERVICE_ADD"
+	ChangeServiceUpdate = "SERVICE_UPDATE"
+	ChangeServiceDelete = "SERVICE_DELETE"
+
+	ChangePending  = "PENDING"
+	ChangeApproved = "APPROVED"
+	ChangeRejected = "REJECTED"
+)
+
+// ListingUpdate is a partial edit of a provider page. Nil fields are left unchanged.
+type ListingUpdate struct {
+	Name        *string  `json:"name,omitempty"`
+	NameEn      *string  `json:"nameEn,omitempty"`
+	Address     *string  `json:"address,omitempty"`
+	Phone       *string  `json:"phone,omitempty"`
+	OpenHours   *string  `json:"openHours,omitempty"`
+	Image       *string  `json:"image,omitempty"`
+	Images      []string `json:"images"` // nil keeps the current gallery, [] clears it
+	Latitude    *float64 `json:"latitude,omitempty"`
+	CommuneCode *string  `json:"communeCode,omitempty"` // "" clears it
+	Longitude   *float64 `json:"longitude,omitempty"`
+}
+
+// ListingClaim is the payload of a CLAIM change.
+type ListingClaim struct {
+	OrgID *string `json:"org_id,omitempty"`
+}
+
+// ListingChange is an owner-submitted change awaiting or past review.
+type ListingChange struct {
+	ID          string          `json:"id"`
+	ProviderID  string          `json:"provider_id"`
+	RequestedBy string          `json:"requested_by"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	ReviewNote  string          `json:"review_note,omitempty"`
+	ReviewedAt  *time.Time      `json:"reviewed_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+type ReviewChangeRequest struct {
+	Note string `json:"note,omitempty"`
+}
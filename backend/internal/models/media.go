@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Media is an uploaded image with its thumbnail.
+type Media struct {
+	ID           string    `json:"id"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Size         int       `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ListingImageUpload is the response of a listing image upload: the stored
+// image and the listing change that attaches it.
+type ListingImageUpload struct {
+	Media    Media  `json:"media"`
+	ChangeID string `json:"change_id"`
+	Status   string `json:"status"`
+}
@@ -0,0 +1,40 @@
+package models
+
+// Medication catalog sources
+const (
+	MedicationSourceSeed = "SEED"
+	MedicationSourceCSV  = "CSV" // national nomenclature export
+)
+
+// Medication is a registered presentation of the formulary: one brand in one
+// form and strength. Generics share the DCI of their reference brand.
+type Medication struct {
+	ID                 string   `json:"id"`
+	RegistrationNumber string   `json:"registration_number"`
+	DCI                string   `json:"dci"`
+	Brand              string   `json:"brand"`
+	Form               string   `json:"form,omitempty"`
+	Strength           string   `json:"strength,omitempty"`
+	Packaging          string   `json:"packaging,omitempty"`
+	Laboratory         string   `json:"laboratory,omitempty"`
+	Country            string   `json:"country,omitempty"`
+	IsGeneric          bool     `json:"is_generic"`
+	IsReimbursable     bool     `json:"is_reimbursable"`
+	ReferencePrice     *float64 `json:"reference_price,omitempty"` // DZD
+	IsActive           bool     `json:"is_active"`
+}
+
+// MedicationDCI is a DCI matched by name or by one of its brands.
+type MedicationDCI struct {
+	DCI    string   `json:"dci"`
+	Brands []string `json:"brands"` // first brands of the DCI, matching ones first
+	Count  int      `json:"count"`  // active presentations
+}
+
+// MedicationImport is the outcome of a formulary import.
+type MedicationImport struct {
+	Imported    int      `json:"imported"`
+	Deactivated int      `json:"deactivated"`
+	Skipped     int      `json:"skipped"`
+	SkippedAt   []string `json:"skipped_at"` // first unreadable rows, as "line N: content"
+}
@@ -0,0 +1,37 @@
+package models
+
+// PublishListingRequest overrides the organization fields copied into its
+// public listing. Empty fields fall back to the organization record.
+type PublishListingRequest struct {
+	WilayaID    string `json:"wilaya_id,omitempty"`
+	City        string `json:"city,omitempty"`
+	CommuneCode string `json:"commune_code,omitempty"`
+	Address     string `json:"address,omitempty"`
+	Phone       string `json:"phone,omitempty"`
+	OpenHours   string `json:"open_hours,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
+
+// OrgListing reports whether an organization is visible in search.
+type OrgListing struct {
+	ProviderID  string `json:"provider_id,omitempty"`
+	IsPublished bool   `json:"is_published"`
+	Services    int    `json:"services_count"`
+}
+
+// OrgPrice is a catalog act or lab test with the organization's own price.
+type OrgPrice struct {
+	Kind       string   `json:"kind"` // "act" or "lab_test"
+	Code       string   `json:"code"`
+	NameAr     string   `json:"name_ar"`
+	NameFr     string   `json:"name_fr"`
+	CategoryAr string   `json:"category_ar,omitempty"`
+	CategoryFr string   `json:"category_fr,omitempty"`
+	Label      string   `json:"label"` // name in the response language
+	BasePrice  float64  `json:"base_price"`
+	Price      *float64 `json:"price,omitempty"` // nil when the org does not offer it
+}
+
+type SetPriceRequest struct {
+	Price float64 `json:"price"`
+}
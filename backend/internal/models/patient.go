@@ -0,0 +1,109 @@
+package models
+
+import "time"
+
+// ERPPatient is a patient record of an organization (tenant). Dates are
+// "YYYY-MM-DD"; enum fields use the database values (gender: male/female,
+// insurance_type: CNAS/CASNOS/MUTUAL/PRIVATE/NONE...).
+type ERPPatient struct {
+	ID             string  `json:"id"`
+	PlatformUserID *string `json:"platform_user_id,omitempty"`
+
+	NIN           string `json:"nin,omitempty"`
+	ChifaNumber   string `json:"chifa_number,omitempty"`
+	FullNameAr    string `json:"full_name_ar"`
+	FullNameFr    string `json:"full_name_fr,omitempty"`
+	DateOfBirth   string `json:"date_of_birth,omitempty"`
+	Gender        string `json:"gender,omitempty"`
+	BloodType     string `json:"blood_type,omitempty"`
+	MaritalStatus string `json:"marital_status,omitempty"`
+
+	Phone          string `json:"phone,omitempty"`
+	PhoneSecondary string `json:"phone_secondary,omitempty"`
+	Email          string `json:"email,omitempty"`
+	Address        string `json:"address,omitempty"`
+	WilayaID       string `json:"wilaya_id,omitempty"`
+	CommuneCode    string `json:"commune_code,omitempty"`
+	City           string `json:"city,omitempty"`
+
+	InsuranceType        string `json:"insurance_type"`
+	InsuranceNumber      string `json:"insurance_number,omitempty"`
+	InsuranceHolderName  string `json:"insurance_holder_name,omitempty"`
+	InsuranceCoveragePct int    `json:"insurance_coverage_pct"`
+	IsALD                bool   `json:"is_ald"`
+
+	Allergies         string `json:"allergies,omitempty"`
+	ChronicConditions string `json:"chronic_conditions,omitempty"`
+	Notes             string `json:"notes,omitempty"`
+
+	EmergencyName     string `json:"emergency_name,omitempty"`
+	EmergencyPhone    string `json:"emergency_phone,omitempty"`
+	EmergencyRelation string `json:"emergency_relation,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ERPPatientInput creates or updates a patient. Nil fields are left
+// unchanged; an empty string clears an optional field.
+type ERPPatientInput struct {
+	NIN           *string `json:"nin"`
+	ChifaNumber   *string `json:"chifa_number"`
+	FullNameAr    *string `json:"full_name_ar"`
+	FullNameFr    *string `json:"full_name_fr"`
+	DateOfBirth   *string `json:"date_of_birth"`
+	Gender        *string `json:"gender"`
+	BloodType     *string `json:"blood_type"`
+	MaritalStatus *string `json:"marital_status"`
+
+	Phone          *string `json:"phone"`
+	PhoneSecondary *string `json:"phone_secondary"`
+	Email          *string `json:"email"`
+	Address        *string `json:"address"`
+	WilayaID       *string `json:"wilaya_id"`
+	CommuneCode    *string `json:"commune_code"`
+	City           *string `json:"city"`
+
+	InsuranceType        *string `json:"insurance_type"`
+	InsuranceNumber      *string `json:"insurance_number"`
+	InsuranceHolderName  *string `json:"insurance_holder_name"`
+	InsuranceCoveragePct *int    `json:"insurance_coverage_pct"`
+	IsALD                *bool   `json:"is_ald"`
+
+	Allergies         *string `json:"allergies"`
+	ChronicConditions *string `json:"chronic_conditions"`
+	Notes             *string `json:"notes"`
+
+	EmergencyName     *string `json:"emergency_name"`
+	EmergencyPhone    *string `json:"emergency_phone"`
+	EmergencyRelation *string `json:"emergency_relation"`
+}
+
+// Reasons a patient is reported as a possible duplicate.
+const (
+	DuplicateNIN         = "nin"
+	DuplicateChifa       = "chifa_number"
+	DuplicateDateOfBirth = "date_of_birth"
+	DuplicatePhone       = "phone"
+	DuplicateName        = "name"
+)
+
+// DuplicateCandidate is a patient that may be the same person as another.
+type DuplicateCandidate struct {
+	Patient ERPPatient `json:"patient"`
+	Score   int        `json:"score"` // 0–100
+	Reasons []string   `json:"reasons"`
+}
+
+// MergePatientsRequest merges source_id into the patient of the URL.
+type MergePatientsRequest struct {
+	SourceID string `json:"source_id"`
+}
+
+// PatientMerge is the surviving record and how many rows of each table were
+// moved to it from the merged one.
+type PatientMerge struct {
+	Patient  ERPPatient       `json:"patient"`
+	MergedID string           `json:"merged_id"`
+	Moved    map[string]int64 `json:"moved"`
+}
@@ -0,0 +1,123 @@
+package models
+
+import "time"
+
+// Lab result release rules
+const (
+	LabReleaseValidated = "VALIDATED" // VALIDATED and DELIVERED results
+	LabReleaseDelivered = "DELIVERED" // only results handed to the patient
+)
+
+// PortalSettings is what an organization shows to linked patients.
+type PortalSettings struct {
+	ShowAppointments  bool   `json:"show_appointments"`
+	ShowLabResults    bool   `json:"show_lab_results"`
+	LabRelease        string `json:"lab_release"`
+	ResultDelayHours  int    `json:"result_delay_hours"`
+	ShowPrescriptions bool   `json:"show_prescriptions"`
+	ShowDischarges    bool   `json:"show_discharges"`
+	ShowInvoices      bool   `json:"show_invoices"`
+}
+
+// PortalSettingsUpdate changes the fields that are set.
+type PortalSettingsUpdate struct {
+	ShowAppointments  *bool   `json:"show_appointments"`
+	ShowLabResults    *bool   `json:"show_lab_results"`
+	LabRelease        *string `json:"lab_release"`
+	ResultDelayHours  *int    `json:"result_delay_hours"`
+	ShowPrescriptions *bool   `json:"show_prescriptions"`
+	ShowDischarges    *bool   `json:"show_discharges"`
+	ShowInvoices      *bool   `json:"show_invoices"`
+}
+
+// PortalAppointment is an appointment at any linked organization.
+type PortalAppointment struct {
+	ID              string    `json:"id"`
+	OrgID           string    `json:"org_id"`
+	OrgName         string    `json:"org_name"`
+	ScheduledAt     time.Time `json:"scheduled_at"`
+	DurationMinutes int       `json:"duration_minutes"`
+	Status          string    `json:"status"`
+	Reason          string    `json:"reason,omitempty"`
+	Doctor          string    `json:"doctor,omitempty"`
+}
+
+// PortalLabOrder is a lab order with its released results.
+type PortalLabOrder struct {
+	ID          string            `json:"id"`
+	OrgID       string            `json:"org_id"`
+	OrgName     string            `json:"org_name"`
+	OrderNumber string            `json:"order_number"`
+	OrderedAt   time.Time         `json:"ordered_at"`
+	Results     []PortalLabResult `json:"results"`
+}
+
+type PortalLabResult struct {
+	TestCode    string     `json:"test_code"`
+	TestName    string     `json:"test_name"`
+	Value       string     `json:"value"`
+	Unit        string     `json:"unit,omitempty"`
+	NormalRange string     `json:"normal_range,omitempty"`
+	IsAbnormal  bool       `json:"is_abnormal"`
+	Status      string     `json:"status"`
+	ValidatedAt *time.Time `json:"validated_at,omitempty"`
+}
+
+// PortalPrescription is a prescription with its medications.
+type PortalPrescription struct {
+	ID      string                   `json:"id"`
+	OrgID   string                   `json:"org_id"`
+	OrgName string                   `json:"org_name"`
+	Date    string                   `json:"date"`
+	Doctor  string                   `json:"doctor,omitempty"`
+	Notes   string                   `json:"notes,omitempty"`
+	Items   []PortalPrescriptionItem `json:"items"`
+}
+
+type PortalPrescriptionItem struct {
+	MedicationName string `json:"medication_name"`
+	Dosage         string `json:"dosage,omitempty"`
+	Frequency      string `json:"frequency,omitempty"`
+	Duration       string `json:"duration,omitempty"`
+	Quantity       *int   `json:"quantity,omitempty"`
+	Instructions   string `json:"instructions,omitempty"`
+}
+
+// PortalDischarge is the discharge summary of a completed hospital stay.
+type PortalDischarge struct {
+	AdmissionID            string    `json:"admission_id"`
+	OrgID                  string    `json:"org_id"`
+	OrgName                string    `json:"org_name"`
+	AdmittedAt             time.Time `json:"admitted_at"`
+	DischargedAt           time.Time `json:"discharged_at"`
+	Doctor                 string    `json:"doctor,omitempty"`
+	Diagnosis              string    `json:"diagnosis,omitempty"`
+	DischargeSummary       string    `json:"discharge_summary,omitempty"`
+	DischargePrescriptions string    `json:"discharge_prescriptions,omitempty"`
+	FollowUpNotes          string    `json:"follow_up_notes,omitempty"`
+}
+
+// PortalInvoice is an issued invoice; drafts are never shown.
+type PortalInvoice struct {
+	ID             string              `json:"id"`
+	OrgID          string              `json:"org_id"`
+	OrgName        string              `json:"org_name"`
+	InvoiceNumber  string              `json:"invoice_number"`
+	Status         string              `json:"status"`
+	IssuedAt       time.Time           `json:"issued_at"`
+	DueAt          *time.Time          `json:"due_at,omitempty"`
+	Total          float64             `json:"total"`
+	CNASCoverage   float64             `json:"cnas_coverage"`
+	MutualCoverage float64             `json:"mutual_coverage"`
+	PatientAmount  float64             `json:"patient_amount"`
+	PaidAmount     float64             `json:"paid_amount"`
+	Remaining      float64             `json:"remaining"`
+	Items          []PortalInvoiceItem `json:"items"`
+}
+
+type PortalInvoiceItem struct {
+	Description string  `json:"description"`
+	Quantity    int     `json:"quantity"`
+	UnitPrice   float64 `json:"unit_price"`
+	Total       float64 `json:"total"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// Prescription is an ordonnance written for an ERP patient, usually during a
+// visit.
+type Prescription struct {
+	ID               string              `json:"id"`
+	PatientID        string              `json:"patient_id"`
+	VisitID          *string             `json:"visit_id,omitempty"`
+	DoctorID         *string             `json:"doctor_id,omitempty"`
+	DoctorName       string              `json:"doctor_name,omitempty"`
+	PrescriptionDate string              `json:"prescription_date"` // YYYY-MM-DD
+	Notes            string              `json:"notes,omitempty"`
+	RenewedFrom      *string             `json:"renewed_from,omitempty"`
+	VerificationCode string              `json:"verification_code"` // XXXX-XXXX-XXXX, printed as a QR code
+	Items            []PrescriptionItem  `json:"items"`
+	Alerts           []PrescriptionAlert `json:"alerts,omitempty"` // on creation only
+	CreatedAt        time.Time           `json:"created_at"`
+}
+
+// PrescriptionItem is one medication of a prescription. medication_id refers
+// to the formulary; medication_name is the printed text, filled from the
+// formulary when left empty.
+type PrescriptionItem struct {
+	MedicationID   *string `json:"medication_id,omitempty"`
+	DCI            string  `json:"dci,omitempty"` // from the formulary, read-only
+	MedicationName string  `json:"medication_name"`
+	Dosage         string  `json:"dosage,omitempty"`
+	Frequency      string  `json:"frequency,omitempty"`
+	Duration       string  `json:"duration,omitempty"`
+	Quantity       *int    `json:"quantity,omitempty"`
+	Instructions   string  `json:"instructions,omitempty"`
+}
+
+// PrescriptionInput writes a prescription from a visit. With renew_from and
+// no items, the medications of that prescription are prescribed again.
+type PrescriptionInput struct {
+	RenewFrom      *string            `json:"renew_from"`
+	Notes          *string            `json:"notes"`
+	Items          []PrescriptionItem `json:"items"`
+	OverrideReason *string            `json:"override_reason"` // required when blocking alerts are raised
+}
+
+// Prescription alert levels and types
+const (
+	AlertWarning  = "WARNING"
+	AlertBlocking = "BLOCKING"
+
+	AlertAllergy     = "ALLERGY"
+	AlertDuplicate   = "DUPLICATE"
+	AlertInteraction = "INTERACTION"
+	AlertCondition   = "CONDITION"
+)
+
+// PrescriptionAlert is a conflict found when prescribing, between a
+// medication and an allergy, another medication of the prescription or of
+// the patient's current treatment, or a condition.
+type PrescriptionAlert struct {
+	Level      string `json:"level"`
+	Type       string `json:"type"`
+	Medication string `json:"medication"`
+	With       string `json:"with"`
+	Severity   string `json:"severity,omitempty"` // of allergies and interaction rules
+	Message    string `json:"message"`
+}
+
+// PrescriptionVerification is what a pharmacist sees when checking the QR
+// code of an ordonnance: enough to match the paper, not the patient's record.
+type PrescriptionVerification struct {
+	VerificationCode string   `json:"verification_code"`
+	OrgName          string   `json:"org_name"`
+	Doctor           string   `json:"doctor,omitempty"`
+	PrescriptionDate string   `json:"prescription_date"`
+	PatientInitials  string   `json:"patient_initials"`
+	Medications      []string `json:"medications"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// PriceSummary aggregates provider_services prices for one group.
+type PriceSummary struct {
+	Key    string  `json:"key,omitempty"`   // wilaya ID or provider type
+	Label  string  `json:"label,omitempty"` // wilaya name
+	Min    int     `json:"min"`
+	Median float64 `json:"median"`
+	Max    int     `json:"max"`
+	Count  int     `json:"count"`
+}
+
+// CheapestOffer is one provider's price for the requested service.
+type CheapestOffer struct {
+	ProviderID string  `json:"provider_id"`
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Wilaya     string  `json:"wilaya"`
+	WilayaID   string  `json:"wilaya_id"`
+	Price      int     `json:"price"`
+	Rating     float64 `json:"rating"`
+}
+
+// PriceStats is the response of the price statistics endpoint.
+type PriceStats struct {
+	Service     MedicalService  `json:"service"`
+	Overall     PriceSummary    `json:"overall"`
+	ByWilaya    []PriceSummary  `json:"by_wilaya"`
+	ByType      []PriceSummary  `json:"by_type"`
+	Cheapest    []CheapestOffer `json:"cheapest"`
+	GeneratedAt time.Time       `json:"generated_at"`
+}
@@ -9,16 +9,24 @@ type Provider struct {
 	Wilaya       string            `json:"wilaya"`
 	WilayaID     string            `json:"wilayaId"`
 	City         string            `json:"city"`
+	CommuneCode  string            `json:"communeCode,omitempty"`
 	Address      string            `json:"address"`
 	Phone        string            `json:"phone"`
 	Rating       float64           `json:"rating"`
 	ReviewsCount int               `json:"reviewsCount"`
 	Image        string            `json:"image"`
+	Images       []string          `json:"images,omitempty"`
 	OpenHours    string            `json:"openHours"`
+	Latitude     *float64          `json:"latitude,omitempty"`
+	Longitude    *float64          `json:"longitude,omitempty"`
+	Label        string            `json:"label,omitempty"` // name in the response language
 	Services     []ProviderService `json:"services"`
+	Doctors      []Doctor          `json:"doctors,omitempty"`
+	Equipment    []Equipment       `json:"equipment,omitempty"`
 }
 
 type ProviderService struct {
+	ID         int        `json:"id,omitempty"`
 	ServiceID  string     `json:"serviceId"`
 	Name       string     `json:"name"`
 	Price      int        `json:"price"`
@@ -27,13 +35,20 @@ type ProviderService struct {
 	Equipment  *Equipment `json:"equipment,omitempty"`
 }
 
+// Doctor is a practitioner of a provider, shared by the services they perform.
+// Experience is the display form ("15 سنة"); ExperienceYears is what search
+// filters on and is derived from Experience when left at zero.
 type Doctor struct {
-	Name       string `json:"name"`
-	Specialty  string `json:"specialty"`
-	Experience string `json:"experience"`
+	ID              int    `json:"id,omitempty"`
+	Name            string `json:"name"`
+	Specialty       string `json:"specialty"`
+	Experience      string `json:"experience"`
+	ExperienceYears int    `json:"experienceYears"`
 }
 
+// Equipment is a machine of a provider, shared by the services that use it.
 type Equipment struct {
+	ID     int    `json:"id,omitempty"`
 	Name   string `json:"name"`
 	Type   string `json:"type"`
 	Origin string `json:"origin"`
@@ -44,18 +59,97 @@ type Wilaya struct {
 	ID     string `json:"id"`
 	Name   string `json:"name"`
 	ArName string `json:"ar_name"`
+	Label  string `json:"label,omitempty"` // name in the response language
+}
+
+// Daira is an administrative district of a wilaya. Its code is the code of
+// its chef-lieu commune; Communes is filled only when seeding.
+type Daira struct {
+	Code     string    `json:"code"`
+	WilayaID string    `json:"wilaya_id"`
+	Name     string    `json:"name"`
+	ArName   string    `json:"ar_name"`
+	Label    string    `json:"label,omitempty"` // name in the response language
+	Communes []Commune `json:"communes,omitempty"`
+}
+
+// Commune is a municipality, identified by its ONS code (e.g. "4001").
+type Commune struct {
+	Code      string `json:"code"`
+	DairaCode string `json:"daira_code"`
+	WilayaID  string `json:"wilaya_id"`
+	Name      string `json:"name"`
+	ArName    string `json:"ar_name"`
+	Label     string `json:"label,omitempty"` // name in the response language
 }
 
 // MedicalService represents a medical test or procedure.
+// Name is the Arabic display name; Category is the Arabic label of CategoryID.
+// Label is the name in the response language.
 type MedicalService struct {
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	Category string `json:"category"`
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	Category     string   `json:"category"`
+	NameFr       string   `json:"name_fr,omitempty"`
+	NameEn       string   `json:"name_en,omitempty"`
+	Label        string   `json:"label,omitempty"`
+	CategoryID   string   `json:"category_id,omitempty"`
+	Synonyms     []string `json:"synonyms,omitempty"`
+	NgapCodes    []string `json:"ngap_codes,omitempty"`
+	LabTestCodes []string `json:"lab_test_codes,omitempty"`
+}
+
+// ServiceCategory is a node of the service taxonomy tree.
+type ServiceCategory struct {
+	ID       string            `json:"id"`
+	ParentID *string           `json:"parent_id,omitempty"`
+	NameAr   string            `json:"name_ar"`
+	NameFr   string            `json:"name_fr"`
+	NameEn   string            `json:"name_en"`
+	Label    string            `json:"label,omitempty"` // name in the response language
+	Children []ServiceCategory `json:"children,omitempty"`
 }
 
-// SearchQuery holds parsed search parameters.
+// SearchQuery holds parsed search parameters. Slice fields are multi-value
+// filters: a provider matches if it matches any of the values.
 type SearchQuery struct {
-	Wilaya  string
-	Service string
-	SortBy  string // "rating", "price", "name"
+	Wilayas        []string // wilaya IDs or names
+	Communes       []string // commune codes
+	Types          []string // "clinic", "lab"
+	Service        string   // free text, resolved through names and synonyms
+	ServiceID      string
+	Categories     []string // include sub-categories
+	Specialties    []string // doctor specialty
+	MinExperience  int      // doctor experience in years
+	EquipmentTypes []string
+	Origins        []string // equipment origin
+	Prices         []string // price buckets, e.g. "1000-3000" or "10000-"
+	SortBy         string   // "rating", "price", "name"
+	Facets         bool
+	Lang           string // language of labels in results and facets
+}
+
+// FacetCount is the number of matching providers for one facet value.
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// SearchFacets groups facet counts. Each facet is counted with every filter
+// applied except its own, so selecting a value never hides its siblings.
+type SearchFacets struct {
+	Wilaya          []FacetCount `json:"wilaya"`
+	Type            []FacetCount `json:"type"`
+	Category        []FacetCount `json:"category"`
+	Specialty       []FacetCount `json:"specialty"`
+	EquipmentType   []FacetCount `json:"equipment_type"`
+	EquipmentOrigin []FacetCount `json:"equipment_origin"`
+	Price           []FacetCount `json:"price"`
+}
+
+// SearchResponse is returned instead of a bare list when facets are requested.
+type SearchResponse struct {
+	Results []Provider    `json:"results"`
+	Facets  *SearchFacets `json:"facets"`
 }
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+const (
+	ReviewPublished = "PUBLISHED"
+	ReviewHidden    = "HIDDEN"
+
+	ReportOpen      = "OPEN"
+	ReportResolved  = "RESOLVED"
+	ReportDismissed = "DISMISSED"
+)
+
+// Review is a patient's rating of a provider after a verified visit.
+type Review struct {
+	ID         string       `json:"id"`
+	ProviderID string       `json:"provider_id"`
+	AuthorName string       `json:"author_name"`
+	Rating     int          `json:"rating"`
+	Comment    string       `json:"comment,omitempty"`
+	VisitType  string       `json:"visit_type"` // "appointment" or "lab_result"
+	Status     string       `json:"status,omitempty"`
+	Reply      *ReviewReply `json:"reply,omitempty"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// ReviewReply is the provider's public answer to a review.
+type ReviewReply struct {
+	Text      string    `json:"text"`
+	RepliedAt time.Time `json:"replied_at"`
+}
+
+// ReviewReport is an abuse report filed against a review.
+type ReviewReport struct {
+	ID         string    `json:"id"`
+	ReviewID   string    `json:"review_id"`
+	ProviderID string    `json:"provider_id"`
+	ReporterID string    `json:"reporter_id"`
+	Reason     string    `json:"reason"`
+	Status     string    `json:"status"`
+	Review     Review    `json:"review"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Page wraps a slice of results with pagination metadata.
+type Page struct {
+	Items interface{} `json:"items"`
+	Page  int         `json:"page"`
+	Limit int         `json:"limit"`
+	Total int         `json:"total"`
+}
+
+// --- Request DTOs ---
+
+type CreateReviewRequest struct {
+	AppointmentID string `json:"appointment_id,omitempty"`
+	LabOrderID    string `json:"lab_order_id,omitempty"`
+	Rating        int    `json:"rating"`
+	Comment       string `json:"comment,omitempty"`
+}
+
+type ReplyReviewRequest struct {
+	Reply string `json:"reply"`
+}
+
+type ReportReviewRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ModerateReviewRequest struct {
+	Status string `json:"status"` // PUBLISHED or HIDDEN
+	Note   string `json:"note,omitempty"`
+}
+
+type ResolveReportRequest struct {
+	Status string `json:"status"` // RESOLVED or DISMISSED
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Saved search alert kinds
+const (
+	AlertNewProvider = "new_provider"
+	AlertLowerPrice  = "lower_price"
+)
+
+// Favorite is a provider bookmarked by a patient.
+type Favorite struct {
+	Provider  Provider  `json:"provider"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SavedSearch is a SearchProviders query kept by a patient. Query is the
+// search query string, e.g. "wilaya=16&service=FNS&price=0-1000".
+type SavedSearch struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	Query         string     `json:"query"`
+	Notify        bool       `json:"notify"`
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// SavedSearchInput creates or edits a saved search.
+type SavedSearchInput struct {
+	Name   *string `json:"name"`
+	Query  *string `json:"query"`
+	Notify *bool   `json:"notify"`
+}
+
+// SearchAlert is one match reported by the saved search alert job.
+// OldPrice is set for lower_price alerts.
+type SearchAlert struct {
+	Kind         string `json:"kind"`
+	ProviderID   string `json:"provider_id"`
+	ProviderName string `json:"provider_name"`
+	Price        int    `json:"price,omitempty"`
+	OldPrice     int    `json:"old_price,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// Suggestion types
+const (
+	SuggestProvider = "provider"
+	SuggestService  = "service"
+	SuggestCity     = "city"
+	SuggestWilaya   = "wilaya"
+)
+
+// Suggestion is one autocomplete entry. Text is the name or synonym that
+// matched the prefix; Label is the name in the response language.
+type Suggestion struct {
+	Type       string `json:"type"`
+	ID         string `json:"id,omitempty"` // provider, service, wilaya ID or commune code
+	Text       string `json:"text"`
+	Label      string `json:"label"`
+	WilayaID   string `json:"wilaya_id,omitempty"` // for providers and cities
+	Popularity int    `json:"popularity"`
+}
+
+// Suggestions groups suggestions by type, each ranked by popularity.
+type Suggestions struct {
+	Providers []Suggestion `json:"providers"`
+	Services  []Suggestion `json:"services"`
+	Cities    []Suggestion `json:"cities"`
+	Wilayas   []Suggestion `json:"wilayas"`
+}
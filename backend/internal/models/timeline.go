@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Timeline event types
+const (
+	TimelineVisit        = "VISIT"
+	TimelineLabOrder     = "LAB_ORDER"
+	TimelineAdmission    = "ADMISSION"
+	TimelineSurgery      = "SURGERY"
+	TimelinePrescription = "PRESCRIPTION"
+	TimelineAppointment  = "APPOINTMENT"
+	TimelineInvoice      = "INVOICE"
+)
+
+// TimelineEvent is one entry of a patient's clinical timeline. ID is the id
+// of the visit, lab order, admission... named by Type.
+type TimelineEvent struct {
+	Type    string    `json:"type"`
+	ID      string    `json:"id"`
+	At      time.Time `json:"at"`
+	Title   string    `json:"title"`
+	Summary string    `json:"summary,omitempty"`
+	Status  string    `json:"status,omitempty"`
+	Actor   string    `json:"actor,omitempty"` // doctor or surgeon
+}
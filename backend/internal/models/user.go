@@ -76,6 +76,7 @@ type RegisterPatientRequest struct {
 	Phone       string `json:"phone"`
 	DateOfBirth string `json:"date_of_birth,omitempty"`
 	Gender      string `json:"gender,omitempty"`
+	NIN         string `json:"nin,omitempty"` // lets clinics find the account to link records
 }
 
 type RegisterProfessionalRequest struct {
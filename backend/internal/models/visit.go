@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// Vital keys, as stored in medical_visits.vitals and nursing_notes.vitals
+const (
+	VitalSystolicBP  = "systolic_bp"  // mmHg
+	VitalDiastolicBP = "diastolic_bp" // mmHg
+	VitalHeartRate   = "heart_rate"   // beats/min
+	VitalTemperature = "temperature"  // °C
+	VitalSpO2        = "spo2"         // %
+	VitalWeight      = "weight_kg"
+	VitalHeight      = "height_cm"
+	VitalGlycemia    = "glycemia" // g/L
+	VitalBMI         = "bmi"      // derived from weight and height
+)
+
+// Vitals is the typed schema of the vitals JSONB columns. BMI is computed
+// on save and ignored on input.
+type Vitals struct {
+	SystolicBP  *int     `json:"systolic_bp,omitempty"`
+	DiastolicBP *int     `json:"diastolic_bp,omitempty"`
+	HeartRate   *int     `json:"heart_rate,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	SpO2        *int     `json:"spo2,omitempty"`
+	WeightKg    *float64 `json:"weight_kg,omitempty"`
+	HeightCm    *float64 `json:"height_cm,omitempty"`
+	Glycemia    *float64 `json:"glycemia,omitempty"`
+	BMI         *float64 `json:"bmi,omitempty"`
+}
+
+// MedicalVisit is a consultation of an ERP patient.
+type MedicalVisit struct {
+	ID             string           `json:"id"`
+	PatientID      string           `json:"patient_id"`
+	DoctorID       *string          `json:"doctor_id,omitempty"`
+	DoctorName     string           `json:"doctor_name,omitempty"`
+	DepartmentID   *string          `json:"department_id,omitempty"`
+	VisitDate      time.Time        `json:"visit_date"`
+	ChiefComplaint string           `json:"chief_complaint,omitempty"`
+	Diagnosis      string           `json:"diagnosis,omitempty"`
+	DiagnosisCode  string           `json:"diagnosis_code,omitempty"` // primary coded diagnosis
+	Diagnoses      []VisitDiagnosis `json:"diagnoses"`
+	TreatmentPlan  string           `json:"treatment_plan,omitempty"`
+	Notes          string           `json:"notes,omitempty"`
+	Vitals         *Vitals          `json:"vitals,omitempty"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      *time.Time       `json:"updated_at,omitempty"`
+}
+
+// MedicalVisitInput creates or amends a visit. Nil fields are left unchanged;
+// vitals and diagnosis codes, when present, replace the recorded ones as a
+// whole. The first diagnosis code is the primary one; diagnosis_code alone
+// sets a single diagnosis.
+type MedicalVisitInput struct {
+	DoctorID       *string    `json:"doctor_id"`
+	DepartmentID   *string    `json:"department_id"`
+	VisitDate      *time.Time `json:"visit_date"`
+	ChiefComplaint *string    `json:"chief_complaint"`
+	Diagnosis      *string    `json:"diagnosis"`
+	DiagnosisCode  *string    `json:"diagnosis_code"`
+	DiagnosisCodes *[]string  `json:"diagnosis_codes"`
+	TreatmentPlan  *string    `json:"treatment_plan"`
+	Notes          *string    `json:"notes"`
+	Vitals         *Vitals    `json:"vitals"`
+}
+
+// VitalPoint is one measurement of a trend.
+type VitalPoint struct {
+	At       time.Time `json:"at"`
+	Value    float64   `json:"value"`
+	Source   string    `json:"source"` // "visit" or "nursing_note"
+	SourceID string    `json:"source_id"`
+}
+
+// VitalTrend is the time series of one vital for a patient, oldest first.
+type VitalTrend struct {
+	PatientID string       `json:"patient_id"`
+	Vital     string       `json:"vital"`
+	Points    []VitalPoint `json:"points"`
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Message is one notification to a user. Data carries the structured
+// payload (e.g. the matches of a saved search) for channels that use it.
+type Message struct {
+	UserID  string      `json:"user_id"`
+	Email   string      `json:"email"`
+	Lang    string      `json:"lang"`
+	Kind    string      `json:"kind"`
+	Subject string      `json:"subject"`
+	Body    string      `json:"body"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Notifier delivers messages to users. Implementations must be safe for
+// concurrent use.
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// Log records in the server log that a message would have been sent; the
+// default when no channel is set up. Only the kind and the user ID are
+// written: addresses, subjects and bodies reveal what users search for.
+type Log struct{}
+
+func (Log) Notify(_ context.Context, m Message) error {
+	log.Printf("🔔 [%s] for user %s (not delivered: no notification channel)", m.Kind, m.UserID)
+	return nil
+}
+
+// Webhook POSTs each message as JSON, for a mailer or push relay to deliver.
+type Webhook struct {
+	URL    string
+	client *http.Client
+}
+
+func NewWebhook(url string) *Webhook {
+	return &Webhook{URL: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (h *Webhook) Notify(ctx context.Context, m Message) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("notify webhook: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Config points at an S3-compatible service (AWS S3, MinIO, R2...).
+type S3Config struct {
+	Endpoint  string // e.g. "https://s3.eu-west-3.amazonaws.com" or "http://localhost:9000"
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PublicURL serves objects without signing when the bucket is public
+	// (e.g. a CDN in front of it). Empty means every read is presigned.
+	PublicURL string
+}
+
+// S3 stores media in a bucket using path-style requests signed with AWS
+// Signature Version 4, so any S3-compatible server works as a stand-in.
+type S3 struct {
+	cfg    S3Config
+	client *http.Client
+}
+
+// signedURLTTL is how long presigned read URLs stay valid.
+const signedURLTTL = time.Hour
+
+func NewS3(cfg S3Config) *S3 {
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	cfg.PublicURL = strings.TrimRight(cfg.PublicURL, "/")
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &S3{cfg: cfg, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (s *S3) Put(ctx context.Context, key, contentType string, data []byte) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.ContentLength = int64(len(data))
+	s.sign(req, data, time.Now())
+	return s.do(req)
+}
+
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	if !ValidKey(key) {
+		return nil, ErrInvalidKey
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(req, nil, time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("s3 GET %s: %s: %s", req.URL.Path, resp.Status, bytes.TrimSpace(body))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if !ValidKey(key) {
+		return ErrInvalidKey
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	s.sign(req, nil, time.Now())
+	return s.do(req)
+}
+
+// Serve redirects to the public URL, or to a presigned one.
+func (s *S3) Serve(w http.ResponseWriter, r *http.Request, key string) {
+	if !ValidKey(key) {
+		http.NotFound(w, r)
+		return
+	}
+	if s.cfg.PublicURL != "" {
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		http.Redirect(w, r, s.cfg.PublicURL+"/"+escapePath(key), http.StatusFound)
+		return
+	}
+	// Clients may reuse the redirect while the signature is still valid
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(signedURLTTL.Seconds())/2))
+	http.Redirect(w, r, s.PresignGet(key, signedURLTTL, time.Now()), http.StatusFound)
+}
+
+// PresignGet returns a URL that reads key without credentials until ttl elapses.
+func (s *S3) PresignGet(key string, ttl time.Duration, now time.Time) string {
+	u, _ := url.Parse(s.objectURL(key))
+	amzDate, scope := s.scope(now)
+
+	q := url.Values{}
+	q.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	q.Set("X-Amz-Credential", s.cfg.AccessKey+"/"+scope)
+	q.Set("X-Amz-Date", amzDate)
+	q.Set("X-Amz-Expires", strconv.Itoa(int(ttl.Seconds())))
+	q.Set("X-Amz-SignedHeaders", "host")
+
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		canonicalQuery(q),
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	q.Set("X-Amz-Signature", s.signature(now, amzDate, scope, canonical))
+	u.RawQuery = canonicalQuery(q)
+	return u.String()
+}
+
+func (s *S3) objectURL(key string) string {
+	return s.cfg.Endpoint + "/" + escapePath(s.cfg.Bucket) + "/" + escapePath(key)
+}
+
+func (s *S3) do(req *http.Request) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// sign adds the Authorization header for a request with the given body.
+func (s *S3) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate, scope := s.scope(now)
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		headers["content-type"] = ct
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.cfg.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+s.signature(now, amzDate, scope, canonical))
+}
+
+// scope returns the request timestamp and credential scope.
+func (s *S3) scope(now time.Time) (amzDate, scope string) {
+	now = now.UTC()
+	return now.Format("20060102T150405Z"), now.Format("20060102") + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+func (s *S3) signature(now time.Time, amzDate, scope, canonicalRequest string) string {
+	sum := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), now.UTC().Format("20060102"))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery encodes query parameters sorted by name, as SigV4 requires.
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// escapePath encodes each segment of a key, keeping the slashes.
+func escapePath(p string) string {
+	return uriEncode(p, false)
+}
+
+// uriEncode percent-encodes everything except unreserved characters
+// (and "/" unless encodeSlash), per the SigV4 canonical form.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
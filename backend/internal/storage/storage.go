@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Storage keeps uploaded media. Keys are slash-separated relative paths
+// such as "providers/lab_001/3f9a.jpg".
+type Storage interface {
+	// Put stores data under key, replacing any existing object.
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	// Get reads the object, for server-side use such as printed documents.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the object; deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// Serve writes the object to w, or redirects to a public or signed URL.
+	Serve(w http.ResponseWriter, r *http.Request, key string)
+}
+
+// ErrInvalidKey is returned for keys that could escape the storage root.
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// ValidKey reports whether key is a clean relative path.
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+-- ClinicLab Reviews Migration
+-- Migration 004: patient reviews, provider replies, moderation
+
+-- ERP tenant backing a public listing (used to verify patient visits)
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS org_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
+
+-- One review per completed appointment or delivered lab order
+CREATE TABLE IF NOT EXISTS provider_reviews (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    provider_id VARCHAR(20) NOT NULL REFERENCES providers(id) ON DELETE CASCADE,
+    patient_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    -- The visit being reviewed (exactly one is set)
+    appointment_id UUID UNIQUE REFERENCES appointments(id) ON DELETE SET NULL,
+    lab_order_id UUID UNIQUE REFERENCES lab_orders(id) ON DELETE SET NULL,
+
+    rating INT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    comment TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'PUBLISHED', -- PUBLISHED, HIDDEN
+
+    -- Provider reply
+    reply TEXT,
+    replied_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    replied_at TIMESTAMP WITH TIME ZONE,
+
+    -- Platform moderation
+    moderated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    moderated_at TIMESTAMP WITH TIME ZONE,
+    moderation_note TEXT,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Abuse reports raised by users against a review
+CREATE TABLE IF NOT EXISTS review_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    review_id UUID NOT NULL REFERENCES provider_reviews(id) ON DELETE CASCADE,
+    reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'OPEN', -- OPEN, RESOLVED, DISMISSED
+    resolved_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(review_id, reporter_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_providers_org ON providers(org_id);
+CREATE INDEX IF NOT EXISTS idx_provider_reviews_provider ON provider_reviews(provider_id, status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_provider_reviews_patient ON provider_reviews(patient_user_id);
+CREATE INDEX IF NOT EXISTS idx_review_reports_status ON review_reports(status);
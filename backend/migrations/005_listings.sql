@@ -0,0 +1,28 @@
+-- ClinicLab Listings Migration
+-- Migration 005: provider self-service (claims, edits, approval queue)
+
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS images TEXT[] DEFAULT '{}';
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+-- Every owner-submitted change to a listing. When platform approval is
+-- enabled, rows stay PENDING until an admin applies or rejects them;
+-- otherwise they are applied immediately and stored as APPROVED.
+CREATE TABLE IF NOT EXISTS listing_changes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    provider_id VARCHAR(20) NOT NULL REFERENCES providers(id) ON DELETE CASCADE,
+    requested_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    kind VARCHAR(20) NOT NULL, -- CLAIM, UPDATE, SERVICE_ADD, SERVICE_UPDATE, SERVICE_DELETE
+    payload JSONB NOT NULL DEFAULT '{}',
+
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING', -- PENDING, APPROVED, REJECTED
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    review_note TEXT,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_providers_user ON providers(user_id);
+CREATE INDEX IF NOT EXISTS idx_listing_changes_provider ON listing_changes(provider_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_listing_changes_status ON listing_changes(status);
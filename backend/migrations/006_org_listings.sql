@@ -0,0 +1,81 @@
+-- ClinicLab Org Listings Migration
+-- Migration 006: publish ERP organizations as public providers
+
+-- Listings can be hidden from search without losing reviews
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS is_published BOOLEAN DEFAULT TRUE;
+
+-- Derived services: source = 'NGAP' or 'LAB_TEST', source_code = catalog code.
+-- Rows with a NULL source are entered by hand and never touched by the sync.
+ALTER TABLE provider_services ADD COLUMN IF NOT EXISTS source VARCHAR(20);
+ALTER TABLE provider_services ADD COLUMN IF NOT EXISTS source_code VARCHAR(20);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_provider_services_source
+    ON provider_services(provider_id, source, source_code);
+
+-- Per-org lab test prices (labs set their own prices, like org_act_prices)
+CREATE TABLE IF NOT EXISTS org_lab_test_prices (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    test_id UUID NOT NULL REFERENCES lab_tests_catalog(id) ON DELETE CASCADE,
+    custom_price NUMERIC(10,2) NOT NULL,
+    UNIQUE(org_id, test_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_org_act_prices_org ON org_act_prices(org_id);
+CREATE INDEX IF NOT EXISTS idx_org_lab_test_prices_org ON org_lab_test_prices(org_id);
+
+-- Rebuild the derived services of every published listing backed by an org
+CREATE OR REPLACE FUNCTION sync_org_provider_services(p_org_id UUID) RETURNS VOID AS $$
+BEGIN
+    INSERT INTO provider_services (provider_id, name, price, source, source_code)
+    SELECT p.id, a.name_ar || ' (' || a.name_fr || ')', ROUND(oap.custom_price)::INT, 'NGAP', a.code
+    FROM providers p
+    JOIN org_act_prices oap ON oap.org_id = p.org_id
+    JOIN ngap_acts a ON a.id = oap.ngap_act_id AND a.is_active
+    WHERE p.org_id = p_org_id AND p.is_published
+    ON CONFLICT (provider_id, source, source_code)
+    DO UPDATE SET name = EXCLUDED.name, price = EXCLUDED.price;
+
+    INSERT INTO provider_services (provider_id, name, price, source, source_code)
+    SELECT p.id, t.name_ar || ' (' || t.name_fr || ')', ROUND(olp.custom_price)::INT, 'LAB_TEST', t.code
+    FROM providers p
+    JOIN org_lab_test_prices olp ON olp.org_id = p.org_id
+    JOIN lab_tests_catalog t ON t.id = olp.test_id AND t.is_active
+    WHERE p.org_id = p_org_id AND p.is_published
+    ON CONFLICT (provider_id, source, source_code)
+    DO UPDATE SET name = EXCLUDED.name, price = EXCLUDED.price;
+
+    -- Drop derived services whose price was removed
+    DELETE FROM provider_services ps
+    USING providers p
+    WHERE ps.provider_id = p.id AND p.org_id = p_org_id AND ps.source IS NOT NULL
+      AND NOT EXISTS (
+          SELECT 1 FROM org_act_prices oap JOIN ngap_acts a ON a.id = oap.ngap_act_id AND a.is_active
+          WHERE ps.source = 'NGAP' AND oap.org_id = p_org_id AND a.code = ps.source_code)
+      AND NOT EXISTS (
+          SELECT 1 FROM org_lab_test_prices olp JOIN lab_tests_catalog t ON t.id = olp.test_id AND t.is_active
+          WHERE ps.source = 'LAB_TEST' AND olp.org_id = p_org_id AND t.code = ps.source_code);
+
+    UPDATE providers SET updated_at = NOW() WHERE org_id = p_org_id;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION trg_sync_org_provider_services() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        PERFORM sync_org_provider_services(OLD.org_id);
+    ELSE
+        PERFORM sync_org_provider_services(NEW.org_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS org_act_prices_sync ON org_act_prices;
+CREATE TRIGGER org_act_prices_sync
+    AFTER INSERT OR UPDATE OR DELETE ON org_act_prices
+    FOR EACH ROW EXECUTE FUNCTION trg_sync_org_provider_services();
+
+DROP TRIGGER IF EXISTS org_lab_test_prices_sync ON org_lab_test_prices;
+CREATE TRIGGER org_lab_test_prices_sync
+    AFTER INSERT OR UPDATE OR DELETE ON org_lab_test_prices
+    FOR EACH ROW EXECUTE FUNCTION trg_sync_org_provider_services();
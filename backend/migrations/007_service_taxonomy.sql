@@ -0,0 +1,262 @@
+-- ClinicLab Service Taxonomy Migration
+-- Migration 007: hierarchical, multilingual medical services with synonyms
+-- and mappings to the NGAP acts and lab test catalogs
+
+-- =====================================================
+-- CATEGORIES (tree via parent_id)
+-- =====================================================
+
+CREATE TABLE IF NOT EXISTS service_categories (
+    id VARCHAR(30) PRIMARY KEY,
+    parent_id VARCHAR(30) REFERENCES service_categories(id) ON DELETE CASCADE,
+    name_ar VARCHAR(100) NOT NULL,
+    name_fr VARCHAR(100) NOT NULL,
+    name_en VARCHAR(100) NOT NULL,
+    sort_order INT DEFAULT 0
+);
+
+-- medical_services.name stays the Arabic display name
+ALTER TABLE medical_services ADD COLUMN IF NOT EXISTS name_fr VARCHAR(255);
+ALTER TABLE medical_services ADD COLUMN IF NOT EXISTS name_en VARCHAR(255);
+ALTER TABLE medical_services ADD COLUMN IF NOT EXISTS category_id VARCHAR(30) REFERENCES service_categories(id);
+
+-- Search aliases in any language (abbreviations, spellings without accents)
+CREATE TABLE IF NOT EXISTS service_synonyms (
+    id SERIAL PRIMARY KEY,
+    service_id VARCHAR(20) NOT NULL REFERENCES medical_services(id) ON DELETE CASCADE,
+    lang VARCHAR(5) NOT NULL, -- ar, fr, en
+    term VARCHAR(255) NOT NULL,
+    UNIQUE(service_id, term)
+);
+
+-- Each catalog code maps to at most one public service
+CREATE TABLE IF NOT EXISTS service_code_mappings (
+    id SERIAL PRIMARY KEY,
+    service_id VARCHAR(20) NOT NULL REFERENCES medical_services(id) ON DELETE CASCADE,
+    ngap_code VARCHAR(20) UNIQUE REFERENCES ngap_acts(code) ON DELETE CASCADE,
+    lab_test_code VARCHAR(20) UNIQUE REFERENCES lab_tests_catalog(code) ON DELETE CASCADE,
+    CHECK ((ngap_code IS NULL) <> (lab_test_code IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_categories_parent ON service_categories(parent_id);
+CREATE INDEX IF NOT EXISTS idx_medical_services_category ON medical_services(category_id);
+CREATE INDEX IF NOT EXISTS idx_service_synonyms_term ON service_synonyms(LOWER(term));
+CREATE INDEX IF NOT EXISTS idx_service_code_mappings_service ON service_code_mappings(service_id);
+CREATE INDEX IF NOT EXISTS idx_provider_services_service ON provider_services(service_id);
+
+-- =====================================================
+-- SEED: categories
+-- =====================================================
+
+INSERT INTO service_categories (id, parent_id, name_ar, name_fr, name_en, sort_order) VALUES
+    ('lab', NULL, 'مختبر', 'Laboratoire', 'Laboratory', 1),
+    ('imaging', NULL, 'تصوير طبي', 'Imagerie médicale', 'Medical imaging', 2),
+    ('consult', NULL, 'عيادة', 'Consultations', 'Consultations', 3),
+    ('dental', NULL, 'عيادة أسنان', 'Médecine dentaire', 'Dental care', 4),
+    ('nursing', NULL, 'تمريض', 'Soins infirmiers', 'Nursing', 5),
+    ('explorations', NULL, 'استكشافات وظيفية', 'Explorations fonctionnelles', 'Functional tests', 6),
+    ('surgery', NULL, 'جراحة وتوليد', 'Chirurgie et obstétrique', 'Surgery and obstetrics', 7)
+ON CONFLICT (id) DO UPDATE SET parent_id = EXCLUDED.parent_id, name_ar = EXCLUDED.name_ar,
+    name_fr = EXCLUDED.name_fr, name_en = EXCLUDED.name_en, sort_order = EXCLUDED.sort_order;
+
+INSERT INTO service_categories (id, parent_id, name_ar, name_fr, name_en, sort_order) VALUES
+    ('lab_hema', 'lab', 'أمراض الدم', 'Hématologie', 'Hematology', 1),
+    ('lab_hemostasis', 'lab', 'تخثر', 'Hémostase', 'Hemostasis', 2),
+    ('lab_biochem', 'lab', 'كيمياء حيوية', 'Biochimie', 'Biochemistry', 3),
+    ('lab_hormones', 'lab', 'هرمونات', 'Hormonologie', 'Endocrinology', 4),
+    ('lab_immuno', 'lab', 'مناعة', 'Immunologie', 'Immunology', 5),
+    ('lab_bacterio', 'lab', 'جراثيم', 'Bactériologie', 'Microbiology', 6),
+    ('lab_tumor', 'lab', 'علامات ورمية', 'Marqueurs tumoraux', 'Tumor markers', 7),
+    ('consult_general', 'consult', 'طب عام', 'Médecine générale', 'General practice', 1),
+    ('consult_specialist', 'consult', 'طب متخصص', 'Médecine spécialisée', 'Specialist care', 2)
+ON CONFLICT (id) DO UPDATE SET parent_id = EXCLUDED.parent_id, name_ar = EXCLUDED.name_ar,
+    name_fr = EXCLUDED.name_fr, name_en = EXCLUDED.name_en, sort_order = EXCLUDED.sort_order;
+
+-- =====================================================
+-- SEED: services (same IDs as src/data/medical_services.json)
+-- =====================================================
+
+INSERT INTO medical_services (id, name, category, name_fr, name_en, category_id) VALUES
+    ('lab_001', 'تحليل دم شامل (FNS/CBC)', 'مختبر', 'Numération formule sanguine (FNS)', 'Complete blood count (CBC)', 'lab_hema'),
+    ('lab_002', 'تحليل السكر (Glycémie)', 'مختبر', 'Glycémie à jeun', 'Fasting blood glucose', 'lab_biochem'),
+    ('lab_003', 'تحليل السكر التراكمي (HbA1c)', 'مختبر', 'Hémoglobine glyquée (HbA1c)', 'Glycated hemoglobin (HbA1c)', 'lab_biochem'),
+    ('lab_004', 'تحليل الكوليسترول (Cholestérol)', 'مختبر', 'Cholestérol total', 'Total cholesterol', 'lab_biochem'),
+    ('lab_005', 'تحليل وظائف الكلى (Créatinine, Urée)', 'مختبر', 'Bilan rénal (créatinine, urée)', 'Kidney function (creatinine, urea)', 'lab_biochem'),
+    ('lab_006', 'تحليل وظائف الكبد (TGO, TGP)', 'مختبر', 'Bilan hépatique (TGO, TGP)', 'Liver function (AST, ALT)', 'lab_biochem'),
+    ('lab_007', 'تحليل الغدة الدرقية (TSH, T3, T4)', 'مختبر', 'Bilan thyroïdien (TSH, T3, T4)', 'Thyroid panel (TSH, T3, T4)', 'lab_hormones'),
+    ('lab_008', 'تحليل فيتامين د (Vitamine D)', 'مختبر', 'Vitamine D', 'Vitamin D', 'lab_hormones'),
+    ('lab_009', 'تحليل فقر الدم (Ferritine, Fer)', 'مختبر', 'Bilan martial (ferritine, fer)', 'Iron studies (ferritin, iron)', 'lab_hema'),
+    ('lab_010', 'تحليل البول (ECBU)', 'مختبر', 'Examen cytobactériologique des urines (ECBU)', 'Urine culture', 'lab_bacterio'),
+    ('lab_011', 'اختبار الحمل (B-HCG)', 'مختبر', 'Test de grossesse (β-HCG)', 'Pregnancy test (β-hCG)', 'lab_hormones'),
+    ('lab_012', 'تحليل فصيلة الدم (Groupage Sanguin)', 'مختبر', 'Groupage sanguin ABO/Rhésus', 'Blood typing (ABO/Rh)', 'lab_immuno'),
+    ('lab_013', 'تحليل سي آر بي (CRP)', 'مختبر', 'Protéine C réactive (CRP)', 'C-reactive protein (CRP)', 'lab_immuno'),
+    ('lab_014', 'تحليل تخثر الدم (TP/INR)', 'مختبر', 'Taux de prothrombine (TP/INR)', 'Prothrombin time (PT/INR)', 'lab_hemostasis'),
+    ('lab_015', 'سرعة التثفل (VS)', 'مختبر', 'Vitesse de sédimentation (VS)', 'Erythrocyte sedimentation rate (ESR)', 'lab_hema'),
+    ('lab_016', 'الدهون الثلاثية (Triglycérides)', 'مختبر', 'Triglycérides', 'Triglycerides', 'lab_biochem'),
+    ('lab_017', 'مستضد البروستاتا (PSA)', 'مختبر', 'PSA total', 'Prostate-specific antigen (PSA)', 'lab_tumor'),
+    ('img_001', 'أشعة سينية (Radio X-Ray)', 'تصوير طبي', 'Radiographie', 'X-ray', 'imaging'),
+    ('img_002', 'إيكوغراف (Echographie)', 'تصوير طبي', 'Échographie', 'Ultrasound', 'imaging'),
+    ('img_003', 'رنين مغناطيسي (IRM)', 'تصوير طبي', 'IRM', 'MRI', 'imaging'),
+    ('img_004', 'سكانير (Scanner / TDM)', 'تصوير طبي', 'Scanner (TDM)', 'CT scan', 'imaging'),
+    ('img_005', 'ماموغرافيا (Mammographie)', 'تصوير طبي', 'Mammographie', 'Mammography', 'imaging'),
+    ('cli_001', 'استشارة طب عام', 'عيادة', 'Consultation de médecine générale', 'General practitioner consultation', 'consult_general'),
+    ('cli_002', 'استشارة طب أطفال', 'عيادة', 'Consultation de pédiatrie', 'Pediatrics consultation', 'consult_specialist'),
+    ('cli_003', 'استشارة طب نساء وتوليد', 'عيادة', 'Consultation de gynécologie-obstétrique', 'Gynecology and obstetrics consultation', 'consult_specialist'),
+    ('cli_004', 'استشارة قلب وشرايين', 'عيادة', 'Consultation de cardiologie', 'Cardiology consultation', 'consult_specialist'),
+    ('cli_005', 'استشارة جلدية', 'عيادة', 'Consultation de dermatologie', 'Dermatology consultation', 'consult_specialist'),
+    ('cli_006', 'استشارة طب عيون', 'عيادة', 'Consultation d''ophtalmologie', 'Ophthalmology consultation', 'consult_specialist'),
+    ('cli_007', 'استشارة أنف وأذن وحنجرة (ORL)', 'عيادة', 'Consultation ORL', 'ENT consultation', 'consult_specialist'),
+    ('cli_008', 'استشارة عظام ومفاصل', 'عيادة', 'Consultation de rhumatologie et orthopédie', 'Orthopedics and rheumatology consultation', 'consult_specialist'),
+    ('cli_009', 'استشارة جهاز هضمي', 'عيادة', 'Consultation de gastro-entérologie', 'Gastroenterology consultation', 'consult_specialist'),
+    ('cli_010', 'استشارة غدد وسكري', 'عيادة', 'Consultation d''endocrinologie-diabétologie', 'Endocrinology and diabetes consultation', 'consult_specialist'),
+    ('cli_011', 'استشارة أعصاب', 'عيادة', 'Consultation de neurologie', 'Neurology consultation', 'consult_specialist'),
+    ('cli_012', 'استشارة طب نفسي', 'عيادة', 'Consultation de psychiatrie', 'Psychiatry consultation', 'consult_specialist'),
+    ('cli_013', 'تنظيف أسنان (Détartrage)', 'عيادة أسنان', 'Détartrage', 'Dental scaling', 'dental'),
+    ('cli_014', 'حشو أسنان', 'عيادة أسنان', 'Obturation dentaire', 'Dental filling', 'dental'),
+    ('cli_015', 'تقويم أسنان (ODF)', 'عيادة أسنان', 'Orthodontie (ODF)', 'Orthodontics', 'dental'),
+    ('cli_016', 'تبييض أسنان', 'عيادة أسنان', 'Blanchiment dentaire', 'Teeth whitening', 'dental'),
+    ('cli_017', 'قلع أسنان', 'عيادة أسنان', 'Extraction dentaire', 'Tooth extraction', 'dental'),
+    ('cli_018', 'استشارة متخصصة', 'عيادة', 'Consultation spécialisée', 'Specialist consultation', 'consult_specialist'),
+    ('nur_001', 'تغيير ضمادات', 'تمريض', 'Pansement', 'Wound dressing', 'nursing'),
+    ('nur_002', 'رعاية منزلية', 'تمريض', 'Soins à domicile', 'Home care', 'nursing'),
+    ('nur_003', 'حقن (Injections)', 'تمريض', 'Injections', 'Injections', 'nursing'),
+    ('exp_001', 'تخطيط القلب (ECG)', 'استكشافات وظيفية', 'Électrocardiogramme (ECG)', 'Electrocardiogram (ECG)', 'explorations'),
+    ('sur_001', 'ولادة طبيعية', 'جراحة وتوليد', 'Accouchement normal', 'Normal delivery', 'surgery'),
+    ('sur_002', 'عملية قيصرية', 'جراحة وتوليد', 'Césarienne', 'Cesarean section', 'surgery'),
+    ('sur_003', 'استئصال الزائدة الدودية', 'جراحة وتوليد', 'Appendicectomie', 'Appendectomy', 'surgery'),
+    ('sur_004', 'إصلاح الفتق', 'جراحة وتوليد', 'Cure de hernie', 'Hernia repair', 'surgery')
+ON CONFLICT (id) DO UPDATE SET name_fr = EXCLUDED.name_fr, name_en = EXCLUDED.name_en,
+    category_id = EXCLUDED.category_id;
+
+INSERT INTO service_synonyms (service_id, lang, term) VALUES
+    ('lab_001', 'fr', 'FNS'), ('lab_001', 'fr', 'NFS'), ('lab_001', 'en', 'CBC'),
+    ('lab_001', 'fr', 'hémogramme'), ('lab_001', 'fr', 'hemogramme'), ('lab_001', 'en', 'blood count'),
+    ('lab_001', 'ar', 'تحليل الدم'),
+    ('lab_002', 'fr', 'glycémie'), ('lab_002', 'fr', 'glycemie'), ('lab_002', 'en', 'glucose'),
+    ('lab_002', 'en', 'blood sugar'), ('lab_002', 'ar', 'السكري'),
+    ('lab_003', 'en', 'HbA1c'), ('lab_003', 'fr', 'hémoglobine glyquée'), ('lab_003', 'en', 'A1C'),
+    ('lab_004', 'fr', 'cholestérol'), ('lab_004', 'fr', 'cholesterol'), ('lab_004', 'fr', 'bilan lipidique'),
+    ('lab_004', 'en', 'lipid panel'),
+    ('lab_005', 'fr', 'créatinine'), ('lab_005', 'fr', 'creatinine'), ('lab_005', 'fr', 'urée'),
+    ('lab_005', 'fr', 'uree'), ('lab_005', 'en', 'kidney'), ('lab_005', 'ar', 'الكلى'),
+    ('lab_006', 'fr', 'TGO'), ('lab_006', 'fr', 'TGP'), ('lab_006', 'en', 'ASAT'), ('lab_006', 'en', 'ALAT'),
+    ('lab_006', 'fr', 'transaminases'), ('lab_006', 'ar', 'الكبد'),
+    ('lab_007', 'en', 'TSH'), ('lab_007', 'fr', 'thyroïde'), ('lab_007', 'fr', 'thyroide'), ('lab_007', 'ar', 'الغدة الدرقية'),
+    ('lab_008', 'fr', 'vitamine D'), ('lab_008', 'en', '25-OH'),
+    ('lab_009', 'fr', 'ferritine'), ('lab_009', 'fr', 'fer sérique'), ('lab_009', 'en', 'iron'), ('lab_009', 'ar', 'فقر الدم'),
+    ('lab_010', 'fr', 'ECBU'), ('lab_010', 'en', 'urinalysis'), ('lab_010', 'ar', 'البول'),
+    ('lab_011', 'fr', 'β-HCG'), ('lab_011', 'fr', 'BHCG'), ('lab_011', 'fr', 'test de grossesse'), ('lab_011', 'ar', 'الحمل'),
+    ('lab_012', 'fr', 'groupage'), ('lab_012', 'fr', 'groupe sanguin'), ('lab_012', 'en', 'blood type'),
+    ('lab_012', 'ar', 'فصيلة الدم'),
+    ('lab_013', 'en', 'CRP'),
+    ('lab_014', 'fr', 'TP'), ('lab_014', 'en', 'INR'), ('lab_014', 'fr', 'prothrombine'),
+    ('lab_015', 'fr', 'VS'), ('lab_015', 'en', 'ESR'),
+    ('lab_016', 'fr', 'triglycérides'), ('lab_016', 'fr', 'triglycerides'),
+    ('lab_017', 'en', 'PSA'),
+    ('img_001', 'fr', 'radio'), ('img_001', 'en', 'x-ray'), ('img_001', 'en', 'xray'), ('img_001', 'ar', 'راديو'),
+    ('img_002', 'fr', 'échographie'), ('img_002', 'fr', 'echographie'), ('img_002', 'fr', 'écho'),
+    ('img_002', 'fr', 'echo'), ('img_002', 'en', 'ultrasound'), ('img_002', 'ar', 'إيكو'), ('img_002', 'ar', 'ايكو'),
+    ('img_003', 'fr', 'IRM'), ('img_003', 'en', 'MRI'), ('img_003', 'ar', 'الرنين'),
+    ('img_004', 'fr', 'scanner'), ('img_004', 'fr', 'TDM'), ('img_004', 'en', 'CT'), ('img_004', 'ar', 'سكانير'),
+    ('img_005', 'fr', 'mammographie'), ('img_005', 'en', 'mammogram'),
+    ('cli_001', 'fr', 'généraliste'), ('cli_001', 'fr', 'generaliste'), ('cli_001', 'en', 'GP'),
+    ('cli_002', 'fr', 'pédiatre'), ('cli_002', 'fr', 'pediatre'), ('cli_002', 'en', 'pediatrician'),
+    ('cli_003', 'fr', 'gynécologue'), ('cli_003', 'fr', 'gynecologue'), ('cli_003', 'en', 'gynecologist'),
+    ('cli_004', 'fr', 'cardiologue'), ('cli_004', 'en', 'cardiologist'), ('cli_004', 'ar', 'القلب'),
+    ('cli_005', 'fr', 'dermatologue'), ('cli_005', 'en', 'dermatologist'),
+    ('cli_006', 'fr', 'ophtalmologue'), ('cli_006', 'en', 'ophthalmologist'), ('cli_006', 'ar', 'العيون'),
+    ('cli_007', 'fr', 'ORL'), ('cli_007', 'en', 'ENT'),
+    ('cli_008', 'fr', 'orthopédiste'), ('cli_008', 'fr', 'rhumatologue'), ('cli_008', 'en', 'orthopedist'),
+    ('cli_009', 'fr', 'gastro-entérologue'), ('cli_009', 'fr', 'gastro'), ('cli_009', 'en', 'gastroenterologist'),
+    ('cli_010', 'fr', 'endocrinologue'), ('cli_010', 'fr', 'diabétologue'), ('cli_010', 'en', 'endocrinologist'),
+    ('cli_011', 'fr', 'neurologue'), ('cli_011', 'en', 'neurologist'),
+    ('cli_012', 'fr', 'psychiatre'), ('cli_012', 'en', 'psychiatrist'),
+    ('cli_013', 'fr', 'détartrage'), ('cli_013', 'fr', 'detartrage'), ('cli_013', 'en', 'teeth cleaning'),
+    ('cli_014', 'fr', 'plombage'), ('cli_014', 'fr', 'carie'),
+    ('cli_015', 'fr', 'ODF'), ('cli_015', 'en', 'braces'),
+    ('cli_016', 'en', 'whitening'),
+    ('cli_017', 'fr', 'extraction'),
+    ('cli_018', 'fr', 'spécialiste'), ('cli_018', 'fr', 'specialiste'), ('cli_018', 'en', 'specialist'),
+    ('nur_001', 'fr', 'pansement'), ('nur_001', 'en', 'dressing'),
+    ('nur_002', 'fr', 'visite à domicile'), ('nur_002', 'en', 'home visit'),
+    ('nur_003', 'fr', 'piqûre'), ('nur_003', 'fr', 'piqure'), ('nur_003', 'en', 'injection'),
+    ('exp_001', 'fr', 'ECG'), ('exp_001', 'en', 'EKG'), ('exp_001', 'ar', 'تخطيط القلب'),
+    ('sur_001', 'fr', 'accouchement'), ('sur_001', 'en', 'childbirth'), ('sur_001', 'ar', 'ولادة'),
+    ('sur_002', 'fr', 'césarienne'), ('sur_002', 'fr', 'cesarienne'), ('sur_002', 'en', 'c-section'),
+    ('sur_003', 'fr', 'appendicite'), ('sur_003', 'en', 'appendix'),
+    ('sur_004', 'fr', 'hernie'), ('sur_004', 'en', 'hernia')
+ON CONFLICT (service_id, term) DO NOTHING;
+
+INSERT INTO service_code_mappings (service_id, ngap_code) VALUES
+    ('cli_001', 'C'), ('cli_018', 'CS'), ('nur_002', 'V'), ('exp_001', 'ECG'),
+    ('img_002', 'ECHO'), ('img_001', 'RX'), ('lab_001', 'FNS'), ('lab_002', 'GLY'),
+    ('lab_005', 'UREE'), ('lab_005', 'CREAT'), ('lab_004', 'TC'), ('lab_016', 'TG'),
+    ('sur_001', 'ACCOU_N'), ('sur_002', 'ACCOU_C'), ('sur_003', 'CHIR_APP'), ('sur_004', 'CHIR_HERN')
+ON CONFLICT (ngap_code) DO NOTHING;
+
+INSERT INTO service_code_mappings (service_id, lab_test_code) VALUES
+    ('lab_001', 'FNS'), ('lab_015', 'VS'), ('lab_014', 'TP'), ('lab_002', 'GLY'), ('lab_003', 'HBA1C'),
+    ('lab_005', 'UREE'), ('lab_005', 'CREAT'), ('lab_004', 'CHOL'), ('lab_016', 'TG'),
+    ('lab_006', 'SGOT'), ('lab_006', 'SGPT'), ('lab_010', 'ECBU'), ('lab_012', 'GS_RH'),
+    ('lab_013', 'CRP'), ('lab_007', 'TSH'), ('lab_011', 'BHCG'), ('lab_017', 'PSA')
+ON CONFLICT (lab_test_code) DO NOTHING;
+
+-- =====================================================
+-- PROVIDER SERVICES must reference the taxonomy
+-- =====================================================
+
+-- Backfill hand-entered rows whose name matches a catalog entry
+UPDATE provider_services ps SET service_id = ms.id
+FROM medical_services ms
+WHERE ps.service_id IS NULL AND (ps.name = ms.name OR ps.name = ms.name_fr);
+
+DO $$ BEGIN
+    IF EXISTS (SELECT 1 FROM provider_services WHERE service_id IS NULL) THEN
+        RAISE NOTICE 'provider_services has rows without service_id; NOT NULL constraint deferred';
+    ELSE
+        ALTER TABLE provider_services ALTER COLUMN service_id SET NOT NULL;
+    END IF;
+END $$;
+
+-- Derived org services now resolve their service_id through the mappings;
+-- catalog codes without a mapping are not published.
+CREATE OR REPLACE FUNCTION sync_org_provider_services(p_org_id UUID) RETURNS VOID AS $$
+BEGIN
+    INSERT INTO provider_services (provider_id, service_id, name, price, source, source_code)
+    SELECT p.id, m.service_id, a.name_ar || ' (' || a.name_fr || ')', ROUND(oap.custom_price)::INT, 'NGAP', a.code
+    FROM providers p
+    JOIN org_act_prices oap ON oap.org_id = p.org_id
+    JOIN ngap_acts a ON a.id = oap.ngap_act_id AND a.is_active
+    JOIN service_code_mappings m ON m.ngap_code = a.code
+    WHERE p.org_id = p_org_id AND p.is_published
+    ON CONFLICT (provider_id, source, source_code)
+    DO UPDATE SET service_id = EXCLUDED.service_id, name = EXCLUDED.name, price = EXCLUDED.price;
+
+    INSERT INTO provider_services (provider_id, service_id, name, price, source, source_code)
+    SELECT p.id, m.service_id, t.name_ar || ' (' || t.name_fr || ')', ROUND(olp.custom_price)::INT, 'LAB_TEST', t.code
+    FROM providers p
+    JOIN org_lab_test_prices olp ON olp.org_id = p.org_id
+    JOIN lab_tests_catalog t ON t.id = olp.test_id AND t.is_active
+    JOIN service_code_mappings m ON m.lab_test_code = t.code
+    WHERE p.org_id = p_org_id AND p.is_published
+    ON CONFLICT (provider_id, source, source_code)
+    DO UPDATE SET service_id = EXCLUDED.service_id, name = EXCLUDED.name, price = EXCLUDED.price;
+
+    -- Drop derived services whose price (or mapping) was removed
+    DELETE FROM provider_services ps
+    USING providers p
+    WHERE ps.provider_id = p.id AND p.org_id = p_org_id AND ps.source IS NOT NULL
+      AND NOT EXISTS (
+          SELECT 1 FROM org_act_prices oap
+          JOIN ngap_acts a ON a.id = oap.ngap_act_id AND a.is_active
+          JOIN service_code_mappings m ON m.ngap_code = a.code
+          WHERE ps.source = 'NGAP' AND oap.org_id = p_org_id AND a.code = ps.source_code)
+      AND NOT EXISTS (
+          SELECT 1 FROM org_lab_test_prices olp
+          JOIN lab_tests_catalog t ON t.id = olp.test_id AND t.is_active
+          JOIN service_code_mappings m ON m.lab_test_code = t.code
+          WHERE ps.source = 'LAB_TEST' AND olp.org_id = p_org_id AND t.code = ps.source_code);
+
+    UPDATE providers SET updated_at = NOW() WHERE org_id = p_org_id;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,66 @@
+-- ClinicLab Doctors & Equipment Migration
+-- Migration 008: structured doctor and equipment entities per provider
+
+CREATE TABLE IF NOT EXISTS provider_doctors (
+    id SERIAL PRIMARY KEY,
+    provider_id VARCHAR(20) NOT NULL REFERENCES providers(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    specialty VARCHAR(100),
+    experience_years INT CHECK (experience_years >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(provider_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS provider_equipment (
+    id SERIAL PRIMARY KEY,
+    provider_id VARCHAR(20) NOT NULL REFERENCES providers(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(100),
+    origin VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(provider_id, name)
+);
+
+ALTER TABLE provider_services ADD COLUMN IF NOT EXISTS doctor_id INT REFERENCES provider_doctors(id) ON DELETE SET NULL;
+ALTER TABLE provider_services ADD COLUMN IF NOT EXISTS equipment_id INT REFERENCES provider_equipment(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_provider_doctors_specialty ON provider_doctors(specialty);
+CREATE INDEX IF NOT EXISTS idx_provider_equipment_type ON provider_equipment(type);
+CREATE INDEX IF NOT EXISTS idx_provider_equipment_origin ON provider_equipment(origin);
+
+-- Move the flat doctor_* / equipment_* columns into the new tables, then drop
+-- them. 001_init recreates them on a fresh database, so this runs once there too.
+DO $$ BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'provider_services' AND column_name = 'doctor_name') THEN
+
+        INSERT INTO provider_doctors (provider_id, name, specialty, experience_years)
+        SELECT DISTINCT ON (provider_id, doctor_name)
+               provider_id, doctor_name, NULLIF(doctor_specialty, ''),
+               NULLIF(substring(doctor_experience FROM '[0-9]+'), '')::INT
+        FROM provider_services
+        WHERE COALESCE(doctor_name, '') <> ''
+        ORDER BY provider_id, doctor_name, id
+        ON CONFLICT (provider_id, name) DO NOTHING;
+
+        INSERT INTO provider_equipment (provider_id, name, type, origin)
+        SELECT DISTINCT ON (provider_id, equipment_name)
+               provider_id, equipment_name, NULLIF(equipment_type, ''), NULLIF(equipment_origin, '')
+        FROM provider_services
+        WHERE COALESCE(equipment_name, '') <> ''
+        ORDER BY provider_id, equipment_name, id
+        ON CONFLICT (provider_id, name) DO NOTHING;
+
+        UPDATE provider_services ps SET doctor_id = d.id
+        FROM provider_doctors d
+        WHERE d.provider_id = ps.provider_id AND d.name = ps.doctor_name;
+
+        UPDATE provider_services ps SET equipment_id = e.id
+        FROM provider_equipment e
+        WHERE e.provider_id = ps.provider_id AND e.name = ps.equipment_name;
+
+        ALTER TABLE provider_services
+            DROP COLUMN doctor_name, DROP COLUMN doctor_specialty, DROP COLUMN doctor_experience,
+            DROP COLUMN equipment_name, DROP COLUMN equipment_type, DROP COLUMN equipment_origin;
+    END IF;
+END $$;
@@ -0,0 +1,23 @@
+-- ClinicLab Media Migration
+-- Migration 009: uploaded images for provider listings and organization logos
+
+-- One row per upload. storage_key is relative to the configured backend
+-- (local directory or S3 bucket); public URLs are /media/<storage_key>.
+CREATE TABLE IF NOT EXISTS media (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    owner_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    provider_id VARCHAR(20) REFERENCES providers(id) ON DELETE CASCADE,
+    org_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+
+    storage_key TEXT NOT NULL UNIQUE,
+    thumbnail_key TEXT,
+    content_type VARCHAR(50) NOT NULL,
+    size_bytes INT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_provider ON media(provider_id);
+CREATE INDEX IF NOT EXISTS idx_media_org ON media(org_id);
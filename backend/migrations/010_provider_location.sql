@@ -0,0 +1,5 @@
+-- ClinicLab Provider Location Migration
+-- Migration 010: optional coordinates for distance in provider comparison
+
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION;
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
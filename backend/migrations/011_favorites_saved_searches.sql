@@ -0,0 +1,32 @@
+-- ClinicLab Favorites & Saved Searches Migration
+-- Migration 011: patient favorites, saved searches and their alert baseline
+
+CREATE TABLE IF NOT EXISTS patient_favorites (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider_id VARCHAR(20) NOT NULL REFERENCES providers(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, provider_id)
+);
+
+-- query is the SearchProviders query string (wilaya=16&service=FNS&price=0-1000)
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    query TEXT NOT NULL,
+    lang VARCHAR(5) NOT NULL DEFAULT 'ar',
+    notify BOOLEAN NOT NULL DEFAULT TRUE,
+    last_checked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_searches_user ON saved_searches(user_id);
+
+-- What a saved search matched at its last check: the alert job reports
+-- providers missing here (new) and prices below min_price (cheaper).
+CREATE TABLE IF NOT EXISTS saved_search_matches (
+    saved_search_id UUID NOT NULL REFERENCES saved_searches(id) ON DELETE CASCADE,
+    provider_id VARCHAR(20) NOT NULL REFERENCES providers(id) ON DELETE CASCADE,
+    min_price INT,
+    PRIMARY KEY (saved_search_id, provider_id)
+);
@@ -0,0 +1,17 @@
+-- ClinicLab Search Analytics Migration
+-- Migration 012: anonymized log of provider searches
+
+-- No user, IP or session is kept. term is the free-text service query with
+-- anything that looks like an email or phone number masked.
+CREATE TABLE IF NOT EXISTS search_events (
+    id BIGSERIAL PRIMARY KEY,
+    searched_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    term TEXT,
+    service_ids TEXT[] NOT NULL DEFAULT '{}', -- taxonomy IDs the service filter resolved to
+    wilaya_ids TEXT[] NOT NULL DEFAULT '{}',
+    filters JSONB NOT NULL DEFAULT '{}',     -- every other filter (type, category, price...)
+    result_count INT NOT NULL,
+    latency_ms INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_events_time ON search_events(searched_at);
@@ -0,0 +1,30 @@
+-- ClinicLab Communes Migration
+-- Migration 013: daïras and communes, referenced by providers, organizations and ERP patients
+
+-- Communes use their ONS code (wilaya code + 2 digits, e.g. 4001). A daïra
+-- has no code of its own and takes the code of its chef-lieu commune.
+CREATE TABLE IF NOT EXISTS dairas (
+    code VARCHAR(6) PRIMARY KEY,
+    wilaya_id VARCHAR(5) NOT NULL REFERENCES wilayas(id),
+    name VARCHAR(100) NOT NULL,
+    ar_name VARCHAR(100) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS communes (
+    code VARCHAR(6) PRIMARY KEY,
+    daira_code VARCHAR(6) NOT NULL REFERENCES dairas(code),
+    wilaya_id VARCHAR(5) NOT NULL REFERENCES wilayas(id),
+    name VARCHAR(100) NOT NULL,
+    ar_name VARCHAR(100) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_dairas_wilaya ON dairas(wilaya_id);
+CREATE INDEX IF NOT EXISTS idx_communes_wilaya ON communes(wilaya_id);
+CREATE INDEX IF NOT EXISTS idx_communes_daira ON communes(daira_code);
+
+-- city stays as free text (neighbourhood, landmark); commune_code is the reference
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS commune_code VARCHAR(6) REFERENCES communes(code) ON DELETE SET NULL;
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS commune_code VARCHAR(6) REFERENCES communes(code) ON DELETE SET NULL;
+ALTER TABLE erp_patients ADD COLUMN IF NOT EXISTS commune_code VARCHAR(6) REFERENCES communes(code) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_providers_commune ON providers(commune_code);
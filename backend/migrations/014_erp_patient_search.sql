@@ -0,0 +1,11 @@
+-- ClinicLab ERP Patients Migration
+-- Migration 014: indexes for the patient registry lookups (name, phone, NIN, Chifa)
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_erp_patients_org_nin ON erp_patients(org_id, nin);
+CREATE INDEX IF NOT EXISTS idx_erp_patients_org_chifa ON erp_patients(org_id, chifa_number);
+CREATE INDEX IF NOT EXISTS idx_erp_patients_org_phone ON erp_patients(org_id, phone);
+CREATE INDEX IF NOT EXISTS idx_erp_patients_org_updated ON erp_patients(org_id, updated_at DESC);
+CREATE INDEX IF NOT EXISTS idx_erp_patients_name_ar_trgm ON erp_patients USING gin (full_name_ar gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_erp_patients_name_fr_trgm ON erp_patients USING gin (full_name_fr gin_trgm_ops);
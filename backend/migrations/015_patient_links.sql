@@ -0,0 +1,40 @@
+-- ClinicLab Patient Links Migration
+-- Migration 015: consent-based links between platform patient accounts and ERP patient records
+
+-- Lets a clinic find the account of a patient by NIN
+ALTER TABLE profiles_patient ADD COLUMN IF NOT EXISTS nin VARCHAR(30);
+CREATE INDEX IF NOT EXISTS idx_profiles_patient_nin ON profiles_patient(nin);
+
+-- A link is PENDING until the patient confirms a clinic's proposal, or
+-- ACTIVE at once when the patient handed a one-time code at reception.
+-- erp_patients.platform_user_id mirrors the ACTIVE link.
+CREATE TABLE IF NOT EXISTS patient_links (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    patient_id UUID NOT NULL REFERENCES erp_patients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    method VARCHAR(20) NOT NULL,              -- PROPOSAL | CODE
+    matched_by VARCHAR(20),                   -- nin | phone | email (proposals)
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING', -- PENDING | ACTIVE | DECLINED | REVOKED
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    responded_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    revoked_by UUID REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- One open link per record, and per account within an organization
+CREATE UNIQUE INDEX IF NOT EXISTS idx_patient_links_open_patient
+    ON patient_links(patient_id) WHERE status IN ('PENDING', 'ACTIVE');
+CREATE UNIQUE INDEX IF NOT EXISTS idx_patient_links_open_user
+    ON patient_links(org_id, user_id) WHERE status IN ('PENDING', 'ACTIVE');
+CREATE INDEX IF NOT EXISTS idx_patient_links_user ON patient_links(user_id, status);
+
+-- Codes a patient shows at reception; only the SHA-256 is stored
+CREATE TABLE IF NOT EXISTS patient_link_codes (
+    code_hash VARCHAR(64) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX IF NOT EXISTS idx_patient_link_codes_user ON patient_link_codes(user_id);
@@ -0,0 +1,22 @@
+-- ClinicLab Patient Portal Migration
+-- Migration 016: what each organization releases to linked patients
+
+-- One row per organization; an organization without a row uses the defaults.
+-- lab_release: VALIDATED shows VALIDATED and DELIVERED results, DELIVERED
+-- only results handed to the patient. result_delay_hours holds results back
+-- after validation so the doctor can call first.
+CREATE TABLE IF NOT EXISTS org_portal_settings (
+    org_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    show_appointments BOOLEAN NOT NULL DEFAULT TRUE,
+    show_lab_results BOOLEAN NOT NULL DEFAULT TRUE,
+    lab_release VARCHAR(20) NOT NULL DEFAULT 'VALIDATED',
+    result_delay_hours INT NOT NULL DEFAULT 0,
+    show_prescriptions BOOLEAN NOT NULL DEFAULT TRUE,
+    show_discharges BOOLEAN NOT NULL DEFAULT TRUE,
+    show_invoices BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_lab_order_items_order ON lab_order_items(lab_order_id);
+CREATE INDEX IF NOT EXISTS idx_prescription_items_prescription ON prescription_items(prescription_id);
+CREATE INDEX IF NOT EXISTS idx_invoice_items_invoice ON invoice_items(invoice_id);
@@ -0,0 +1,7 @@
+-- ClinicLab Medical Visits Migration
+-- Migration 017: amendment tracking and indexes for vitals trends
+
+ALTER TABLE medical_visits ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_medical_visits_patient_date ON medical_visits(patient_id, visit_date DESC);
+CREATE INDEX IF NOT EXISTS idx_nursing_notes_admission ON nursing_notes(admission_id, note_time);
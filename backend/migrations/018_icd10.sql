@@ -0,0 +1,46 @@
+-- ClinicLab ICD-10 Migration
+-- Migration 018: ICD-10 diagnosis catalog, coded diagnoses of visits,
+-- admissions and surgeries
+
+-- Codes are stored with their dot (J45.0). The catalog is seeded with a
+-- sample and replaced by the full WHO or ATIH (CIM-10 FR) file through the
+-- admin import. Codes are deactivated, never deleted, so old records keep
+-- their meaning.
+CREATE TABLE IF NOT EXISTS icd10_codes (
+    code VARCHAR(10) PRIMARY KEY,
+    parent_code VARCHAR(10),
+    label_fr TEXT,
+    label_ar TEXT,
+    label_en TEXT,
+    is_billable BOOLEAN NOT NULL DEFAULT TRUE, -- no more specific code below it
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    source VARCHAR(10) NOT NULL DEFAULT 'SEED', -- SEED, WHO, ATIH, CSV
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_icd10_codes_parent ON icd10_codes(parent_code);
+CREATE INDEX IF NOT EXISTS idx_icd10_codes_code_prefix ON icd10_codes(code varchar_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_icd10_codes_label_fr_trgm ON icd10_codes USING gin (label_fr gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_icd10_codes_label_ar_trgm ON icd10_codes USING gin (label_ar gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_icd10_codes_label_en_trgm ON icd10_codes USING gin (label_en gin_trgm_ops);
+
+-- A visit has any number of coded diagnoses; the primary one is also kept
+-- in medical_visits.diagnosis_code
+CREATE TABLE IF NOT EXISTS visit_diagnoses (
+    visit_id UUID NOT NULL REFERENCES medical_visits(id) ON DELETE CASCADE,
+    code VARCHAR(10) NOT NULL REFERENCES icd10_codes(code),
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    position INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (visit_id, code)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_visit_diagnoses_primary ON visit_diagnoses(visit_id) WHERE is_primary;
+CREATE INDEX IF NOT EXISTS idx_visit_diagnoses_code ON visit_diagnoses(code);
+
+-- admissions.diagnosis stays the free-text summary; the code is the reference
+ALTER TABLE admissions ADD COLUMN IF NOT EXISTS diagnosis_code VARCHAR(10) REFERENCES icd10_codes(code);
+ALTER TABLE surgeries ADD COLUMN IF NOT EXISTS diagnosis_code VARCHAR(10) REFERENCES icd10_codes(code);
+
+CREATE INDEX IF NOT EXISTS idx_medical_visits_diagnosis_code ON medical_visits(diagnosis_code);
+CREATE INDEX IF NOT EXISTS idx_admissions_diagnosis_code ON admissions(diagnosis_code);
+CREATE INDEX IF NOT EXISTS idx_surgeries_diagnosis_code ON surgeries(diagnosis_code);
@@ -0,0 +1,11 @@
+-- ClinicLab Prescriptions Migration
+-- Migration 019: renewals, item order and verification codes of prescriptions
+
+-- verification_code is printed as a QR code on the ordonnance; pharmacists
+-- check it at /api/prescriptions/verify/<code>
+ALTER TABLE prescriptions ADD COLUMN IF NOT EXISTS renewed_from UUID REFERENCES prescriptions(id) ON DELETE SET NULL;
+ALTER TABLE prescriptions ADD COLUMN IF NOT EXISTS verification_code VARCHAR(16) UNIQUE;
+ALTER TABLE prescription_items ADD COLUMN IF NOT EXISTS position INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_prescriptions_patient_date ON prescriptions(patient_id, prescription_date DESC);
+CREATE INDEX IF NOT EXISTS idx_prescriptions_visit ON prescriptions(visit_id);
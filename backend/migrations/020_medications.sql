@@ -0,0 +1,37 @@
+-- ClinicLab Medications Migration
+-- Migration 020: medication formulary from the national nomenclature, and
+-- formulary references of prescription items
+
+-- One row per registered presentation (brand, form, strength), keyed by its
+-- registration number. The catalog is seeded with a sample and replaced by
+-- the nomenclature of the Ministry of Health through the admin import.
+-- Presentations withdrawn from the nomenclature are deactivated, never
+-- deleted, so old prescriptions keep their reference.
+CREATE TABLE IF NOT EXISTS medications (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    registration_number VARCHAR(50) NOT NULL UNIQUE,
+    dci VARCHAR(255) NOT NULL,               -- dénomination commune internationale
+    brand VARCHAR(255) NOT NULL,
+    form VARCHAR(100),
+    strength VARCHAR(100),
+    packaging VARCHAR(100),
+    laboratory VARCHAR(255),
+    country VARCHAR(100),
+    is_generic BOOLEAN NOT NULL DEFAULT FALSE,
+    is_reimbursable BOOLEAN NOT NULL DEFAULT FALSE, -- CNAS list
+    reference_price NUMERIC(12,2),           -- tarif de référence, DZD
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    source VARCHAR(10) NOT NULL DEFAULT 'SEED', -- SEED, CSV
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_medications_dci ON medications(lower(dci));
+CREATE INDEX IF NOT EXISTS idx_medications_brand_prefix ON medications(lower(brand) varchar_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_medications_dci_trgm ON medications USING gin (dci gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_medications_brand_trgm ON medications USING gin (brand gin_trgm_ops);
+
+-- medication_name stays the printed text, and the only one for medications
+-- outside the formulary
+ALTER TABLE prescription_items ADD COLUMN IF NOT EXISTS medication_id UUID REFERENCES medications(id);
+
+CREATE INDEX IF NOT EXISTS idx_prescription_items_medication ON prescription_items(medication_id);
@@ -0,0 +1,69 @@
+-- ClinicLab Prescribing Safety Migration
+-- Migration 021: structured allergies and conditions of patients, drug
+-- classes and interaction rules checked when prescribing
+
+-- erp_patients.allergies and chronic_conditions stay as free-text notes; the
+-- checks only read these entries. Substances are stored in upper case without
+-- accents, like the DCIs of the formulary, and may name a DCI or a class
+-- (PENICILLINES).
+CREATE TABLE IF NOT EXISTS patient_allergies (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    patient_id UUID NOT NULL REFERENCES erp_patients(id) ON DELETE CASCADE,
+    substance VARCHAR(255) NOT NULL,
+    reaction VARCHAR(255),
+    severity VARCHAR(10) NOT NULL DEFAULT 'MODERATE', -- MILD, MODERATE, SEVERE
+    recorded_by UUID REFERENCES staff(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_patient_allergies_patient ON patient_allergies(patient_id);
+
+CREATE TABLE IF NOT EXISTS patient_conditions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    patient_id UUID NOT NULL REFERENCES erp_patients(id) ON DELETE CASCADE,
+    label VARCHAR(255) NOT NULL,
+    icd10_code VARCHAR(10) REFERENCES icd10_codes(code),
+    since DATE,
+    recorded_by UUID REFERENCES staff(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_patient_conditions_patient ON patient_conditions(patient_id);
+
+-- Therapeutic classes. Two medications of a duplicate_check class in the
+-- same treatment are reported as duplicate therapy; the others (BETALACTAMINES)
+-- only serve allergies and interaction rules.
+CREATE TABLE IF NOT EXISTS drug_classes (
+    name VARCHAR(100) PRIMARY KEY,
+    duplicate_check BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE IF NOT EXISTS drug_class_members (
+    class_name VARCHAR(100) NOT NULL REFERENCES drug_classes(name) ON DELETE CASCADE,
+    dci VARCHAR(255) NOT NULL,
+    PRIMARY KEY (class_name, dci)
+);
+
+CREATE INDEX IF NOT EXISTS idx_drug_class_members_dci ON drug_class_members(dci);
+
+-- A rule pairs a DCI or class with another one, or with a condition given by
+-- an ICD-10 code prefix (N18 matches N18.3).
+CREATE TABLE IF NOT EXISTS drug_interactions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    substance_a VARCHAR(255) NOT NULL,
+    substance_b VARCHAR(255),
+    condition_code VARCHAR(10),
+    severity VARCHAR(20) NOT NULL, -- MINOR, MODERATE, MAJOR, CONTRAINDICATED
+    description_fr TEXT,
+    description_ar TEXT,
+    description_en TEXT,
+    source VARCHAR(10) NOT NULL DEFAULT 'SEED', -- SEED, ADMIN
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((substance_b IS NULL) <> (condition_code IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_drug_interactions_pair ON drug_interactions(LEAST(substance_a, substance_b), GREATEST(substance_a, substance_b)) WHERE substance_b IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_drug_interactions_condition ON drug_interactions(substance_a, condition_code) WHERE condition_code IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_drug_interactions_b ON drug_interactions(substance_b);
@@ -0,0 +1,9 @@
+-- ClinicLab Patient Timeline Migration
+-- Migration 022: per-patient date indexes for the clinical timeline
+
+-- medical_visits and prescriptions already have theirs (017, 019)
+CREATE INDEX IF NOT EXISTS idx_lab_orders_patient_date ON lab_orders(patient_id, ordered_at DESC);
+CREATE INDEX IF NOT EXISTS idx_admissions_patient_date ON admissions(patient_id, admitted_at DESC);
+CREATE INDEX IF NOT EXISTS idx_surgeries_patient_date ON surgeries(patient_id, scheduled_at DESC);
+CREATE INDEX IF NOT EXISTS idx_appointments_patient_date ON appointments(patient_id, scheduled_at DESC);
+CREATE INDEX IF NOT EXISTS idx_invoices_patient_date ON invoices(patient_id, issued_at DESC);